@@ -8,6 +8,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/example/back-end-tcc/cmd/internal/sandboxes"
 	"github.com/example/back-end-tcc/docs"
 	"github.com/example/back-end-tcc/pkg/config"
 	"github.com/example/back-end-tcc/pkg/logger"
@@ -80,8 +81,11 @@ func main() {
 
 	submissionRepo := createRepo[models.Submission](db, "submissions")
 	scoreRepo := createRepo[models.ScoreSummary](db, "scores")
+	scoringConfigRepo := createRepo[models.ScoringConfig](db, "scoring_configs")
 	traceRepo := createRepo[models.TraceEvent](db, "traces")
 	leaderboardRepo := createRepo[models.LeaderboardEntry](db, "leaderboard")
+	leaderboardSummaryRepo := createRepo[models.ScoreSummary](db, "leaderboard_summaries")
+	leaderboardSnapshotRepo := createRepo[models.LeaderboardSnapshot](db, "leaderboard_snapshots")
 	benchmarkRepo := createRepo[models.Benchmark](db, "benchmarks")
 	agentRepoStore := createRepo[models.User](db, "agents")
 
@@ -121,23 +125,37 @@ func main() {
 	orchestratorHTTP := orchestratorhandlers.New(orchestratorSrv)
 
 	runnerRepo := runnerrepository.New(submissionRepo, traceRepo)
+	sandboxOpts, closeSandboxes, err := sandboxes.RunnerOptions(cfg, newServiceLogger("sandbox"), meter)
+	if err != nil {
+		log.Fatalf("Failed to set up sandboxes: %v", err)
+	}
+	defer closeSandboxes()
+	runnerOpts := append([]runnerservice.Option{
+		runnerservice.WithLogger(newServiceLogger("runner")),
+		runnerservice.WithMetrics(meter),
+	}, sandboxOpts...)
 	runnerSrv := runnerservice.New(
 		runnerRepo,
 		agentRepo,
 		benchmarkRepoImpl,
 		bus,
 		bus,
-		runnerservice.WithLogger(newServiceLogger("runner")),
-		runnerservice.WithMetrics(meter),
+		runnerOpts...,
 	)
 	runnerSrv.Start()
 	runnerHTTP := runnerhandlers.New(runnerSrv)
 
-	scoringRepo := scoringrepository.New(scoreRepo)
+	scoringRepo := scoringrepository.New(
+		scoreRepo,
+		traceRepo,
+		scoringrepository.WithSubmissions(submissionRepo),
+		scoringrepository.WithConfigs(scoringConfigRepo),
+	)
 	scoringSrv := scoringservice.New(
 		scoringRepo,
 		bus,
 		bus,
+		scoringservice.WithBenchmarks(benchmarkRepoImpl),
 		scoringservice.WithLogger(newServiceLogger("scoring")),
 		scoringservice.WithMetrics(meter),
 	)
@@ -153,12 +171,20 @@ func main() {
 	)
 	traceHTTP := tracehandlers.New(traceSrv)
 
-	leaderboardRepoImpl := leaderboardrepository.New(leaderboardRepo)
+	leaderboardRepoImpl := leaderboardrepository.New(
+		leaderboardRepo,
+		leaderboardrepository.WithSummaries(leaderboardSummaryRepo),
+		leaderboardrepository.WithSnapshots(leaderboardSnapshotRepo),
+	)
 	leaderboardSrv := leaderboardservice.New(
 		leaderboardRepoImpl,
 		bus,
 		leaderboardservice.WithLogger(newServiceLogger("leaderboard")),
 		leaderboardservice.WithMetrics(meter),
+		leaderboardservice.WithAgents(agentRepo),
+		leaderboardservice.WithBenchmarks(benchmarkRepoImpl),
+		leaderboardservice.WithSelection(leaderboardservice.Selection(cfg.LeaderboardSelection)),
+		leaderboardservice.WithScoringVersion(cfg.LeaderboardScoringVersion),
 	)
 	leaderboardSrv.Start()
 	leaderboardHTTP := leaderboardhandlers.New(leaderboardSrv)
@@ -178,12 +204,55 @@ func main() {
 		http.MethodGet:  orchestratorHTTP.List,
 	}))
 	mux.HandleFunc("/results", runnerHTTP.Results)
+	mux.HandleFunc("/artifacts", withMethod(map[string]http.HandlerFunc{
+		http.MethodGet: runnerHTTP.Artifacts,
+	}))
+	mux.HandleFunc("/sandbox/pool", withMethod(map[string]http.HandlerFunc{
+		http.MethodGet: runnerHTTP.SandboxPool,
+	}))
 	mux.HandleFunc("/scores", scoringHTTP.List)
+	mux.HandleFunc("/scores/compare", withMethod(map[string]http.HandlerFunc{
+		http.MethodGet: scoringHTTP.Compare,
+	}))
+	mux.HandleFunc("/scores/export", withMethod(map[string]http.HandlerFunc{
+		http.MethodGet: scoringHTTP.Export,
+	}))
+	mux.HandleFunc("/scores/configs", withMethod(map[string]http.HandlerFunc{
+		http.MethodGet:  scoringHTTP.Configs,
+		http.MethodPost: scoringHTTP.CreateConfig,
+	}))
+	mux.HandleFunc("/scores/rescore", withMethod(map[string]http.HandlerFunc{
+		http.MethodPost: scoringHTTP.Rescore,
+	}))
+	mux.HandleFunc("/scores/{submissionId}", withMethod(map[string]http.HandlerFunc{
+		http.MethodGet: scoringHTTP.Get,
+	}))
+	mux.HandleFunc("/scores/{submissionId}/history", withMethod(map[string]http.HandlerFunc{
+		http.MethodGet: scoringHTTP.History,
+	}))
 	mux.HandleFunc("/traces", withMethod(map[string]http.HandlerFunc{
 		http.MethodPost: traceHTTP.Record,
 		http.MethodGet:  traceHTTP.List,
 	}))
+	mux.HandleFunc("/submissions/{id}/trace", withMethod(map[string]http.HandlerFunc{
+		http.MethodGet: traceHTTP.Conversation,
+	}))
 	mux.HandleFunc("/leaderboard", leaderboardHTTP.List)
+	mux.HandleFunc("/leaderboard/export", withMethod(map[string]http.HandlerFunc{
+		http.MethodGet: leaderboardHTTP.Export,
+	}))
+	mux.HandleFunc("/leaderboard/overall", withMethod(map[string]http.HandlerFunc{
+		http.MethodGet: leaderboardHTTP.Overall,
+	}))
+	mux.HandleFunc("/leaderboard/pareto", withMethod(map[string]http.HandlerFunc{
+		http.MethodGet: leaderboardHTTP.Pareto,
+	}))
+	mux.HandleFunc("/agents/{id}/metrics", withMethod(map[string]http.HandlerFunc{
+		http.MethodGet: leaderboardHTTP.AgentMetrics,
+	}))
+	mux.HandleFunc("/agents/{id}/rank-history", withMethod(map[string]http.HandlerFunc{
+		http.MethodGet: leaderboardHTTP.RankHistory,
+	}))
 	mux.HandleFunc("/swagger.json", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 		if _, err := w.Write(docs.OpenAPISpec); err != nil && apiLog != nil {
@@ -202,6 +271,7 @@ func enableCORS(next http.Handler) http.Handler {
 		w.Header().Set("Access-Control-Allow-Origin", "http://localhost:5173")
 		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
 		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		w.Header().Set("Access-Control-Expose-Headers", "X-Next-Cursor, X-Total-Count")
 		if r.Method == "OPTIONS" {
 			w.WriteHeader(http.StatusOK)
 			return
@@ -0,0 +1,90 @@
+// Package sandboxes wires the runner's sandboxes from configuration, for every binary that runs tasks.
+package sandboxes
+
+import (
+	"fmt"
+
+	"github.com/example/back-end-tcc/pkg/artifacts"
+	"github.com/example/back-end-tcc/pkg/config"
+	"github.com/example/back-end-tcc/pkg/logger"
+	"github.com/example/back-end-tcc/pkg/observability/metrics"
+	"github.com/example/back-end-tcc/pkg/sandbox"
+	runnerservice "github.com/example/back-end-tcc/services/runner/service"
+
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// RunnerOptions returns the runner options cfg selects: the sandbox backend, the warm pool, the
+// fixtures directory and the artifact store. The returned close stops the pooled sandboxes.
+func RunnerOptions(cfg *config.Config, log logger.Logger, meter metrics.Recorder) ([]runnerservice.Option, func() error, error) {
+	opts := []runnerservice.Option{runnerservice.WithFixturesDir(cfg.SandboxFixturesDir)}
+	if cfg.ArtifactsDir != "" {
+		store, err := artifacts.NewFileStore(cfg.ArtifactsDir)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to open artifact store: %w", err)
+		}
+		opts = append(opts, runnerservice.WithArtifactStore(store))
+	}
+	factory, err := Factory(cfg)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to configure sandboxes: %w", err)
+	}
+	if cfg.SandboxPoolSize <= 0 {
+		return append(opts, runnerservice.WithSandboxProvider(sandbox.NewDirectProvider(factory))), func() error { return nil }, nil
+	}
+	pool := sandbox.NewPool(
+		factory,
+		sandbox.WithPoolSize(cfg.SandboxPoolSize),
+		sandbox.WithMaxUses(cfg.SandboxPoolMaxUses),
+		sandbox.WithPoolLogger(log),
+		sandbox.WithPoolMetrics(meter),
+	)
+	pool.Warm(sandbox.DefaultSpec(sandbox.DefaultImage))
+	return append(opts, runnerservice.WithSandboxProvider(pool)), pool.Close, nil
+}
+
+// Factory returns the sandbox implementation selected by SANDBOX_BACKEND.
+func Factory(cfg *config.Config) (sandbox.Factory, error) {
+	switch cfg.SandboxBackend {
+	case "kubernetes":
+		return kubernetesFactory(cfg)
+	case "docker":
+		return sandbox.DockerFactory, nil
+	}
+	var opts []sandbox.ProcessOption
+	for _, ns := range cfg.SandboxNamespaces {
+		switch ns {
+		case "user":
+			opts = append(opts, sandbox.WithUserNamespace(true))
+		case "network":
+			opts = append(opts, sandbox.WithNetworkNamespace(true))
+		}
+	}
+	return sandbox.ProcessFactory(opts...), nil
+}
+
+// kubernetesFactory connects with KUBECONFIG when set and with the pod's service account otherwise.
+func kubernetesFactory(cfg *config.Config) (sandbox.Factory, error) {
+	var restConfig *rest.Config
+	var err error
+	if cfg.Kubeconfig != "" {
+		restConfig, err = clientcmd.BuildConfigFromFlags("", cfg.Kubeconfig)
+	} else {
+		restConfig, err = rest.InClusterConfig()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load kubernetes config: %w", err)
+	}
+	client, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create kubernetes client: %w", err)
+	}
+	return sandbox.KubernetesFactory(client, restConfig, sandbox.KubernetesConfig{
+		Namespace:       cfg.KubernetesNamespace,
+		ServiceAccount:  cfg.KubernetesServiceAccount,
+		CPURequest:      cfg.KubernetesCPURequest,
+		MemoryRequestMB: cfg.KubernetesMemoryRequestMB,
+	}), nil
+}
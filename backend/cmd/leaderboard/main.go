@@ -32,16 +32,23 @@ func main() {
 		bus,
 		leaderboardservice.WithLogger(log),
 		leaderboardservice.WithMetrics(meter),
+		leaderboardservice.WithSelection(leaderboardservice.Selection(cfg.LeaderboardSelection)),
+		leaderboardservice.WithScoringVersion(cfg.LeaderboardScoringVersion),
 	)
 	srv.Start()
 	handlers := leaderboardhandlers.New(srv)
 
 	// Seed leaderboard event for demonstration.
-	summary := models.ScoreSummary{Score: 1.0, Calculated: time.Now()}
-	_ = bus.Publish(context.Background(), queue.Message{Type: "leaderboard.updated", Data: summary})
+	summary := models.ScoreSummary{SubmissionID: "seed", AgentID: "agent", BenchmarkID: "default", Score: 1.0, Calculated: time.Now(), SubmittedAt: time.Now()}
+	_ = bus.Publish(context.Background(), queue.Message{Type: leaderboardservice.LeaderboardUpdated, Data: summary})
 
 	mux := http.NewServeMux()
 	mux.HandleFunc("/leaderboard", handlers.List)
+	mux.HandleFunc("GET /leaderboard/export", handlers.Export)
+	mux.HandleFunc("GET /leaderboard/overall", handlers.Overall)
+	mux.HandleFunc("GET /leaderboard/pareto", handlers.Pareto)
+	mux.HandleFunc("GET /agents/{id}/metrics", handlers.AgentMetrics)
+	mux.HandleFunc("GET /agents/{id}/rank-history", handlers.RankHistory)
 
 	log.Printf("leaderboard service listening on :%d", cfg.HTTPPort)
 	if err := http.ListenAndServe(fmt.Sprintf(":%d", cfg.HTTPPort), mux); err != nil {
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"net/http"
 
+	"github.com/example/back-end-tcc/cmd/internal/sandboxes"
 	"github.com/example/back-end-tcc/pkg/config"
 	"github.com/example/back-end-tcc/pkg/logger"
 	"github.com/example/back-end-tcc/pkg/models"
@@ -27,17 +28,25 @@ func main() {
 	meter := metrics.NewInMemory()
 
 	bus := queue.NewBus(queue.WithLogger(log), queue.WithMetrics(meter))
-	repo := runnerrepository.New(storage.NewMemoryRepository[models.Submission]())
+	repo := runnerrepository.New(storage.NewMemoryRepository[models.Submission](), storage.NewMemoryRepository[models.TraceEvent]())
 	agentRepo := agentrepository.NewAgentRepository(storage.NewMemoryRepository[models.User]())
 	benchmarkRepo := benchmarkrepository.New(storage.NewMemoryRepository[models.Benchmark]())
+	sandboxOpts, closeSandboxes, err := sandboxes.RunnerOptions(cfg, logger.New(logger.WithPrefix("sandbox ")), meter)
+	if err != nil {
+		log.Println("sandbox setup error:", err)
+		return
+	}
+	defer closeSandboxes()
 	srv := runnerservice.New(
 		repo,
 		agentRepo,
 		benchmarkRepo,
 		bus,
 		bus,
-		runnerservice.WithLogger(log),
-		runnerservice.WithMetrics(meter),
+		append([]runnerservice.Option{
+			runnerservice.WithLogger(log),
+			runnerservice.WithMetrics(meter),
+		}, sandboxOpts...)...,
 	)
 	srv.Start()
 	handlers := runnerhandlers.New(srv)
@@ -47,6 +56,8 @@ func main() {
 
 	mux := http.NewServeMux()
 	mux.HandleFunc("/results", handlers.Results)
+	mux.HandleFunc("GET /artifacts", handlers.Artifacts)
+	mux.HandleFunc("GET /sandbox/pool", handlers.SandboxPool)
 
 	log.Printf("runner service listening on :%d", cfg.HTTPPort)
 	if err := http.ListenAndServe(fmt.Sprintf(":%d", cfg.HTTPPort), mux); err != nil {
@@ -26,7 +26,10 @@ func main() {
 	meter := metrics.NewInMemory()
 
 	bus := queue.NewBus(queue.WithLogger(log), queue.WithMetrics(meter))
-	repo := scoringrepository.New(storage.NewMemoryRepository[models.ScoreSummary]())
+	// The standalone service shares no stores with the orchestrator and benchmark catalog: summaries
+	// use the default formula, and POST /scores/rescore, which reads stored submissions, is only
+	// served by the API gateway.
+	repo := scoringrepository.New(storage.NewMemoryRepository[models.ScoreSummary](), storage.NewMemoryRepository[models.TraceEvent]())
 	srv := scoringservice.New(
 		repo,
 		bus,
@@ -43,6 +46,12 @@ func main() {
 
 	mux := http.NewServeMux()
 	mux.HandleFunc("/scores", handlers.List)
+	mux.HandleFunc("GET /scores/compare", handlers.Compare)
+	mux.HandleFunc("GET /scores/export", handlers.Export)
+	mux.HandleFunc("GET /scores/configs", handlers.Configs)
+	mux.HandleFunc("POST /scores/configs", handlers.CreateConfig)
+	mux.HandleFunc("GET /scores/{submissionId}", handlers.Get)
+	mux.HandleFunc("GET /scores/{submissionId}/history", handlers.History)
 
 	log.Printf("scoring service listening on :%d", cfg.HTTPPort)
 	if err := http.ListenAndServe(fmt.Sprintf(":%d", cfg.HTTPPort), mux); err != nil {
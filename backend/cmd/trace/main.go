@@ -38,6 +38,9 @@ func main() {
 		http.MethodPost: handlers.Record,
 		http.MethodGet:  handlers.List,
 	}))
+	mux.HandleFunc("/submissions/{id}/trace", withMethod(map[string]http.HandlerFunc{
+		http.MethodGet: handlers.Conversation,
+	}))
 
 	log.Printf("trace service listening on :%d", cfg.HTTPPort)
 	if err := http.ListenAndServe(fmt.Sprintf(":%d", cfg.HTTPPort), mux); err != nil {
@@ -0,0 +1,206 @@
+package artifacts
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/example/back-end-tcc/pkg/models"
+)
+
+// diffContext is the number of unchanged lines shown around each change.
+const diffContext = 3
+
+// maxLCSCells bounds the line comparison table; larger files are diffed as a whole replacement.
+const maxLCSCells = 4 << 20
+
+// Diff compares two snapshots and renders the changes as a unified diff in git style, which the
+// apply_patch tool accepts. Binary files and files without captured content are only named.
+func Diff(before, after *Snapshot) (models.WorkdirChanges, string) {
+	var changes models.WorkdirChanges
+	for name, file := range after.Files {
+		old, ok := before.Files[name]
+		switch {
+		case !ok:
+			changes.Added = append(changes.Added, name)
+		case old.Hash != file.Hash:
+			changes.Modified = append(changes.Modified, name)
+		}
+	}
+	for name := range before.Files {
+		if _, ok := after.Files[name]; !ok {
+			changes.Deleted = append(changes.Deleted, name)
+		}
+	}
+	sort.Strings(changes.Added)
+	sort.Strings(changes.Modified)
+	sort.Strings(changes.Deleted)
+
+	names := append(append(append([]string{}, changes.Added...), changes.Modified...), changes.Deleted...)
+	sort.Strings(names)
+	var patch strings.Builder
+	for _, name := range names {
+		old, hadOld := before.Files[name]
+		cur, hasNew := after.Files[name]
+		writeFileDiff(&patch, name, old, hadOld, cur, hasNew)
+	}
+	return changes, patch.String()
+}
+
+func writeFileDiff(w *strings.Builder, name string, old File, hadOld bool, cur File, hasNew bool) {
+	oldName, newName := "a/"+name, "b/"+name
+	if !hadOld {
+		oldName = "/dev/null"
+	}
+	if !hasNew {
+		newName = "/dev/null"
+	}
+	if (hadOld && !isText(old)) || (hasNew && !isText(cur)) {
+		fmt.Fprintf(w, "Binary files %s and %s differ\n", oldName, newName)
+		return
+	}
+	fmt.Fprintf(w, "--- %s\n+++ %s\n", oldName, newName)
+	writeHunks(w, splitLines(old.Content), splitLines(cur.Content))
+}
+
+// isText reports whether the file content was captured and is UTF-8 text.
+func isText(f File) bool {
+	if f.Content == nil {
+		return f.Size == 0
+	}
+	return utf8.Valid(f.Content) && !bytes.Contains(f.Content, []byte{0})
+}
+
+// splitLines splits content into lines that keep their newline.
+func splitLines(content []byte) []string {
+	if len(content) == 0 {
+		return nil
+	}
+	lines := strings.SplitAfter(string(content), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+type lineOp struct {
+	kind byte // ' ', '-' or '+'
+	line string
+}
+
+// lineOps returns an edit script turning a into b, based on the longest common subsequence.
+func lineOps(a, b []string) []lineOp {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	ops := make([]lineOp, 0, len(a)+len(b))
+	for _, l := range a[:prefix] {
+		ops = append(ops, lineOp{' ', l})
+	}
+	ma, mb := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+	if len(ma)*len(mb) > maxLCSCells {
+		for _, l := range ma {
+			ops = append(ops, lineOp{'-', l})
+		}
+		for _, l := range mb {
+			ops = append(ops, lineOp{'+', l})
+		}
+	} else {
+		// lcs[i][j] is the LCS length of ma[i:] and mb[j:].
+		lcs := make([][]int, len(ma)+1)
+		for i := range lcs {
+			lcs[i] = make([]int, len(mb)+1)
+		}
+		for i := len(ma) - 1; i >= 0; i-- {
+			for j := len(mb) - 1; j >= 0; j-- {
+				if ma[i] == mb[j] {
+					lcs[i][j] = lcs[i+1][j+1] + 1
+				} else {
+					lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+				}
+			}
+		}
+		i, j := 0, 0
+		for i < len(ma) || j < len(mb) {
+			switch {
+			case i < len(ma) && j < len(mb) && ma[i] == mb[j]:
+				ops = append(ops, lineOp{' ', ma[i]})
+				i++
+				j++
+			case i < len(ma) && (j == len(mb) || lcs[i+1][j] >= lcs[i][j+1]):
+				// Prefer deletions so removed lines precede their replacements.
+				ops = append(ops, lineOp{'-', ma[i]})
+				i++
+			default:
+				ops = append(ops, lineOp{'+', mb[j]})
+				j++
+			}
+		}
+	}
+	for _, l := range a[len(a)-suffix:] {
+		ops = append(ops, lineOp{' ', l})
+	}
+	return ops
+}
+
+// writeHunks renders the edit script as unified diff hunks with diffContext lines of context.
+func writeHunks(w *strings.Builder, a, b []string) {
+	ops := lineOps(a, b)
+	// oldPos[i] and newPos[i] count the lines consumed before ops[i].
+	oldPos := make([]int, len(ops)+1)
+	newPos := make([]int, len(ops)+1)
+	for i, op := range ops {
+		oldPos[i+1], newPos[i+1] = oldPos[i], newPos[i]
+		if op.kind != '+' {
+			oldPos[i+1]++
+		}
+		if op.kind != '-' {
+			newPos[i+1]++
+		}
+	}
+
+	for i := 0; i < len(ops); {
+		if ops[i].kind == ' ' {
+			i++
+			continue
+		}
+		start := max(0, i-diffContext)
+		// Extend the hunk while the next change is close enough for the contexts to touch.
+		end, unchanged := i, 0
+		for j := i; j < len(ops) && unchanged <= 2*diffContext; j++ {
+			if ops[j].kind == ' ' {
+				unchanged++
+				continue
+			}
+			end, unchanged = j+1, 0
+		}
+		end = min(len(ops), end+diffContext)
+
+		oldCount, newCount := oldPos[end]-oldPos[start], newPos[end]-newPos[start]
+		fmt.Fprintf(w, "@@ -%s +%s @@\n", hunkRange(oldPos[start], oldCount), hunkRange(newPos[start], newCount))
+		for _, op := range ops[start:end] {
+			w.WriteByte(op.kind)
+			w.WriteString(op.line)
+			if !strings.HasSuffix(op.line, "\n") {
+				w.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+		i = end
+	}
+}
+
+// hunkRange formats a hunk range; empty ranges name the line before them, as diff does.
+func hunkRange(start, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
@@ -0,0 +1,153 @@
+package artifacts
+
+import (
+	"archive/tar"
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/example/back-end-tcc/pkg/sandbox"
+)
+
+// Size limits applied while reading sandbox contents.
+const (
+	// MaxDiffFileBytes is the largest file whose content is kept for the textual diff.
+	MaxDiffFileBytes = 256 << 10
+	// MaxSnapshotContentBytes bounds the file content a snapshot keeps in total.
+	MaxSnapshotContentBytes = 32 << 20
+	// MaxArchiveBytes bounds the size of an archive of artifact paths.
+	MaxArchiveBytes = 64 << 20
+)
+
+// ErrArchiveTooLarge is returned when the selected artifact paths exceed MaxArchiveBytes.
+var ErrArchiveTooLarge = fmt.Errorf("artifact archive exceeds %d bytes", MaxArchiveBytes)
+
+// File is the state of one regular file in a snapshot.
+type File struct {
+	Size int64
+	Hash [sha256.Size]byte
+	// Content is nil when the file is larger than MaxDiffFileBytes or the snapshot budget ran out.
+	Content []byte
+}
+
+// Snapshot records the regular files below a sandbox directory, keyed by their relative path.
+type Snapshot struct {
+	Files map[string]File
+}
+
+// TakeSnapshot reads every regular file below dir. Files are hashed in full; small files keep their content.
+func TakeSnapshot(sb sandbox.Sandbox, dir string) (*Snapshot, error) {
+	reader, err := sb.CopyOut(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", dir, err)
+	}
+	defer reader.Close()
+
+	snap := &Snapshot{Files: make(map[string]File)}
+	budget := int64(MaxSnapshotContentBytes)
+	tr := tar.NewReader(reader)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return snap, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read archive: %w", err)
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		// Entries are named after the base name of dir.
+		_, name, ok := strings.Cut(path.Clean(header.Name), "/")
+		if !ok {
+			continue
+		}
+
+		h := sha256.New()
+		var content bytes.Buffer
+		var w io.Writer = h
+		keep := header.Size <= MaxDiffFileBytes && header.Size <= budget
+		if keep {
+			w = io.MultiWriter(h, &content)
+		}
+		if _, err := io.Copy(w, tr); err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", name, err)
+		}
+		file := File{Size: header.Size}
+		copy(file.Hash[:], h.Sum(nil))
+		if keep {
+			file.Content = content.Bytes()
+			budget -= header.Size
+		}
+		snap.Files[name] = file
+	}
+}
+
+// Archive builds a tar archive of the given paths, relative to root. Entry names are relative to root;
+// paths that do not exist are skipped and returned.
+func Archive(sb sandbox.Sandbox, root string, paths []string) ([]byte, []string, error) {
+	var buf bytes.Buffer
+	var missing []string
+	tw := tar.NewWriter(&buf)
+	for _, p := range paths {
+		p = path.Clean(p)
+		reader, err := sb.CopyOut(path.Join(root, p))
+		if errors.Is(err, sandbox.ErrNotFound) {
+			missing = append(missing, p)
+			continue
+		}
+		if err != nil {
+			return nil, missing, err
+		}
+		err = appendEntries(tw, reader, path.Dir(p), &buf)
+		reader.Close()
+		if err != nil {
+			return nil, missing, err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, missing, err
+	}
+	return buf.Bytes(), missing, nil
+}
+
+// appendEntries copies the entries of a CopyOut archive into tw, prefixing their names with dir.
+func appendEntries(tw *tar.Writer, archive io.Reader, dir string, written *bytes.Buffer) error {
+	tr := tar.NewReader(archive)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read archive: %w", err)
+		}
+		if header.Typeflag != tar.TypeReg && header.Typeflag != tar.TypeDir {
+			continue
+		}
+		if int64(written.Len())+header.Size > MaxArchiveBytes {
+			return ErrArchiveTooLarge
+		}
+		name := path.Join(dir, header.Name)
+		if header.Typeflag == tar.TypeDir {
+			name += "/"
+		}
+		out := &tar.Header{
+			Name:     name,
+			Typeflag: header.Typeflag,
+			Mode:     header.Mode,
+			Size:     header.Size,
+			ModTime:  header.ModTime,
+		}
+		if err := tw.WriteHeader(out); err != nil {
+			return err
+		}
+		if _, err := io.Copy(tw, tr); err != nil {
+			return err
+		}
+	}
+}
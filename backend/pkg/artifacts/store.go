@@ -0,0 +1,205 @@
+// Package artifacts captures what a task left in its sandbox and stores it for review.
+package artifacts
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/example/back-end-tcc/pkg/models"
+)
+
+// Artifact kinds.
+const (
+	KindDiff    = "diff"
+	KindArchive = "archive"
+)
+
+// ErrNotFound is returned for unknown artifact ids.
+var ErrNotFound = errors.New("artifact not found")
+
+// Store persists artifact contents alongside their metadata.
+type Store interface {
+	// Put stores data and returns the metadata completed with ID, Size, SHA256 and CreatedAt.
+	Put(meta models.Artifact, data []byte) (models.Artifact, error)
+	Get(id string) (models.Artifact, error)
+	Open(id string) (io.ReadCloser, error)
+	// List returns the artifacts of a submission, oldest first.
+	List(submissionID string) []models.Artifact
+}
+
+var idSeq atomic.Int64
+
+// complete fills the fields Put is responsible for.
+func complete(meta models.Artifact, data []byte) models.Artifact {
+	sum := sha256.Sum256(data)
+	meta.ID = fmt.Sprintf("artifact-%d-%d", time.Now().UnixNano(), idSeq.Add(1))
+	meta.Size = int64(len(data))
+	meta.SHA256 = hex.EncodeToString(sum[:])
+	meta.CreatedAt = time.Now()
+	return meta
+}
+
+func sortArtifacts(list []models.Artifact) {
+	sort.Slice(list, func(i, j int) bool {
+		if !list[i].CreatedAt.Equal(list[j].CreatedAt) {
+			return list[i].CreatedAt.Before(list[j].CreatedAt)
+		}
+		return list[i].ID < list[j].ID
+	})
+}
+
+// MemoryStore keeps artifacts in memory.
+type MemoryStore struct {
+	mu    sync.RWMutex
+	metas map[string]models.Artifact
+	data  map[string][]byte
+}
+
+// NewMemoryStore creates a MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{metas: make(map[string]models.Artifact), data: make(map[string][]byte)}
+}
+
+// Put stores the artifact.
+func (s *MemoryStore) Put(meta models.Artifact, data []byte) (models.Artifact, error) {
+	meta = complete(meta, data)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.metas[meta.ID] = meta
+	s.data[meta.ID] = append([]byte(nil), data...)
+	return meta, nil
+}
+
+// Get returns artifact metadata.
+func (s *MemoryStore) Get(id string) (models.Artifact, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	meta, ok := s.metas[id]
+	if !ok {
+		return models.Artifact{}, ErrNotFound
+	}
+	return meta, nil
+}
+
+// Open returns the artifact content.
+func (s *MemoryStore) Open(id string) (io.ReadCloser, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	data, ok := s.data[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+// List returns the artifacts of a submission.
+func (s *MemoryStore) List(submissionID string) []models.Artifact {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var list []models.Artifact
+	for _, meta := range s.metas {
+		if meta.SubmissionID == submissionID {
+			list = append(list, meta)
+		}
+	}
+	sortArtifacts(list)
+	return list
+}
+
+// FileStore keeps artifacts in a directory: the content in <id> and the metadata in <id>.json.
+type FileStore struct {
+	dir string
+}
+
+// NewFileStore creates a FileStore rooted at dir, creating the directory when needed.
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create artifact directory: %w", err)
+	}
+	return &FileStore{dir: dir}, nil
+}
+
+// Put writes the content before the metadata, so listed artifacts can always be opened.
+func (s *FileStore) Put(meta models.Artifact, data []byte) (models.Artifact, error) {
+	meta = complete(meta, data)
+	encoded, err := json.Marshal(meta)
+	if err != nil {
+		return models.Artifact{}, err
+	}
+	if err := os.WriteFile(filepath.Join(s.dir, meta.ID), data, 0o640); err != nil {
+		return models.Artifact{}, fmt.Errorf("failed to write artifact: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(s.dir, meta.ID+".json"), encoded, 0o640); err != nil {
+		return models.Artifact{}, fmt.Errorf("failed to write artifact metadata: %w", err)
+	}
+	return meta, nil
+}
+
+// Get reads artifact metadata.
+func (s *FileStore) Get(id string) (models.Artifact, error) {
+	if !validID(id) {
+		return models.Artifact{}, ErrNotFound
+	}
+	data, err := os.ReadFile(filepath.Join(s.dir, id+".json"))
+	if errors.Is(err, os.ErrNotExist) {
+		return models.Artifact{}, ErrNotFound
+	}
+	if err != nil {
+		return models.Artifact{}, err
+	}
+	var meta models.Artifact
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return models.Artifact{}, fmt.Errorf("corrupt artifact metadata %s: %w", id, err)
+	}
+	return meta, nil
+}
+
+// Open opens the artifact content.
+func (s *FileStore) Open(id string) (io.ReadCloser, error) {
+	if !validID(id) {
+		return nil, ErrNotFound
+	}
+	f, err := os.Open(filepath.Join(s.dir, id))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return f, err
+}
+
+// List scans the metadata files for the artifacts of a submission. Unreadable entries are skipped.
+func (s *FileStore) List(submissionID string) []models.Artifact {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil
+	}
+	var list []models.Artifact
+	for _, e := range entries {
+		id, ok := strings.CutSuffix(e.Name(), ".json")
+		if !ok {
+			continue
+		}
+		meta, err := s.Get(id)
+		if err == nil && meta.SubmissionID == submissionID {
+			list = append(list, meta)
+		}
+	}
+	sortArtifacts(list)
+	return list
+}
+
+// validID rejects ids that could escape the store directory.
+func validID(id string) bool {
+	return id != "" && !strings.ContainsAny(id, `/\`) && id != "." && id != ".."
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 	"sync"
 )
 
@@ -14,6 +15,30 @@ type Config struct {
 	QueueBufferSize  int
 	StorageDSN       string
 	JWTSigningSecret string
+	// SandboxBackend selects the sandbox implementation: docker, process or kubernetes.
+	SandboxBackend string
+	// SandboxNamespaces lists the Linux namespaces (user, network) process sandboxes run in.
+	SandboxNamespaces []string
+	// KubernetesNamespace and KubernetesServiceAccount place kubernetes sandbox pods.
+	KubernetesNamespace      string
+	KubernetesServiceAccount string
+	// KubernetesCPURequest and KubernetesMemoryRequestMB reserve less than the limits; 0 requests the limits.
+	KubernetesCPURequest      float64
+	KubernetesMemoryRequestMB int64
+	// Kubeconfig is used outside a cluster; empty uses the in-cluster service account.
+	Kubeconfig string
+	// SandboxPoolSize is the number of warm sandboxes kept per spec; 0 disables pooling.
+	SandboxPoolSize int
+	// SandboxPoolMaxUses bounds how many tasks reuse a pooled sandbox before it is replaced.
+	SandboxPoolMaxUses int
+	// SandboxFixturesDir holds files and directories that task fixtures may copy into sandboxes.
+	SandboxFixturesDir string
+	// ArtifactsDir stores captured task artifacts on disk; empty keeps them in memory.
+	ArtifactsDir string
+	// LeaderboardScoringVersion pins the leaderboard to one scoring config version; 0 follows every rescore.
+	LeaderboardScoringVersion int
+	// LeaderboardSelection keeps each agent's best or latest submission on a benchmark's leaderboard.
+	LeaderboardSelection string
 }
 
 var (
@@ -40,6 +65,37 @@ func populate(cfg *Config) error {
 	cfg.Environment = getString("APP_ENV", "development")
 	cfg.StorageDSN = getString("STORAGE_DSN", "memory://default")
 	cfg.JWTSigningSecret = getString("JWT_SIGNING_SECRET", "dev-secret")
+	cfg.SandboxFixturesDir = getString("SANDBOX_FIXTURES_DIR", "")
+	cfg.ArtifactsDir = getString("ARTIFACTS_DIR", "")
+
+	cfg.SandboxBackend = getString("SANDBOX_BACKEND", "docker")
+	switch cfg.SandboxBackend {
+	case "docker", "process", "kubernetes":
+	default:
+		return fmt.Errorf("invalid SANDBOX_BACKEND: %q", cfg.SandboxBackend)
+	}
+	cfg.KubernetesNamespace = getString("SANDBOX_K8S_NAMESPACE", "default")
+	cfg.KubernetesServiceAccount = getString("SANDBOX_K8S_SERVICE_ACCOUNT", "")
+	cfg.Kubeconfig = getString("KUBECONFIG", "")
+	cpuRequest, err := strconv.ParseFloat(getString("SANDBOX_K8S_CPU_REQUEST", "0"), 64)
+	if err != nil || cpuRequest < 0 {
+		return fmt.Errorf("invalid SANDBOX_K8S_CPU_REQUEST: %q", os.Getenv("SANDBOX_K8S_CPU_REQUEST"))
+	}
+	cfg.KubernetesCPURequest = cpuRequest
+	memoryRequest, err := strconv.ParseInt(getString("SANDBOX_K8S_MEMORY_REQUEST_MB", "0"), 10, 64)
+	if err != nil || memoryRequest < 0 {
+		return fmt.Errorf("invalid SANDBOX_K8S_MEMORY_REQUEST_MB: %q", os.Getenv("SANDBOX_K8S_MEMORY_REQUEST_MB"))
+	}
+	cfg.KubernetesMemoryRequestMB = memoryRequest
+	for _, ns := range strings.Split(getString("SANDBOX_NAMESPACES", ""), ",") {
+		switch ns = strings.TrimSpace(ns); ns {
+		case "":
+		case "user", "network":
+			cfg.SandboxNamespaces = append(cfg.SandboxNamespaces, ns)
+		default:
+			return fmt.Errorf("invalid SANDBOX_NAMESPACES entry: %q", ns)
+		}
+	}
 
 	port, err := strconv.Atoi(getString("HTTP_PORT", "8080"))
 	if err != nil {
@@ -53,6 +109,30 @@ func populate(cfg *Config) error {
 	}
 	cfg.QueueBufferSize = bufferSize
 
+	poolSize, err := strconv.Atoi(getString("SANDBOX_POOL_SIZE", "0"))
+	if err != nil || poolSize < 0 {
+		return fmt.Errorf("invalid SANDBOX_POOL_SIZE: %q", os.Getenv("SANDBOX_POOL_SIZE"))
+	}
+	cfg.SandboxPoolSize = poolSize
+
+	maxUses, err := strconv.Atoi(getString("SANDBOX_POOL_MAX_USES", "20"))
+	if err != nil || maxUses < 1 {
+		return fmt.Errorf("invalid SANDBOX_POOL_MAX_USES: %q", os.Getenv("SANDBOX_POOL_MAX_USES"))
+	}
+	cfg.SandboxPoolMaxUses = maxUses
+
+	scoringVersion, err := strconv.Atoi(getString("LEADERBOARD_SCORING_VERSION", "0"))
+	if err != nil || scoringVersion < 0 {
+		return fmt.Errorf("invalid LEADERBOARD_SCORING_VERSION: %q", os.Getenv("LEADERBOARD_SCORING_VERSION"))
+	}
+	cfg.LeaderboardScoringVersion = scoringVersion
+	cfg.LeaderboardSelection = getString("LEADERBOARD_SELECTION", "best")
+	switch cfg.LeaderboardSelection {
+	case "best", "latest":
+	default:
+		return fmt.Errorf("invalid LEADERBOARD_SELECTION: %q", cfg.LeaderboardSelection)
+	}
+
 	return nil
 }
 
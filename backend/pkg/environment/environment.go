@@ -0,0 +1,235 @@
+// Package environment prepares task sandboxes: fixtures, setup, verification and teardown commands.
+package environment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/example/back-end-tcc/pkg/models"
+	"github.com/example/back-end-tcc/pkg/sandbox"
+)
+
+// Phases reported on steps.
+const (
+	PhaseSetup    = "setup"
+	PhaseVerify   = "verify"
+	PhaseTeardown = "teardown"
+)
+
+// DefaultCommandTimeout bounds each setup, verify and teardown command unless the environment overrides it.
+const DefaultCommandTimeout = 5 * time.Minute
+
+// MaxCommandTimeout is the largest per-command timeout an environment may request.
+const MaxCommandTimeout = 30 * time.Minute
+
+var envKeyPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Step is the outcome of one environment command.
+type Step struct {
+	Phase   string
+	Command string
+	Result  sandbox.ExecResult
+	Err     error
+}
+
+// OK reports whether the command ran and exited with code 0.
+func (s Step) OK() bool {
+	return s.Err == nil && s.Result.Success()
+}
+
+// Merge combines a benchmark environment with a task environment. The task image and env vars win;
+// fixtures, setup and verify commands run benchmark first, teardown runs task first. Artifact paths are combined.
+func Merge(base, task *models.Environment) models.Environment {
+	var merged models.Environment
+	for _, env := range []*models.Environment{base, task} {
+		if env == nil {
+			continue
+		}
+		if env.Image != "" {
+			merged.Image = env.Image
+		}
+		if env.TimeoutSeconds > 0 {
+			merged.TimeoutSeconds = env.TimeoutSeconds
+		}
+		if len(env.Env) > 0 && merged.Env == nil {
+			merged.Env = make(map[string]string)
+		}
+		for k, v := range env.Env {
+			merged.Env[k] = v
+		}
+		merged.Fixtures = append(merged.Fixtures, env.Fixtures...)
+		merged.Setup = append(merged.Setup, env.Setup...)
+		merged.Verify = append(merged.Verify, env.Verify...)
+		merged.Artifacts = append(merged.Artifacts, env.Artifacts...)
+		merged.Teardown = append(append([]string{}, env.Teardown...), merged.Teardown...)
+	}
+	return merged
+}
+
+// Validate checks an environment definition without touching any sandbox.
+func Validate(env *models.Environment) error {
+	if env == nil {
+		return nil
+	}
+	if strings.TrimSpace(env.Image) != env.Image {
+		return fmt.Errorf("invalid image %q", env.Image)
+	}
+	if env.TimeoutSeconds < 0 || time.Duration(env.TimeoutSeconds)*time.Second > MaxCommandTimeout {
+		return fmt.Errorf("timeoutSeconds must be between 0 and %d", int(MaxCommandTimeout.Seconds()))
+	}
+	for k := range env.Env {
+		if !envKeyPattern.MatchString(k) {
+			return fmt.Errorf("invalid env var name %q", k)
+		}
+	}
+	for i, f := range env.Fixtures {
+		if f.Path == "" {
+			return fmt.Errorf("fixture %d: missing path", i)
+		}
+		if f.Content != "" && f.Source != "" {
+			return fmt.Errorf("fixture %s: content and source are mutually exclusive", f.Path)
+		}
+		if f.Source != "" && !localPath(f.Source) {
+			return fmt.Errorf("fixture %s: source must be a relative path inside the fixtures directory", f.Path)
+		}
+		if f.Mode < 0 || f.Mode > 0o777 {
+			return fmt.Errorf("fixture %s: invalid mode %o", f.Path, f.Mode)
+		}
+	}
+	for _, p := range env.Artifacts {
+		if !localPath(p) {
+			return fmt.Errorf("artifact path %q must be relative to the workdir", p)
+		}
+	}
+	for _, cmds := range [][]string{env.Setup, env.Verify, env.Teardown} {
+		for _, cmd := range cmds {
+			if strings.TrimSpace(cmd) == "" {
+				return errors.New("environment commands must not be empty")
+			}
+		}
+	}
+	return nil
+}
+
+// Preparer copies fixtures into sandboxes and runs setup commands.
+type Preparer struct {
+	fixturesDir string
+}
+
+// NewPreparer creates a preparer resolving fixture sources under fixturesDir.
+// An empty fixturesDir only allows inline fixtures.
+func NewPreparer(fixturesDir string) *Preparer {
+	return &Preparer{fixturesDir: fixturesDir}
+}
+
+// Prepare copies fixtures and runs setup commands in order, stopping at the first failing command.
+// The steps that ran are returned along with the error.
+func (p *Preparer) Prepare(ctx context.Context, sb sandbox.Sandbox, env models.Environment) ([]Step, error) {
+	for _, f := range env.Fixtures {
+		if err := p.copyFixture(ctx, sb, f); err != nil {
+			return nil, fmt.Errorf("fixture %s: %w", f.Path, err)
+		}
+	}
+	var steps []Step
+	for _, cmd := range env.Setup {
+		step := run(ctx, sb, PhaseSetup, cmd, timeout(env))
+		steps = append(steps, step)
+		if !step.OK() {
+			return steps, fmt.Errorf("setup command %q failed: %s", cmd, describe(step))
+		}
+	}
+	return steps, nil
+}
+
+// Verify runs every verify command and reports whether all of them succeeded.
+func Verify(ctx context.Context, sb sandbox.Sandbox, env models.Environment) ([]Step, bool) {
+	passed := true
+	steps := make([]Step, 0, len(env.Verify))
+	for _, cmd := range env.Verify {
+		step := run(ctx, sb, PhaseVerify, cmd, timeout(env))
+		steps = append(steps, step)
+		passed = passed && step.OK()
+	}
+	return steps, passed
+}
+
+// Teardown runs every teardown command, ignoring failures.
+func Teardown(ctx context.Context, sb sandbox.Sandbox, env models.Environment) []Step {
+	steps := make([]Step, 0, len(env.Teardown))
+	for _, cmd := range env.Teardown {
+		steps = append(steps, run(ctx, sb, PhaseTeardown, cmd, timeout(env)))
+	}
+	return steps
+}
+
+func (p *Preparer) copyFixture(ctx context.Context, sb sandbox.Sandbox, f models.Fixture) error {
+	dst := f.Path
+	if !path.IsAbs(dst) {
+		dst = path.Join(sb.Workdir(), dst)
+	}
+	if f.Source == "" {
+		return sandbox.WriteFile(ctx, sb, dst, []byte(f.Content), f.Mode)
+	}
+
+	if p.fixturesDir == "" {
+		return errors.New("fixture sources are disabled: no fixtures directory configured")
+	}
+	if !localPath(f.Source) {
+		return fmt.Errorf("invalid source %q", f.Source)
+	}
+	src := filepath.Join(p.fixturesDir, filepath.FromSlash(f.Source))
+	info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		return sandbox.CopyDir(ctx, sb, src, dst)
+	}
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	mode := f.Mode
+	if mode == 0 {
+		mode = int64(info.Mode().Perm())
+	}
+	return sandbox.WriteFile(ctx, sb, dst, data, mode)
+}
+
+func run(ctx context.Context, sb sandbox.Sandbox, phase, cmd string, timeout time.Duration) Step {
+	res, err := sb.Exec(ctx, []string{"sh", "-c", cmd}, sandbox.ExecOptions{Timeout: timeout})
+	return Step{Phase: phase, Command: cmd, Result: res, Err: err}
+}
+
+func timeout(env models.Environment) time.Duration {
+	if env.TimeoutSeconds > 0 {
+		return time.Duration(env.TimeoutSeconds) * time.Second
+	}
+	return DefaultCommandTimeout
+}
+
+func describe(step Step) string {
+	switch {
+	case step.Err != nil:
+		return step.Err.Error()
+	case step.Result.TimedOut:
+		return "timed out"
+	}
+	msg := fmt.Sprintf("exit code %d", step.Result.ExitCode)
+	if stderr := strings.TrimSpace(step.Result.Stderr); stderr != "" {
+		msg += ": " + stderr
+	}
+	return msg
+}
+
+// localPath reports whether p is a relative slash-separated path that stays inside its root.
+func localPath(p string) bool {
+	return p != "" && !path.IsAbs(p) && !strings.Contains(p, `\`) && filepath.IsLocal(filepath.FromSlash(p))
+}
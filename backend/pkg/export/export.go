@@ -0,0 +1,291 @@
+// Package export renders rows as CSV, Markdown tables or JSON Lines with selectable columns, for
+// pasting results into papers and reports.
+package export
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Format is an export format.
+type Format string
+
+// Formats.
+const (
+	CSV      Format = "csv"
+	Markdown Format = "markdown"
+	JSONL    Format = "jsonl"
+)
+
+// Errors returned for unknown formats and columns.
+var (
+	ErrUnknownFormat = errors.New("unknown export format")
+	ErrUnknownColumn = errors.New("unknown export column")
+)
+
+// ParseFormat parses a format name; md and ndjson are accepted as aliases and empty means CSV.
+func ParseFormat(name string) (Format, error) {
+	switch strings.ToLower(name) {
+	case "", "csv":
+		return CSV, nil
+	case "markdown", "md":
+		return Markdown, nil
+	case "jsonl", "ndjson":
+		return JSONL, nil
+	}
+	return "", fmt.Errorf("%w %q: use csv, markdown or jsonl", ErrUnknownFormat, name)
+}
+
+// ContentType returns the MIME type of the format.
+func (f Format) ContentType() string {
+	switch f {
+	case Markdown:
+		return "text/markdown; charset=utf-8"
+	case JSONL:
+		return "application/x-ndjson"
+	}
+	return "text/csv; charset=utf-8"
+}
+
+// Extension returns the file extension of the format, without the dot.
+func (f Format) Extension() string {
+	if f == Markdown {
+		return "md"
+	}
+	return string(f)
+}
+
+// Column is a named value of a row.
+type Column[T any] struct {
+	Name  string
+	Value func(T) any
+}
+
+// Table lists the columns rows of type T can be exported with.
+type Table[T any] struct {
+	Columns []Column[T]
+	Default []string // Exported when no columns are selected
+	// Dynamic resolves names outside Columns, such as "metrics.<name>"; nil allows none.
+	Dynamic func(name string) (Column[T], bool)
+}
+
+// Names returns the names of the table's fixed columns.
+func (t Table[T]) Names() []string {
+	names := make([]string, len(t.Columns))
+	for i, column := range t.Columns {
+		names[i] = column.Name
+	}
+	return names
+}
+
+// Select resolves column names, in order. No names selects the default columns.
+func (t Table[T]) Select(names []string) ([]Column[T], error) {
+	if len(names) == 0 {
+		names = t.Default
+	}
+	selected := make([]Column[T], 0, len(names))
+next:
+	for _, name := range names {
+		for _, column := range t.Columns {
+			if column.Name == name {
+				selected = append(selected, column)
+				continue next
+			}
+		}
+		if t.Dynamic != nil {
+			if column, ok := t.Dynamic(name); ok {
+				selected = append(selected, column)
+				continue
+			}
+		}
+		return nil, fmt.Errorf("%w %q: use %s", ErrUnknownColumn, name, strings.Join(t.Names(), ", "))
+	}
+	return selected, nil
+}
+
+// ParseColumns splits a comma-separated column list, ignoring blanks.
+func ParseColumns(list string) []string {
+	var names []string
+	for _, name := range strings.Split(list, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// Write renders rows with the given columns. Values are numbers, strings, booleans, times or
+// pointers to them; nil values are left empty (null in JSON Lines).
+func Write[T any](w io.Writer, format Format, columns []Column[T], rows []T) error {
+	switch format {
+	case CSV:
+		return writeCSV(w, columns, rows)
+	case Markdown:
+		return writeMarkdown(w, columns, rows)
+	case JSONL:
+		return writeJSONL(w, columns, rows)
+	}
+	return fmt.Errorf("%w %q", ErrUnknownFormat, format)
+}
+
+func writeCSV[T any](w io.Writer, columns []Column[T], rows []T) error {
+	out := csv.NewWriter(w)
+	record := make([]string, len(columns))
+	for i, column := range columns {
+		record[i] = column.Name
+	}
+	if err := out.Write(record); err != nil {
+		return err
+	}
+	for _, row := range rows {
+		for i, column := range columns {
+			record[i] = text(column.Value(row))
+		}
+		if err := out.Write(record); err != nil {
+			return err
+		}
+	}
+	out.Flush()
+	return out.Error()
+}
+
+// writeMarkdown writes a GitHub-flavoured table; numbers are right-aligned.
+func writeMarkdown[T any](w io.Writer, columns []Column[T], rows []T) error {
+	var b strings.Builder
+	cells := make([][]string, len(rows))
+	numeric := make([]bool, len(columns))
+	for i := range numeric {
+		numeric[i] = len(rows) > 0
+	}
+	for r, row := range rows {
+		cells[r] = make([]string, len(columns))
+		for i, column := range columns {
+			value := deref(column.Value(row))
+			if value != nil && !isNumber(value) {
+				numeric[i] = false
+			}
+			cells[r][i] = escapeMarkdown(text(value))
+		}
+	}
+	b.WriteString("|")
+	for _, column := range columns {
+		b.WriteString(" " + escapeMarkdown(column.Name) + " |")
+	}
+	b.WriteString("\n|")
+	for i := range columns {
+		if numeric[i] {
+			b.WriteString(" ---: |")
+		} else {
+			b.WriteString(" --- |")
+		}
+	}
+	b.WriteString("\n")
+	for _, row := range cells {
+		b.WriteString("|")
+		for _, cell := range row {
+			b.WriteString(" " + cell + " |")
+		}
+		b.WriteString("\n")
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writeJSONL writes one object per row, its keys in column order.
+func writeJSONL[T any](w io.Writer, columns []Column[T], rows []T) error {
+	var b bytes.Buffer
+	for _, row := range rows {
+		b.Reset()
+		b.WriteByte('{')
+		for i, column := range columns {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			key, _ := json.Marshal(column.Name)
+			b.Write(key)
+			b.WriteByte(':')
+			value := deref(column.Value(row))
+			if f, ok := value.(float64); ok && (math.IsNaN(f) || math.IsInf(f, 0)) {
+				value = nil
+			}
+			raw, err := json.Marshal(value)
+			if err != nil {
+				return err
+			}
+			b.Write(raw)
+		}
+		b.WriteString("}\n")
+		if _, err := w.Write(b.Bytes()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// text formats a value for CSV and Markdown cells.
+func text(value any) string {
+	switch v := deref(value).(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case time.Time:
+		if v.IsZero() {
+			return ""
+		}
+		return v.UTC().Format(time.RFC3339)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// deref follows the pointers optional values are stored as.
+func deref(value any) any {
+	switch v := value.(type) {
+	case *float64:
+		if v == nil {
+			return nil
+		}
+		return *v
+	case *int:
+		if v == nil {
+			return nil
+		}
+		return *v
+	case *bool:
+		if v == nil {
+			return nil
+		}
+		return *v
+	case *string:
+		if v == nil {
+			return nil
+		}
+		return *v
+	}
+	return value
+}
+
+func isNumber(value any) bool {
+	switch value.(type) {
+	case int, int64, float64:
+		return true
+	}
+	return false
+}
+
+var markdownEscaper = strings.NewReplacer("|", `\|`, "\r\n", "<br>", "\n", "<br>")
+
+func escapeMarkdown(s string) string {
+	return markdownEscaper.Replace(s)
+}
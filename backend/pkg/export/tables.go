@@ -0,0 +1,171 @@
+package export
+
+import (
+	"io"
+	"strings"
+
+	"github.com/example/back-end-tcc/pkg/models"
+)
+
+// Leaderboard exports leaderboard entries. "tasks.<taskId>" selects the score of one task.
+var Leaderboard = Table[models.LeaderboardEntry]{
+	Columns: []Column[models.LeaderboardEntry]{
+		{"rank", func(e models.LeaderboardEntry) any { return e.Rank }},
+		{"benchmarkId", func(e models.LeaderboardEntry) any { return e.BenchmarkID }},
+		{"domain", func(e models.LeaderboardEntry) any { return e.Domain }},
+		{"agentId", func(e models.LeaderboardEntry) any { return e.AgentID }},
+		{"agentName", func(e models.LeaderboardEntry) any { return e.AgentName }},
+		{"submissionId", func(e models.LeaderboardEntry) any { return e.SubmissionID }},
+		{"score", func(e models.LeaderboardEntry) any { return e.Score }},
+		{"successRate", func(e models.LeaderboardEntry) any { return e.SuccessRate }},
+		{"toolCorrectness", func(e models.LeaderboardEntry) any { return e.ToolCorrectness }},
+		{"violations", func(e models.LeaderboardEntry) any { return e.Violations }},
+		{"avgTurns", func(e models.LeaderboardEntry) any { return e.AvgTurns }},
+		{"totalCost", func(e models.LeaderboardEntry) any { return e.TotalCost }},
+		{"avgLatency", func(e models.LeaderboardEntry) any { return e.AvgLatency }},
+		{"totalTokens", func(e models.LeaderboardEntry) any { return e.TotalTokens }},
+		{"passedTasks", func(e models.LeaderboardEntry) any { return e.PassedTasks }},
+		{"scorePerDollar", func(e models.LeaderboardEntry) any { return e.ScorePerDollar }},
+		{"scorePerSecond", func(e models.LeaderboardEntry) any { return e.ScorePerSecond }},
+		{"tokensPerSuccess", func(e models.LeaderboardEntry) any { return e.TokensPerSuccess }},
+		{"scoredAt", func(e models.LeaderboardEntry) any { return e.ScoredAt }},
+		{"scoringVersion", func(e models.LeaderboardEntry) any { return e.ScoringVersion }},
+	},
+	Default: []string{"rank", "benchmarkId", "agentId", "agentName", "score", "successRate", "totalCost", "avgLatency"},
+	Dynamic: func(name string) (Column[models.LeaderboardEntry], bool) {
+		taskID, ok := strings.CutPrefix(name, "tasks.")
+		return Column[models.LeaderboardEntry]{name, func(e models.LeaderboardEntry) any {
+			return lookup(e.TaskScores, taskID)
+		}}, ok && taskID != ""
+	},
+}
+
+// Summaries exports score summaries. "metrics.<name>" selects a metric, and
+// "intervals.<metric>.lower" or ".upper" a bound of its confidence interval.
+var Summaries = Table[models.ScoreSummary]{
+	Columns: []Column[models.ScoreSummary]{
+		{"submissionId", func(s models.ScoreSummary) any { return s.SubmissionID }},
+		{"agentId", func(s models.ScoreSummary) any { return s.AgentID }},
+		{"benchmarkId", func(s models.ScoreSummary) any { return s.BenchmarkID }},
+		{"score", func(s models.ScoreSummary) any { return s.Score }},
+		{"successRate", func(s models.ScoreSummary) any { return s.SuccessRate }},
+		{"toolCorrectness", func(s models.ScoreSummary) any { return s.ToolCorrectness }},
+		{"violations", func(s models.ScoreSummary) any { return s.Violations }},
+		{"avgTurns", func(s models.ScoreSummary) any { return s.AvgTurns }},
+		{"totalCost", func(s models.ScoreSummary) any { return s.TotalCost }},
+		{"avgLatency", func(s models.ScoreSummary) any { return s.AvgLatency }},
+		{"totalTokens", func(s models.ScoreSummary) any { return s.TotalTokens }},
+		{"tasks", func(s models.ScoreSummary) any { return len(s.Tasks) }},
+		{"calculated", func(s models.ScoreSummary) any { return s.Calculated }},
+		{"scoringVersion", func(s models.ScoreSummary) any { return s.ScoringVersion }},
+	},
+	Default: []string{"submissionId", "agentId", "benchmarkId", "score", "successRate", "totalCost", "avgLatency", "scoringVersion"},
+	Dynamic: func(name string) (Column[models.ScoreSummary], bool) {
+		if metric, ok := strings.CutPrefix(name, "metrics."); ok && metric != "" {
+			return Column[models.ScoreSummary]{name, func(s models.ScoreSummary) any { return lookup(s.Metrics, metric) }}, true
+		}
+		rest, ok := strings.CutPrefix(name, "intervals.")
+		if !ok {
+			return Column[models.ScoreSummary]{}, false
+		}
+		metric, bound := rest, ""
+		if i := strings.LastIndex(rest, "."); i > 0 {
+			metric, bound = rest[:i], rest[i+1:]
+		}
+		if bound != "lower" && bound != "upper" {
+			return Column[models.ScoreSummary]{}, false
+		}
+		return Column[models.ScoreSummary]{name, func(s models.ScoreSummary) any {
+			interval, ok := s.Intervals[metric]
+			switch {
+			case !ok:
+				return nil
+			case bound == "lower":
+				return interval.Lower
+			default:
+				return interval.Upper
+			}
+		}}, true
+	},
+}
+
+// TaskRow is a task's score within a submission's summary.
+type TaskRow struct {
+	SubmissionID string
+	AgentID      string
+	BenchmarkID  string
+	models.TaskScore
+}
+
+// TaskRows flattens the per-task breakdown of summaries.
+func TaskRows(summaries []models.ScoreSummary) []TaskRow {
+	var rows []TaskRow
+	for _, summary := range summaries {
+		for _, task := range summary.Tasks {
+			rows = append(rows, TaskRow{SubmissionID: summary.SubmissionID, AgentID: summary.AgentID, BenchmarkID: summary.BenchmarkID, TaskScore: task})
+		}
+	}
+	return rows
+}
+
+// Tasks exports per-task results. "grades.<type>" selects the score of a grader type.
+var Tasks = Table[TaskRow]{
+	Columns: []Column[TaskRow]{
+		{"submissionId", func(t TaskRow) any { return t.SubmissionID }},
+		{"agentId", func(t TaskRow) any { return t.AgentID }},
+		{"benchmarkId", func(t TaskRow) any { return t.BenchmarkID }},
+		{"taskId", func(t TaskRow) any { return t.TaskID }},
+		{"status", func(t TaskRow) any { return t.Status }},
+		{"score", func(t TaskRow) any { return t.Score }},
+		{"expectedTool", func(t TaskRow) any { return t.ExpectedTool }},
+		{"toolCorrect", func(t TaskRow) any { return t.ToolCorrect }},
+		{"violations", func(t TaskRow) any { return t.Violations }},
+		{"turns", func(t TaskRow) any { return t.Turns }},
+		{"cost", func(t TaskRow) any { return t.Cost }},
+		{"latency", func(t TaskRow) any { return t.Latency }},
+		{"tokens", func(t TaskRow) any { return t.Tokens }},
+	},
+	Default: []string{"submissionId", "agentId", "taskId", "status", "score", "turns", "cost", "latency"},
+	Dynamic: func(name string) (Column[TaskRow], bool) {
+		graderType, ok := strings.CutPrefix(name, "grades.")
+		return Column[TaskRow]{name, func(t TaskRow) any {
+			for _, grade := range t.Grades {
+				if grade.Type == graderType {
+					return grade.Score
+				}
+			}
+			return nil
+		}}, ok && graderType != ""
+	},
+}
+
+// WriteLeaderboard renders leaderboard entries; no columns selects the default ones.
+func WriteLeaderboard(w io.Writer, format Format, columns []string, entries []models.LeaderboardEntry) error {
+	return write(w, format, Leaderboard, columns, entries)
+}
+
+// WriteSummaries renders score summaries; no columns selects the default ones.
+func WriteSummaries(w io.Writer, format Format, columns []string, summaries []models.ScoreSummary) error {
+	return write(w, format, Summaries, columns, summaries)
+}
+
+// WriteTasks renders the per-task results of score summaries; no columns selects the default ones.
+func WriteTasks(w io.Writer, format Format, columns []string, summaries []models.ScoreSummary) error {
+	return write(w, format, Tasks, columns, TaskRows(summaries))
+}
+
+func write[T any](w io.Writer, format Format, table Table[T], names []string, rows []T) error {
+	columns, err := table.Select(names)
+	if err != nil {
+		return err
+	}
+	return Write(w, format, columns, rows)
+}
+
+// lookup returns a map value, or nil when the key is missing.
+func lookup(values map[string]float64, key string) any {
+	if value, ok := values[key]; ok {
+		return value
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+// Package formula composes the headline score of a benchmark from weighted, normalised metrics.
+package formula
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/example/back-end-tcc/pkg/models"
+)
+
+// Metrics a formula can weigh. They name the ScoreSummary fields, with accuracy for the mean task score.
+const (
+	Accuracy        = "accuracy"
+	SuccessRate     = "successRate"
+	ToolCorrectness = "toolCorrectness"
+	Violations      = "violations"
+	AvgTurns        = "avgTurns"
+	TotalCost       = "totalCost"
+	AvgLatency      = "avgLatency"
+)
+
+// lowerIsBetter tells, per metric, whether the metric is a penalty whose normalised value is inverted.
+var lowerIsBetter = map[string]bool{
+	Accuracy:        false,
+	SuccessRate:     false,
+	ToolCorrectness: false,
+	Violations:      true,
+	AvgTurns:        true,
+	TotalCost:       true,
+	AvgLatency:      true,
+}
+
+// Metrics returns the metric names a formula can weigh, sorted.
+func Metrics() []string {
+	names := make([]string, 0, len(lowerIsBetter))
+	for name := range lowerIsBetter {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Default scores a benchmark by its mean task score.
+func Default() *models.ScoreFormula {
+	return &models.ScoreFormula{Terms: []models.ScoreTerm{{Metric: Accuracy, Weight: 1, Min: 0, Max: 1}}}
+}
+
+// Normalize validates f and returns a copy with default ranges filled in: rates without a range
+// span 0 to 1, while penalties need an explicit Max. A nil formula is the Default.
+func Normalize(f *models.ScoreFormula) (*models.ScoreFormula, error) {
+	if f == nil {
+		return Default(), nil
+	}
+	if len(f.Terms) == 0 {
+		return nil, errors.New("score formula needs at least one term")
+	}
+	out := &models.ScoreFormula{Terms: make([]models.ScoreTerm, len(f.Terms))}
+	total := 0.0
+	for i, term := range f.Terms {
+		penalty, ok := lowerIsBetter[term.Metric]
+		if !ok {
+			return nil, fmt.Errorf("score formula: unknown metric %q (expected one of %s)", term.Metric, strings.Join(Metrics(), ", "))
+		}
+		if term.Weight < 0 {
+			return nil, fmt.Errorf("score formula: weight of %s must not be negative", term.Metric)
+		}
+		if term.Min == 0 && term.Max == 0 && !penalty {
+			term.Max = 1
+		}
+		if term.Max <= term.Min {
+			return nil, fmt.Errorf("score formula: %s needs a range with max above min", term.Metric)
+		}
+		total += term.Weight
+		out.Terms[i] = term
+	}
+	if total == 0 {
+		return nil, errors.New("score formula weights must not all be zero")
+	}
+	return out, nil
+}
+
+// Values returns the formula metrics of a summary, leaving out ToolCorrectness when it is unset.
+func Values(summary models.ScoreSummary) map[string]float64 {
+	values := map[string]float64{
+		Accuracy:    summary.Metrics["accuracy"],
+		SuccessRate: summary.SuccessRate,
+		Violations:  float64(summary.Violations),
+		AvgTurns:    summary.AvgTurns,
+		TotalCost:   summary.TotalCost,
+		AvgLatency:  summary.AvgLatency,
+	}
+	if summary.ToolCorrectness != nil {
+		values[ToolCorrectness] = *summary.ToolCorrectness
+	}
+	return values
+}
+
+// Compose returns the weighted mean of the normalised metrics, and the component each term adds.
+// Values are clamped to the term's range and scaled to 0-1, and penalties are inverted so lower
+// values score higher. Terms whose metric is missing from values are dropped and the remaining
+// weights renormalised. f must be normalised.
+func Compose(f *models.ScoreFormula, values map[string]float64) (float64, []models.ScoreComponent) {
+	total := 0.0
+	for _, term := range f.Terms {
+		if _, ok := values[term.Metric]; ok {
+			total += term.Weight
+		}
+	}
+	score := 0.0
+	components := make([]models.ScoreComponent, 0, len(f.Terms))
+	if total == 0 {
+		return score, components
+	}
+	for _, term := range f.Terms {
+		value, ok := values[term.Metric]
+		if !ok {
+			continue
+		}
+		normalized := (min(max(value, term.Min), term.Max) - term.Min) / (term.Max - term.Min)
+		if lowerIsBetter[term.Metric] {
+			normalized = 1 - normalized
+		}
+		contribution := term.Weight / total * normalized
+		score += contribution
+		components = append(components, models.ScoreComponent{
+			Metric:       term.Metric,
+			Value:        value,
+			Normalized:   normalized,
+			Weight:       term.Weight,
+			Contribution: contribution,
+		})
+	}
+	return score, components
+}
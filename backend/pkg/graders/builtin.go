@@ -0,0 +1,212 @@
+package graders
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/example/back-end-tcc/pkg/jsonschema"
+	"github.com/example/back-end-tcc/pkg/models"
+	"github.com/example/back-end-tcc/pkg/sandbox"
+)
+
+// DefaultCommandTimeout bounds command graders that set no timeout.
+const DefaultCommandTimeout = 5 * time.Minute
+
+// GraderFunc adapts a function to the Grader interface.
+type GraderFunc func(ctx context.Context, in Input) (Result, error)
+
+// Grade calls f.
+func (f GraderFunc) Grade(ctx context.Context, in Input) (Result, error) {
+	return f(ctx, in)
+}
+
+// pass converts a check into a full or zero score.
+func pass(ok bool, detail string) Result {
+	if ok {
+		return Result{Score: 1, Detail: detail}
+	}
+	return Result{Detail: detail}
+}
+
+// newExact compares the subject, without surrounding whitespace, with the expected text.
+func newExact(spec models.Grader) (Grader, error) {
+	return GraderFunc(func(ctx context.Context, in Input) (Result, error) {
+		text, err := subject(in, spec.Path)
+		if err != nil {
+			return Result{}, err
+		}
+		ok := strings.TrimSpace(text) == strings.TrimSpace(spec.Expected)
+		return pass(ok, fmt.Sprintf("expected %q", spec.Expected)), nil
+	}), nil
+}
+
+// newNormalized compares case-insensitively, ignoring runs of whitespace and punctuation around words.
+func newNormalized(spec models.Grader) (Grader, error) {
+	expected := normalize(spec.Expected)
+	return GraderFunc(func(ctx context.Context, in Input) (Result, error) {
+		text, err := subject(in, spec.Path)
+		if err != nil {
+			return Result{}, err
+		}
+		return pass(normalize(text) == expected, fmt.Sprintf("expected %q after normalization", expected)), nil
+	}), nil
+}
+
+// normalize lowercases s and trims punctuation from its words, keeping e.g. decimal points.
+func normalize(s string) string {
+	var words []string
+	for _, word := range strings.Fields(strings.ToLower(s)) {
+		if word = strings.TrimFunc(word, unicode.IsPunct); word != "" {
+			words = append(words, word)
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// newRegex passes when the pattern matches anywhere in the subject.
+func newRegex(spec models.Grader) (Grader, error) {
+	if spec.Pattern == "" {
+		return nil, errors.New("pattern is required")
+	}
+	re, err := regexp.Compile(spec.Pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern: %w", err)
+	}
+	return GraderFunc(func(ctx context.Context, in Input) (Result, error) {
+		text, err := subject(in, spec.Path)
+		if err != nil {
+			return Result{}, err
+		}
+		return pass(re.MatchString(text), fmt.Sprintf("pattern %q", spec.Pattern)), nil
+	}), nil
+}
+
+var numberPattern = regexp.MustCompile(`[-+]?(\d[\d,]*\.?\d*|\.\d+)([eE][-+]?\d+)?`)
+
+// newNumeric compares the last number in the subject with the expected value.
+func newNumeric(spec models.Grader) (Grader, error) {
+	expected, err := strconv.ParseFloat(strings.TrimSpace(spec.Expected), 64)
+	if err != nil {
+		return nil, fmt.Errorf("expected must be a number: %w", err)
+	}
+	if spec.Tolerance < 0 {
+		return nil, errors.New("tolerance must not be negative")
+	}
+	return GraderFunc(func(ctx context.Context, in Input) (Result, error) {
+		text, err := subject(in, spec.Path)
+		if err != nil {
+			return Result{}, err
+		}
+		matches := numberPattern.FindAllString(text, -1)
+		if len(matches) == 0 {
+			return Result{Detail: "no number found"}, nil
+		}
+		got, err := strconv.ParseFloat(strings.ReplaceAll(matches[len(matches)-1], ",", ""), 64)
+		if err != nil {
+			return Result{Detail: fmt.Sprintf("cannot parse %q", matches[len(matches)-1])}, nil
+		}
+		ok := math.Abs(got-expected) <= spec.Tolerance
+		return pass(ok, fmt.Sprintf("got %g, expected %g ± %g", got, expected, spec.Tolerance)), nil
+	}), nil
+}
+
+var fencedJSON = regexp.MustCompile("(?s)```(?:json)?\\s*\\n(.*?)```")
+
+// newJSONSchema validates the JSON document in the subject: the whole text, or its first fenced code block.
+func newJSONSchema(spec models.Grader) (Grader, error) {
+	if len(spec.Schema) == 0 {
+		return nil, errors.New("schema is required")
+	}
+	schema, err := jsonschema.Compile(spec.Schema)
+	if err != nil {
+		return nil, err
+	}
+	return GraderFunc(func(ctx context.Context, in Input) (Result, error) {
+		text, err := subject(in, spec.Path)
+		if err != nil {
+			return Result{}, err
+		}
+		text = strings.TrimSpace(text)
+		if m := fencedJSON.FindStringSubmatch(text); m != nil && !json.Valid([]byte(text)) {
+			text = m[1]
+		}
+		var doc interface{}
+		if err := json.Unmarshal([]byte(text), &doc); err != nil {
+			return Result{Detail: fmt.Sprintf("not valid JSON: %v", err)}, nil
+		}
+		if err := schema.Validate(doc); err != nil {
+			return Result{Detail: err.Error()}, nil
+		}
+		return Result{Score: 1, Detail: "document conforms to the schema"}, nil
+	}), nil
+}
+
+// newToolSequence checks that the tools were called in order, other calls in between allowed.
+// The score is the fraction of the sequence found.
+func newToolSequence(spec models.Grader) (Grader, error) {
+	if len(spec.Tools) == 0 {
+		return nil, errors.New("tools are required")
+	}
+	return GraderFunc(func(ctx context.Context, in Input) (Result, error) {
+		found := 0
+		for _, call := range in.ToolCalls {
+			if found < len(spec.Tools) && call == spec.Tools[found] {
+				found++
+			}
+		}
+		detail := fmt.Sprintf("%d of %d tools called in order", found, len(spec.Tools))
+		if found < len(spec.Tools) {
+			detail += fmt.Sprintf("; missing %s", spec.Tools[found])
+		}
+		return Result{Score: float64(found) / float64(len(spec.Tools)), Detail: detail}, nil
+	}), nil
+}
+
+// newCommand runs a hidden test in the sandbox and passes when it exits with the expected code.
+func newCommand(spec models.Grader) (Grader, error) {
+	if spec.Command == "" {
+		return nil, errors.New("command is required")
+	}
+	if spec.TimeoutSeconds < 0 {
+		return nil, errors.New("timeoutSeconds must not be negative")
+	}
+	timeout := DefaultCommandTimeout
+	if spec.TimeoutSeconds > 0 {
+		timeout = time.Duration(spec.TimeoutSeconds) * time.Second
+	}
+	return GraderFunc(func(ctx context.Context, in Input) (Result, error) {
+		if in.Sandbox == nil {
+			return Result{}, errors.New("no sandbox to run the command in")
+		}
+		res, err := in.Sandbox.Exec(ctx, []string{"sh", "-c", spec.Command}, sandbox.ExecOptions{Timeout: timeout})
+		if err != nil {
+			return Result{}, err
+		}
+		if res.TimedOut {
+			return Result{Detail: fmt.Sprintf("timed out after %s", timeout)}, nil
+		}
+		detail := fmt.Sprintf("exit code %d, expected %d", res.ExitCode, spec.ExitCode)
+		if res.ExitCode != spec.ExitCode {
+			if out := strings.TrimSpace(res.Stdout + res.Stderr); out != "" {
+				detail += ": " + tail(out, 500)
+			}
+		}
+		return pass(res.ExitCode == spec.ExitCode, detail), nil
+	}), nil
+}
+
+// tail keeps the last n bytes of s, where test failures are usually reported.
+func tail(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[len(s)-n:]
+}
@@ -0,0 +1,165 @@
+package graders
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"sync"
+
+	"github.com/example/back-end-tcc/pkg/models"
+	"github.com/example/back-end-tcc/pkg/sandbox"
+)
+
+// MaxGradedFileBytes bounds the workdir files graders read through Path.
+const MaxGradedFileBytes = 1 << 20
+
+// Input is what graders see of a finished task.
+type Input struct {
+	Prompt    string
+	Response  string   // Final answer of the agent
+	ToolCalls []string // Names of the executed tool calls, in order
+	Sandbox   sandbox.Sandbox
+	Judge     Judge // Used by llm_judge graders; nil uses the default HTTP judge
+}
+
+// Result is the score, between 0 and 1, a grader assigned and a short explanation.
+type Result struct {
+	Score  float64
+	Detail string
+}
+
+// Grader scores one aspect of a task outcome. Errors mean the grader could not decide and score 0.
+type Grader interface {
+	Grade(ctx context.Context, in Input) (Result, error)
+}
+
+// Factory builds a grader from its task definition, validating the parameters.
+type Factory func(spec models.Grader) (Grader, error)
+
+var (
+	mu        sync.RWMutex
+	factories = map[string]Factory{
+		"exact":         newExact,
+		"normalized":    newNormalized,
+		"regex":         newRegex,
+		"numeric":       newNumeric,
+		"json_schema":   newJSONSchema,
+		"tool_sequence": newToolSequence,
+		"command":       newCommand,
+		"llm_judge":     newLLMJudge,
+	}
+)
+
+// Register makes a grader type available to tasks, replacing any grader of the same type.
+func Register(typ string, factory Factory) {
+	mu.Lock()
+	defer mu.Unlock()
+	factories[typ] = factory
+}
+
+// Types lists the registered grader types.
+func Types() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	types := make([]string, 0, len(factories))
+	for typ := range factories {
+		types = append(types, typ)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// New builds the grader a spec describes.
+func New(spec models.Grader) (Grader, error) {
+	mu.RLock()
+	factory, ok := factories[spec.Type]
+	mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown grader type %q", spec.Type)
+	}
+	if spec.Weight < 0 {
+		return nil, errors.New("grader weight must not be negative")
+	}
+	return factory(spec)
+}
+
+// Validate checks the graders and pass threshold of a task.
+func Validate(specs []models.Grader, threshold float64) error {
+	if threshold < 0 || threshold > 1 {
+		return errors.New("passThreshold must be between 0 and 1")
+	}
+	for i, spec := range specs {
+		if _, err := New(spec); err != nil {
+			return fmt.Errorf("grader %d (%s): %w", i, spec.Type, err)
+		}
+	}
+	return nil
+}
+
+// Outcome combines the grades of a task.
+type Outcome struct {
+	Score  float64 // Weighted mean of the grader scores
+	Passed bool    // Score reached the pass threshold
+	Grades []models.Grade
+}
+
+// Evaluate runs every grader and combines their scores. A zero threshold means 1, so by default
+// every grader must give full marks.
+func Evaluate(ctx context.Context, specs []models.Grader, threshold float64, in Input) Outcome {
+	if threshold == 0 {
+		threshold = 1
+	}
+	var out Outcome
+	var total, weights float64
+	for _, spec := range specs {
+		weight := spec.Weight
+		if weight == 0 {
+			weight = 1
+		}
+		grade := models.Grade{Type: spec.Type, Weight: weight}
+		res, err := run(ctx, spec, in)
+		if err != nil {
+			grade.Error = err.Error()
+		} else {
+			grade.Score = min(max(res.Score, 0), 1)
+			grade.Detail = res.Detail
+		}
+		out.Grades = append(out.Grades, grade)
+		total += grade.Score * weight
+		weights += weight
+	}
+	if weights > 0 {
+		out.Score = total / weights
+	}
+	// Tolerate rounding in weighted means that should reach the threshold exactly.
+	out.Passed = len(specs) > 0 && out.Score >= threshold-1e-9
+	return out
+}
+
+func run(ctx context.Context, spec models.Grader, in Input) (Result, error) {
+	g, err := New(spec)
+	if err != nil {
+		return Result{}, err
+	}
+	return g.Grade(ctx, in)
+}
+
+// subject returns the text a grader checks: the workdir file at p, or the final response.
+func subject(in Input, p string) (string, error) {
+	if p == "" {
+		return in.Response, nil
+	}
+	if in.Sandbox == nil {
+		return "", errors.New("no sandbox to read files from")
+	}
+	if !path.IsAbs(p) {
+		p = path.Join(in.Sandbox.Workdir(), p)
+	}
+	data, _, err := sandbox.ReadFile(in.Sandbox, p, MaxGradedFileBytes)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
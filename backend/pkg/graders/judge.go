@@ -0,0 +1,146 @@
+package graders
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/example/back-end-tcc/pkg/models"
+)
+
+// DefaultJudgeModel grades llm_judge graders that name no model.
+const DefaultJudgeModel = "gpt-4"
+
+// JudgeRequest is what the judge model is asked to grade.
+type JudgeRequest struct {
+	Model    string
+	Rubric   string
+	Prompt   string
+	Response string
+}
+
+// Judge grades a response against a rubric with a language model.
+type Judge interface {
+	Judge(ctx context.Context, req JudgeRequest) (Result, error)
+}
+
+// HTTPJudge calls an OpenAI-compatible chat completions endpoint. Zero fields use the OpenAI API
+// and the OPENAI_API_KEY environment variable.
+type HTTPJudge struct {
+	Endpoint string
+	APIKey   string
+	Client   *http.Client
+}
+
+const judgeSystemPrompt = "You are a strict grader. Grade the response to the task against the rubric. " +
+	"Explain your reasoning briefly, then end with a final line of the form 'SCORE: n' where n is an integer from 0 (fails the rubric) to 10 (fully satisfies it)."
+
+var judgeScore = regexp.MustCompile(`(?i)SCORE:\s*(\d+(?:\.\d+)?)`)
+
+// Judge asks the model for a 0-10 score and scales it to 0-1.
+func (j HTTPJudge) Judge(ctx context.Context, req JudgeRequest) (Result, error) {
+	endpoint := j.Endpoint
+	if endpoint == "" {
+		endpoint = "https://api.openai.com/v1/chat/completions"
+	}
+	apiKey := j.APIKey
+	if apiKey == "" {
+		apiKey = os.Getenv("OPENAI_API_KEY")
+	}
+	client := j.Client
+	if client == nil {
+		client = &http.Client{Timeout: 60 * time.Second}
+	}
+
+	body, err := json.Marshal(map[string]interface{}{
+		"model": req.Model,
+		"messages": []map[string]string{
+			{"role": "system", "content": judgeSystemPrompt},
+			{"role": "user", "content": fmt.Sprintf("Task:\n%s\n\nRubric:\n%s\n\nResponse:\n%s", req.Prompt, req.Rubric, req.Response)},
+		},
+		"temperature": 0,
+	})
+	if err != nil {
+		return Result{}, err
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return Result{}, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if apiKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+apiKey)
+	}
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return Result{}, err
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return Result{}, fmt.Errorf("judge api error: %s - %s", resp.Status, string(data))
+	}
+
+	var completion struct {
+		Choices []struct {
+			Message struct {
+				Content string `json:"content"`
+			} `json:"message"`
+		} `json:"choices"`
+	}
+	if err := json.Unmarshal(data, &completion); err != nil {
+		return Result{}, err
+	}
+	if len(completion.Choices) == 0 {
+		return Result{}, errors.New("no choices in judge response")
+	}
+	return parseVerdict(completion.Choices[0].Message.Content)
+}
+
+// parseVerdict reads the last SCORE line of a judge answer.
+func parseVerdict(content string) (Result, error) {
+	matches := judgeScore.FindAllStringSubmatch(content, -1)
+	if len(matches) == 0 {
+		return Result{}, errors.New("judge answer has no SCORE line")
+	}
+	score, err := strconv.ParseFloat(matches[len(matches)-1][1], 64)
+	if err != nil || score > 10 {
+		return Result{}, fmt.Errorf("invalid judge score %q", matches[len(matches)-1][1])
+	}
+	return Result{Score: score / 10, Detail: strings.TrimSpace(content)}, nil
+}
+
+// newLLMJudge grades the subject against a rubric. The "mock" model gives full marks without
+// calling a model, like mock agents.
+func newLLMJudge(spec models.Grader) (Grader, error) {
+	if strings.TrimSpace(spec.Rubric) == "" {
+		return nil, errors.New("rubric is required")
+	}
+	model := spec.Model
+	if model == "" {
+		model = DefaultJudgeModel
+	}
+	return GraderFunc(func(ctx context.Context, in Input) (Result, error) {
+		text, err := subject(in, spec.Path)
+		if err != nil {
+			return Result{}, err
+		}
+		if model == "mock" {
+			return Result{Score: 1, Detail: "mock judge"}, nil
+		}
+		judge := in.Judge
+		if judge == nil {
+			judge = HTTPJudge{}
+		}
+		return judge.Judge(ctx, JudgeRequest{Model: model, Rubric: spec.Rubric, Prompt: in.Prompt, Response: text})
+	}), nil
+}
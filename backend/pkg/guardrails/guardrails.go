@@ -0,0 +1,212 @@
+package guardrails
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+
+	"github.com/example/back-end-tcc/pkg/models"
+)
+
+// Decision actions.
+const (
+	ActionAllow = "allow"
+	ActionDeny  = "deny"
+	ActionAbort = "abort"
+)
+
+// Rule names reported on decisions.
+const (
+	RuleForbiddenTool    = "forbidden_tool"
+	RuleForbiddenPattern = "forbidden_pattern"
+	RuleMaxCalls         = "max_calls"
+)
+
+// anyTool is the MaxCallsPerTool key limiting calls across all tools.
+const anyTool = "*"
+
+// presets are built-in pattern sets that policies can enable by name.
+var presets = map[string][]models.PatternRule{
+	"destructive": {
+		{Pattern: `\brm\s+(-[a-zA-Z]*\s+)*-[a-zA-Z]*[rR][a-zA-Z]*\s+(-[a-zA-Z]*\s+)*(/|/\*|~|\$HOME)(\s|$|;|&|\|)`, Message: "recursive deletion of the filesystem root or home directory is not allowed"},
+		{Pattern: `\bmkfs(\.\w+)?\b|\bdd\s+.*\bof=/dev/`, Message: "formatting or overwriting block devices is not allowed"},
+		{Pattern: `:\(\)\s*\{\s*:\|:&\s*\};:`, Message: "fork bombs are not allowed"},
+		{Pattern: `\b(shutdown|reboot|halt|poweroff)\b`, Message: "power management commands are not allowed"},
+	},
+	"network": {
+		{Pattern: `\b(curl|wget|nc|ncat|netcat|telnet|ssh|scp|sftp|ftp|rsync)\b`, Message: "network commands are not allowed"},
+		{Pattern: `\b(pip3?|npm|yarn|apt-get|apt|apk)\s+(install|add)\b`, Message: "installing packages from the network is not allowed"},
+		{Pattern: `/dev/(tcp|udp)/`, Message: "raw network sockets are not allowed"},
+	},
+}
+
+// Decision is the outcome of evaluating a tool call.
+type Decision struct {
+	Action  string `json:"action"`
+	Rule    string `json:"rule,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+// Allowed reports whether the tool call may proceed.
+func (d Decision) Allowed() bool {
+	return d.Action == ActionAllow
+}
+
+type compiledPattern struct {
+	tool    string
+	re      *regexp.Regexp
+	action  string
+	message string
+}
+
+// Engine evaluates tool calls against the policies attached to a benchmark and its task.
+// It keeps per-task call counts, so a new Engine is needed for every task run.
+type Engine struct {
+	action    string
+	forbidden map[string]bool
+	patterns  []compiledPattern
+	maxCalls  map[string]int
+	calls     map[string]int
+	total     int
+}
+
+// New merges the given policies (nil entries are skipped) into a single engine.
+// Later policies override the default action of earlier ones; limits keep the strictest value.
+func New(policies ...*models.GuardrailPolicy) (*Engine, error) {
+	e := &Engine{
+		action:    ActionDeny,
+		forbidden: make(map[string]bool),
+		maxCalls:  make(map[string]int),
+		calls:     make(map[string]int),
+	}
+	for _, p := range policies {
+		if p == nil {
+			continue
+		}
+		if p.Action != "" {
+			if err := validAction(p.Action); err != nil {
+				return nil, err
+			}
+			e.action = p.Action
+		}
+		for _, tool := range p.ForbiddenTools {
+			e.forbidden[tool] = true
+		}
+		for tool, limit := range p.MaxCallsPerTool {
+			if limit < 0 {
+				return nil, fmt.Errorf("guardrails: negative call limit for %s", tool)
+			}
+			if current, ok := e.maxCalls[tool]; !ok || limit < current {
+				e.maxCalls[tool] = limit
+			}
+		}
+		rules := append([]models.PatternRule(nil), p.ForbiddenPatterns...)
+		for _, name := range p.Presets {
+			preset, ok := presets[name]
+			if !ok {
+				return nil, fmt.Errorf("guardrails: unknown preset %q", name)
+			}
+			rules = append(rules, preset...)
+		}
+		for _, rule := range rules {
+			re, err := regexp.Compile(rule.Pattern)
+			if err != nil {
+				return nil, fmt.Errorf("guardrails: invalid pattern %q: %w", rule.Pattern, err)
+			}
+			if rule.Action != "" {
+				if err := validAction(rule.Action); err != nil {
+					return nil, err
+				}
+			}
+			e.patterns = append(e.patterns, compiledPattern{tool: rule.Tool, re: re, action: rule.Action, message: rule.Message})
+		}
+	}
+	return e, nil
+}
+
+// Validate reports configuration errors in a policy without building an engine.
+func Validate(policy *models.GuardrailPolicy) error {
+	_, err := New(policy)
+	return err
+}
+
+// Evaluate decides whether a tool call may run. Allowed calls count towards the call limits.
+func (e *Engine) Evaluate(tool, args string) Decision {
+	if e.forbidden[tool] {
+		return Decision{Action: e.action, Rule: RuleForbiddenTool, Message: fmt.Sprintf("tool %s is not allowed for this task", tool)}
+	}
+
+	values := argumentValues(args)
+	for _, p := range e.patterns {
+		if p.tool != "" && p.tool != tool {
+			continue
+		}
+		for _, v := range values {
+			if !p.re.MatchString(v) {
+				continue
+			}
+			action := p.action
+			if action == "" {
+				action = e.action
+			}
+			message := p.message
+			if message == "" {
+				message = fmt.Sprintf("arguments match forbidden pattern %q", p.re.String())
+			}
+			return Decision{Action: action, Rule: RuleForbiddenPattern, Message: message}
+		}
+	}
+
+	if limit, ok := e.maxCalls[tool]; ok && e.calls[tool] >= limit {
+		return Decision{Action: e.action, Rule: RuleMaxCalls, Message: fmt.Sprintf("tool %s may be called at most %d times", tool, limit)}
+	}
+	if limit, ok := e.maxCalls[anyTool]; ok && e.total >= limit {
+		return Decision{Action: e.action, Rule: RuleMaxCalls, Message: fmt.Sprintf("at most %d tool calls are allowed", limit)}
+	}
+
+	e.calls[tool]++
+	e.total++
+	return Decision{Action: ActionAllow}
+}
+
+func validAction(action string) error {
+	switch action {
+	case ActionDeny, ActionAbort:
+		return nil
+	default:
+		return fmt.Errorf("guardrails: unknown action %q", action)
+	}
+}
+
+// argumentValues flattens the string values of the JSON arguments so patterns see unescaped text.
+// The raw arguments are included as well to catch content hidden in keys or malformed JSON.
+func argumentValues(args string) []string {
+	values := []string{args}
+	var decoded interface{}
+	if err := json.Unmarshal([]byte(args), &decoded); err != nil {
+		return values
+	}
+	var walk func(v interface{})
+	walk = func(v interface{}) {
+		switch t := v.(type) {
+		case string:
+			values = append(values, t)
+		case []interface{}:
+			for _, item := range t {
+				walk(item)
+			}
+		case map[string]interface{}:
+			keys := make([]string, 0, len(t))
+			for k := range t {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				walk(t[k])
+			}
+		}
+	}
+	walk(decoded)
+	return values
+}
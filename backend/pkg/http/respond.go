@@ -19,3 +19,11 @@ func JSON(w stdhttp.ResponseWriter, status int, payload interface{}) {
 func Error(w stdhttp.ResponseWriter, status int, message string) {
 	JSON(w, status, map[string]string{"error": message})
 }
+
+// Attachment writes body as a file download with the given content type.
+func Attachment(w stdhttp.ResponseWriter, contentType, filename string, body []byte) {
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+	w.WriteHeader(stdhttp.StatusOK)
+	_, _ = w.Write(body)
+}
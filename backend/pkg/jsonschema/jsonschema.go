@@ -0,0 +1,321 @@
+package jsonschema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Schema is the subset of JSON Schema used to describe tool parameters and structured outputs.
+// Compile rejects keywords outside it rather than ignoring them.
+type Schema struct {
+	Dialect              string             `json:"$schema,omitempty"`
+	Type                 TypeList           `json:"type,omitempty"`
+	Title                string             `json:"title,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *Additional        `json:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Enum                 []interface{}      `json:"enum,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+	MinItems             *int               `json:"minItems,omitempty"`
+	MaxItems             *int               `json:"maxItems,omitempty"`
+
+	pattern *regexp.Regexp
+}
+
+// TypeList accepts both the string and array forms of the "type" keyword.
+type TypeList []string
+
+// UnmarshalJSON decodes "type": "string" as well as "type": ["string", "null"].
+func (t *TypeList) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*t = TypeList{single}
+		return nil
+	}
+	var many []string
+	if err := json.Unmarshal(data, &many); err != nil {
+		return fmt.Errorf("type must be a string or an array of strings")
+	}
+	*t = many
+	return nil
+}
+
+// Additional models "additionalProperties", which is either a boolean or a schema.
+type Additional struct {
+	Allowed bool
+	Schema  *Schema
+}
+
+// UnmarshalJSON decodes the boolean or schema form.
+func (a *Additional) UnmarshalJSON(data []byte) error {
+	var allowed bool
+	if err := json.Unmarshal(data, &allowed); err == nil {
+		a.Allowed = allowed
+		return nil
+	}
+	var schema Schema
+	if err := decodeStrict(data, &schema); err != nil {
+		return err
+	}
+	a.Allowed = true
+	a.Schema = &schema
+	return nil
+}
+
+// MarshalJSON encodes the boolean or schema form.
+func (a Additional) MarshalJSON() ([]byte, error) {
+	if a.Schema != nil {
+		return json.Marshal(a.Schema)
+	}
+	return json.Marshal(a.Allowed)
+}
+
+// Violation describes a single schema mismatch.
+type Violation struct {
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+// ValidationError aggregates every violation found for a document.
+type ValidationError struct {
+	Violations []Violation `json:"violations"`
+}
+
+func (e *ValidationError) Error() string {
+	parts := make([]string, 0, len(e.Violations))
+	for _, v := range e.Violations {
+		parts = append(parts, fmt.Sprintf("%s: %s", v.Path, v.Message))
+	}
+	return "schema validation failed: " + strings.Join(parts, "; ")
+}
+
+// Compile converts any JSON-compatible schema definition (maps, structs, raw JSON) into a Schema.
+// Keywords the package does not implement, such as oneOf, $ref or format, are an error.
+func Compile(definition interface{}) (*Schema, error) {
+	var raw []byte
+	switch d := definition.(type) {
+	case *Schema:
+		raw, _ = json.Marshal(d)
+	case []byte:
+		raw = d
+	case json.RawMessage:
+		raw = d
+	case string:
+		raw = []byte(d)
+	default:
+		encoded, err := json.Marshal(definition)
+		if err != nil {
+			return nil, fmt.Errorf("invalid schema: %w", err)
+		}
+		raw = encoded
+	}
+	var schema Schema
+	if err := decodeStrict(raw, &schema); err != nil {
+		return nil, fmt.Errorf("invalid schema: %w", err)
+	}
+	if err := schema.compilePatterns(); err != nil {
+		return nil, err
+	}
+	return &schema, nil
+}
+
+// decodeStrict decodes a schema, failing on unknown keywords.
+func decodeStrict(data []byte, schema *Schema) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(schema); err != nil {
+		if keyword, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+			return fmt.Errorf("unsupported keyword %s", keyword)
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *Schema) compilePatterns() error {
+	if s == nil {
+		return nil
+	}
+	if s.Pattern != "" {
+		re, err := regexp.Compile(s.Pattern)
+		if err != nil {
+			return fmt.Errorf("invalid pattern %q: %w", s.Pattern, err)
+		}
+		s.pattern = re
+	}
+	for _, prop := range s.Properties {
+		if err := prop.compilePatterns(); err != nil {
+			return err
+		}
+	}
+	if s.AdditionalProperties != nil {
+		if err := s.AdditionalProperties.Schema.compilePatterns(); err != nil {
+			return err
+		}
+	}
+	return s.Items.compilePatterns()
+}
+
+// ValidateJSON decodes the document and validates it against the schema.
+func (s *Schema) ValidateJSON(document []byte) error {
+	var value interface{}
+	if err := json.Unmarshal(document, &value); err != nil {
+		return &ValidationError{Violations: []Violation{{Path: "/", Message: fmt.Sprintf("invalid JSON: %v", err)}}}
+	}
+	return s.Validate(value)
+}
+
+// Validate checks a decoded JSON value (as produced by encoding/json) against the schema.
+func (s *Schema) Validate(value interface{}) error {
+	var violations []Violation
+	s.validate("", value, &violations)
+	if len(violations) == 0 {
+		return nil
+	}
+	return &ValidationError{Violations: violations}
+}
+
+func (s *Schema) validate(path string, value interface{}, out *[]Violation) {
+	if s == nil {
+		return
+	}
+	report := func(format string, args ...interface{}) {
+		p := path
+		if p == "" {
+			p = "/"
+		}
+		*out = append(*out, Violation{Path: p, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if len(s.Type) > 0 && !s.Type.matches(value) {
+		report("expected %s, got %s", strings.Join(s.Type, " or "), typeOf(value))
+		return
+	}
+	if len(s.Enum) > 0 && !inEnum(s.Enum, value) {
+		report("must be one of %s", formatEnum(s.Enum))
+	}
+
+	switch v := value.(type) {
+	case string:
+		length := utf8.RuneCountInString(v)
+		if s.MinLength != nil && length < *s.MinLength {
+			report("must be at least %d characters", *s.MinLength)
+		}
+		if s.MaxLength != nil && length > *s.MaxLength {
+			report("must be at most %d characters", *s.MaxLength)
+		}
+		if s.pattern != nil && !s.pattern.MatchString(v) {
+			report("must match pattern %q", s.Pattern)
+		}
+	case float64:
+		if s.Minimum != nil && v < *s.Minimum {
+			report("must be >= %v", *s.Minimum)
+		}
+		if s.Maximum != nil && v > *s.Maximum {
+			report("must be <= %v", *s.Maximum)
+		}
+	case []interface{}:
+		if s.MinItems != nil && len(v) < *s.MinItems {
+			report("must contain at least %d items", *s.MinItems)
+		}
+		if s.MaxItems != nil && len(v) > *s.MaxItems {
+			report("must contain at most %d items", *s.MaxItems)
+		}
+		for i, item := range v {
+			s.Items.validate(fmt.Sprintf("%s/%d", path, i), item, out)
+		}
+	case map[string]interface{}:
+		for _, name := range s.Required {
+			if _, ok := v[name]; !ok {
+				*out = append(*out, Violation{Path: path + "/" + name, Message: "is required"})
+			}
+		}
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			child := path + "/" + k
+			if prop, ok := s.Properties[k]; ok {
+				prop.validate(child, v[k], out)
+				continue
+			}
+			if s.AdditionalProperties == nil {
+				continue
+			}
+			if !s.AdditionalProperties.Allowed {
+				*out = append(*out, Violation{Path: child, Message: "is not an allowed property"})
+				continue
+			}
+			s.AdditionalProperties.Schema.validate(child, v[k], out)
+		}
+	}
+}
+
+func (t TypeList) matches(value interface{}) bool {
+	actual := typeOf(value)
+	for _, expected := range t {
+		if expected == actual {
+			return true
+		}
+		if expected == "number" && actual == "integer" {
+			return true
+		}
+	}
+	return false
+}
+
+func typeOf(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case float64:
+		if v == math.Trunc(v) && !math.IsInf(v, 0) {
+			return "integer"
+		}
+		return "number"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
+
+func inEnum(enum []interface{}, value interface{}) bool {
+	encoded, _ := json.Marshal(value)
+	for _, candidate := range enum {
+		c, _ := json.Marshal(candidate)
+		if string(c) == string(encoded) {
+			return true
+		}
+	}
+	return false
+}
+
+func formatEnum(enum []interface{}) string {
+	parts := make([]string, 0, len(enum))
+	for _, e := range enum {
+		encoded, _ := json.Marshal(e)
+		parts = append(parts, string(encoded))
+	}
+	return "[" + strings.Join(parts, ", ") + "]"
+}
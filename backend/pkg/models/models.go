@@ -1,6 +1,9 @@
 package models
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 // User represents an authenticated subject or an Agent.
 type User struct {
@@ -27,6 +30,38 @@ type Benchmark struct {
 	TasksCount  int       `json:"tasksCount"` // New
 	Tasks       []Task    `json:"tasks"`      // New
 	CreatedAt   time.Time `json:"createdAt"`
+
+	Guardrails  *GuardrailPolicy `json:"guardrails,omitempty"`  // Applied to every task
+	Sandbox     *SandboxSettings `json:"sandbox,omitempty"`     // Overrides the hardened sandbox defaults
+	Environment *Environment     `json:"environment,omitempty"` // Shared by every task
+
+	ScoreFormula *ScoreFormula `json:"scoreFormula,omitempty"` // How the headline score is composed
+}
+
+// ScoreFormula defines a benchmark's headline score as the weighted mean of metrics normalised to 0-1.
+type ScoreFormula struct {
+	Terms []ScoreTerm `json:"terms"`
+}
+
+// ScoreTerm weighs one summary metric. Values are clamped to [Min, Max] and scaled to 0-1;
+// violations, turns, cost and latency are inverted so that lower values score higher.
+type ScoreTerm struct {
+	Metric string  `json:"metric"` // accuracy, successRate, toolCorrectness, violations, avgTurns, totalCost or avgLatency
+	Weight float64 `json:"weight"`
+	Min    float64 `json:"min"`
+	Max    float64 `json:"max"`
+}
+
+// SandboxSettings configures isolation and resource limits of the sandboxes used by a benchmark.
+// Zero values keep the hardened defaults.
+type SandboxSettings struct {
+	CPUs      float64 `json:"cpus,omitempty"`
+	MemoryMB  int64   `json:"memoryMb,omitempty"`
+	PidsLimit int64   `json:"pidsLimit,omitempty"`
+	DiskMB    int64   `json:"diskMb,omitempty"`
+	Network   bool    `json:"network,omitempty"` // Disabled unless explicitly enabled
+	User      string  `json:"user,omitempty"`
+	Workdir   string  `json:"workdir,omitempty"`
 }
 
 // Task describes a specific task within a benchmark.
@@ -36,6 +71,67 @@ type Task struct {
 	ExpectedTool string   `json:"expectedTool"`
 	Constraints  []string `json:"constraints"`
 	MaxTurns     int      `json:"maxTurns"`
+
+	Guardrails  *GuardrailPolicy `json:"guardrails,omitempty"`  // Merged with the benchmark policy
+	Environment *Environment     `json:"environment,omitempty"` // Merged with the benchmark environment
+
+	Graders       []Grader `json:"graders,omitempty"`       // Decide success instead of the agent's reflection
+	PassThreshold float64  `json:"passThreshold,omitempty"` // Weighted grader score needed to pass; defaults to 1
+}
+
+// Grader checks one aspect of a task outcome. Type selects the grader; the other fields are its parameters.
+type Grader struct {
+	Type           string          `json:"type"`             // exact, normalized, regex, numeric, json_schema, tool_sequence, command, llm_judge
+	Weight         float64         `json:"weight,omitempty"` // Relative weight; defaults to 1
+	Path           string          `json:"path,omitempty"`   // Grades this workdir file instead of the final response
+	Expected       string          `json:"expected,omitempty"`
+	Pattern        string          `json:"pattern,omitempty"`
+	Tolerance      float64         `json:"tolerance,omitempty"` // Absolute tolerance of numeric graders
+	Schema         json.RawMessage `json:"schema,omitempty"`
+	Tools          []string        `json:"tools,omitempty"`   // Tools that must be called in this order
+	Command        string          `json:"command,omitempty"` // Hidden test run in the sandbox after the agent finished
+	ExitCode       int             `json:"exitCode,omitempty"`
+	TimeoutSeconds int             `json:"timeoutSeconds,omitempty"`
+	Rubric         string          `json:"rubric,omitempty"`
+	Model          string          `json:"model,omitempty"` // Judge model of llm_judge graders
+}
+
+// Environment prepares the sandbox a task runs in. Task environments extend the benchmark one:
+// the task image wins, env vars are merged, fixtures and setup run benchmark first.
+type Environment struct {
+	Image          string            `json:"image,omitempty"`
+	Env            map[string]string `json:"env,omitempty"`
+	Fixtures       []Fixture         `json:"fixtures,omitempty"`
+	Setup          []string          `json:"setup,omitempty"`          // Shell commands run before the agent starts
+	Teardown       []string          `json:"teardown,omitempty"`       // Shell commands run after the task, even on failure
+	Verify         []string          `json:"verify,omitempty"`         // Shell commands that must all exit 0 for the task to pass
+	TimeoutSeconds int               `json:"timeoutSeconds,omitempty"` // Per-command limit for setup, teardown and verify
+	Artifacts      []string          `json:"artifacts,omitempty"`      // Workdir paths archived after the task for review
+}
+
+// Fixture is a file or directory copied into the sandbox before setup runs.
+type Fixture struct {
+	Path    string `json:"path"`              // Destination; relative paths are resolved against the workdir
+	Content string `json:"content,omitempty"` // Inline file content
+	Source  string `json:"source,omitempty"`  // File or directory under the runner's fixtures directory
+	Mode    int64  `json:"mode,omitempty"`
+}
+
+// GuardrailPolicy declares runtime rules evaluated before every tool call.
+type GuardrailPolicy struct {
+	ForbiddenTools    []string       `json:"forbiddenTools,omitempty"`
+	ForbiddenPatterns []PatternRule  `json:"forbiddenPatterns,omitempty"`
+	Presets           []string       `json:"presets,omitempty"`         // Built-in pattern sets: destructive, network
+	MaxCallsPerTool   map[string]int `json:"maxCallsPerTool,omitempty"` // "*" limits the total number of calls
+	Action            string         `json:"action,omitempty"`          // deny (default) or abort
+}
+
+// PatternRule forbids tool calls whose arguments match a regular expression.
+type PatternRule struct {
+	Tool    string `json:"tool,omitempty"` // Empty matches every tool
+	Pattern string `json:"pattern"`
+	Action  string `json:"action,omitempty"` // Overrides the policy action
+	Message string `json:"message,omitempty"`
 }
 
 // Submission is a benchmark submission by an agent (Run).
@@ -51,19 +147,134 @@ type Submission struct {
 	Status        string        `json:"status"`
 	Progress      int           `json:"progress"` // New: 0-100
 	ScoreSummary  *ScoreSummary `json:"scoreSummary"`
+	TaskResults   []TaskResult  `json:"taskResults,omitempty"`
+}
+
+// TaskResult records the outcome of a single task of a submission.
+type TaskResult struct {
+	TaskID           string          `json:"taskId"`
+	Status           string          `json:"status"` // passed, failed or error
+	Approved         bool            `json:"approved"`
+	Verified         *bool           `json:"verified,omitempty"` // Set when the task declares verify commands
+	Score            float64         `json:"score"`
+	ToolCalls        int             `json:"toolCalls"`
+	Violations       int             `json:"violations"`
+	ArgumentAccuracy float64         `json:"argumentAccuracy"`
+	Error            string          `json:"error,omitempty"`
+	Changes          *WorkdirChanges `json:"changes,omitempty"` // Files the task changed in the workdir
+	Artifacts        []Artifact      `json:"artifacts,omitempty"`
+	Grades           []Grade         `json:"grades,omitempty"`
+}
+
+// Grade is the result of one grader. Scores range from 0 to 1.
+type Grade struct {
+	Type   string  `json:"type"`
+	Weight float64 `json:"weight"`
+	Score  float64 `json:"score"`
+	Detail string  `json:"detail,omitempty"`
+	Error  string  `json:"error,omitempty"`
+}
+
+// WorkdirChanges lists the paths, relative to the workdir, a task added, modified and deleted.
+type WorkdirChanges struct {
+	Added    []string `json:"added,omitempty"`
+	Modified []string `json:"modified,omitempty"`
+	Deleted  []string `json:"deleted,omitempty"`
+}
+
+// Artifact describes a file captured from a task sandbox. The content lives in the artifact store.
+type Artifact struct {
+	ID           string    `json:"id"`
+	SubmissionID string    `json:"submissionId"`
+	TaskID       string    `json:"taskId"`
+	Kind         string    `json:"kind"` // diff or archive
+	Name         string    `json:"name"`
+	ContentType  string    `json:"contentType"`
+	Size         int64     `json:"size"`
+	SHA256       string    `json:"sha256"`
+	CreatedAt    time.Time `json:"createdAt"`
 }
 
 // ScoreSummary captures scoring results.
 type ScoreSummary struct {
-	Score           float64            `json:"score"`
-	SuccessRate     float64            `json:"successRate"`     // New
-	ToolCorrectness float64            `json:"toolCorrectness"` // New
-	Violations      int                `json:"violations"`      // New
-	AvgTurns        float64            `json:"avgTurns"`        // New
-	TotalCost       float64            `json:"totalCost"`       // New
-	AvgLatency      float64            `json:"avgLatency"`      // New
-	Metrics         map[string]float64 `json:"metrics"`
-	Calculated      time.Time          `json:"calculated"`
+	SubmissionID    string              `json:"submissionId"`
+	AgentID         string              `json:"agentId"`
+	BenchmarkID     string              `json:"benchmarkId"`
+	Score           float64             `json:"score"`                     // Composed by the benchmark's score formula
+	SuccessRate     float64             `json:"successRate"`               // New
+	ToolCorrectness *float64            `json:"toolCorrectness,omitempty"` // Unset when no task expects a tool
+	Violations      int                 `json:"violations"`                // New
+	AvgTurns        float64             `json:"avgTurns"`                  // New
+	TotalCost       float64             `json:"totalCost"`                 // New
+	AvgLatency      float64             `json:"avgLatency"`                // New
+	TotalTokens     int                 `json:"totalTokens"`
+	Metrics         map[string]float64  `json:"metrics"`
+	Calculated      time.Time           `json:"calculated"`
+	SubmittedAt     time.Time           `json:"submittedAt"`          // When the submission was created; unlike Calculated, rescoring keeps it
+	Tasks           []TaskScore         `json:"tasks,omitempty"`      // Per-task breakdown
+	Intervals       map[string]Interval `json:"intervals,omitempty"`  // Bootstrap confidence intervals keyed by metric name
+	ScoringVersion  int                 `json:"scoringVersion"`       // ScoringConfig version that computed the summary
+	Formula         *ScoreFormula       `json:"formula,omitempty"`    // Formula that composed Score
+	Components      []ScoreComponent    `json:"components,omitempty"` // Terms of Score; their contributions sum to it
+}
+
+// ScoreComponent is one term of a composite score.
+type ScoreComponent struct {
+	Metric       string  `json:"metric"`
+	Value        float64 `json:"value"`
+	Normalized   float64 `json:"normalized"`
+	Weight       float64 `json:"weight"`
+	Contribution float64 `json:"contribution"` // Normalized scaled by the term's share of the total weight
+}
+
+// ScoringConfig is one version of the settings scores are computed with. Changing the settings
+// creates a new version, and every summary records the version that produced it.
+type ScoringConfig struct {
+	Version       int                `json:"version"`
+	Description   string             `json:"description,omitempty"`
+	GraderWeights map[string]float64 `json:"graderWeights,omitempty"` // Overrides grader weights by grader type
+	PassThreshold float64            `json:"passThreshold,omitempty"` // Overrides task pass thresholds when set
+	Created       time.Time          `json:"created"`
+}
+
+// Interval is a confidence interval of a metric.
+type Interval struct {
+	Lower float64 `json:"lower"`
+	Upper float64 `json:"upper"`
+	Level float64 `json:"level"`
+}
+
+// Comparison is a paired test of two submissions of the same benchmark over their shared tasks.
+// Differences are B minus A.
+type Comparison struct {
+	SubmissionA   string   `json:"submissionA"`
+	SubmissionB   string   `json:"submissionB"`
+	BenchmarkID   string   `json:"benchmarkId"`
+	PairedTasks   int      `json:"pairedTasks"`
+	ScoreA        float64  `json:"scoreA"` // Mean score over the paired tasks
+	ScoreB        float64  `json:"scoreB"`
+	ScoreDiff     float64  `json:"scoreDiff"`
+	DiffInterval  Interval `json:"diffInterval"`         // Paired bootstrap interval of ScoreDiff
+	EffectSize    *float64 `json:"effectSize,omitempty"` // Cohen's d_z; unset when every task differs equally
+	PValue        float64  `json:"pValue"`               // Paired bootstrap test of ScoreDiff
+	OnlyAPassed   int      `json:"onlyAPassed"`
+	OnlyBPassed   int      `json:"onlyBPassed"`
+	McNemarPValue float64  `json:"mcnemarPValue"` // Exact McNemar test of per-task success
+}
+
+// TaskScore is the part of a score summary contributed by one task.
+type TaskScore struct {
+	TaskID       string  `json:"taskId"`
+	Status       string  `json:"status"`
+	Score        float64 `json:"score"`
+	ExpectedTool string  `json:"expectedTool,omitempty"`
+	ToolCorrect  *bool   `json:"toolCorrect,omitempty"` // Set when the task expects a tool
+	Violations   int     `json:"violations"`
+	Turns        int     `json:"turns"`
+	Cost         float64 `json:"cost"`
+	Latency      float64 `json:"latency"`
+	Tokens       int     `json:"tokens"`
+	Grades       []Grade `json:"grades,omitempty"`
 }
 
 // TraceEvent stores trace logs produced by benchmark runs.
@@ -85,18 +296,78 @@ type TraceEvent struct {
 	Latency      float64           `json:"latency"` // New
 }
 
-// LeaderboardEntry is a projection combining benchmark results.
+// LeaderboardEntry is the submission that represents an agent on a benchmark, ranked within the benchmark.
 type LeaderboardEntry struct {
-	SubmissionID    string  `json:"submissionId"`
-	BenchmarkID     string  `json:"benchmarkId"`
-	AgentID         string  `json:"agentId"`
-	AgentName       string  `json:"agentName"` // New
-	Score           float64 `json:"score"`
-	SuccessRate     float64 `json:"successRate"`     // New
-	ToolCorrectness float64 `json:"toolCorrectness"` // New
-	Violations      int     `json:"violations"`      // New
-	AvgTurns        float64 `json:"avgTurns"`        // New
-	TotalCost       float64 `json:"totalCost"`       // New
-	AvgLatency      float64 `json:"avgLatency"`      // New
-	Rank            int     `json:"rank"`
+	SubmissionID    string             `json:"submissionId"`
+	BenchmarkID     string             `json:"benchmarkId"`
+	AgentID         string             `json:"agentId"`
+	AgentName       string             `json:"agentName"` // New
+	Domain          string             `json:"domain,omitempty"`
+	Score           float64            `json:"score"`
+	SuccessRate     float64            `json:"successRate"`               // New
+	ToolCorrectness *float64           `json:"toolCorrectness,omitempty"` // Unset when no task expects a tool
+	Violations      int                `json:"violations"`                // New
+	AvgTurns        float64            `json:"avgTurns"`                  // New
+	TotalCost       float64            `json:"totalCost"`                 // New
+	AvgLatency      float64            `json:"avgLatency"`                // New
+	TotalTokens     int                `json:"totalTokens"`
+	PassedTasks     int                `json:"passedTasks"`
+	TaskScores      map[string]float64 `json:"taskScores,omitempty"` // Per-task scores, compared across agents by the overall ratings
+	Rank            int                `json:"rank"`                 // Within the benchmark
+	ScoredAt        time.Time          `json:"scoredAt"`
+	SubmittedAt     time.Time          `json:"submittedAt"`
+	ScoringVersion  int                `json:"scoringVersion"`
+
+	// Efficiency metrics derived from the fields above; unset when their denominator is zero.
+	ScorePerDollar   *float64 `json:"scorePerDollar,omitempty"`   // Score over TotalCost
+	ScorePerSecond   *float64 `json:"scorePerSecond,omitempty"`   // Score over AvgLatency
+	TokensPerSuccess *float64 `json:"tokensPerSuccess,omitempty"` // TotalTokens over PassedTasks
+}
+
+// AgentRating is an agent's overall rating across benchmarks: a Bradley-Terry fit of the pairwise
+// outcomes of tasks it shares with other agents, on the Elo scale.
+type AgentRating struct {
+	AgentID     string   `json:"agentId"`
+	AgentName   string   `json:"agentName,omitempty"`
+	Rating      float64  `json:"rating"`
+	StdErr      float64  `json:"stdErr"`
+	Interval    Interval `json:"interval"`
+	Wins        int      `json:"wins"` // Shared tasks scored higher than the opponent
+	Losses      int      `json:"losses"`
+	Ties        int      `json:"ties"`
+	Comparisons int      `json:"comparisons"`
+	Benchmarks  int      `json:"benchmarks"`
+	Rank        int      `json:"rank"`
+}
+
+// LeaderboardSnapshot records a benchmark's leaderboard after it changed.
+type LeaderboardSnapshot struct {
+	BenchmarkID string             `json:"benchmarkId"`
+	Taken       time.Time          `json:"taken"`
+	Entries     []LeaderboardEntry `json:"entries"` // Ranked, without task scores
+}
+
+// RankChange is a point of an agent's rank history on a benchmark.
+type RankChange struct {
+	BenchmarkID  string    `json:"benchmarkId"`
+	At           time.Time `json:"at"`
+	Rank         int       `json:"rank"`
+	Agents       int       `json:"agents"` // Entries ranked on the benchmark at the time
+	Score        float64   `json:"score"`
+	SubmissionID string    `json:"submissionId"`
+}
+
+// AgentMetrics gathers an agent's leaderboard entries across benchmarks.
+type AgentMetrics struct {
+	AgentID    string             `json:"agentId"`
+	AgentName  string             `json:"agentName,omitempty"`
+	Benchmarks []LeaderboardEntry `json:"benchmarks"`
+}
+
+// ParetoFrontier lists the leaderboard entries of a benchmark that no other entry beats on both
+// score and cost, or on both score and latency, cheapest or fastest first.
+type ParetoFrontier struct {
+	BenchmarkID string             `json:"benchmarkId"`
+	Cost        []LeaderboardEntry `json:"cost"`
+	Latency     []LeaderboardEntry `json:"latency"`
 }
@@ -0,0 +1,163 @@
+package sandbox
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// WriteFile stores data at the given absolute path inside the sandbox, creating parent directories when needed.
+func WriteFile(ctx context.Context, sb Sandbox, filePath string, data []byte, mode int64) error {
+	if !path.IsAbs(filePath) {
+		return fmt.Errorf("path must be absolute: %s", filePath)
+	}
+	if mode == 0 {
+		mode = 0o644
+	}
+	archive, err := singleFileArchive(path.Base(filePath), data, mode)
+	if err != nil {
+		return err
+	}
+	dir := path.Dir(filePath)
+	err = sb.CopyIn(dir, bytes.NewReader(archive))
+	if errors.Is(err, ErrNotFound) {
+		res, mkErr := sb.Exec(ctx, []string{"mkdir", "-p", "--", dir}, ExecOptions{})
+		if mkErr != nil {
+			return fmt.Errorf("failed to create %s: %w", dir, mkErr)
+		}
+		if !res.Success() {
+			return fmt.Errorf("failed to create %s: %s", dir, strings.TrimSpace(res.Stderr))
+		}
+		err = sb.CopyIn(dir, bytes.NewReader(archive))
+	}
+	return err
+}
+
+// ReadFile reads up to limit bytes of the regular file at filePath. The boolean result reports
+// whether the content was truncated. A non-positive limit reads the whole file.
+func ReadFile(sb Sandbox, filePath string, limit int64) ([]byte, bool, error) {
+	reader, err := sb.CopyOut(filePath)
+	if err != nil {
+		return nil, false, err
+	}
+	defer reader.Close()
+
+	tr := tar.NewReader(reader)
+	header, err := tr.Next()
+	if err == io.EOF {
+		return nil, false, fmt.Errorf("%s: %w", filePath, ErrNotFound)
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read archive: %w", err)
+	}
+	if header.Typeflag == tar.TypeDir {
+		return nil, false, fmt.Errorf("%s is a directory", filePath)
+	}
+	if header.Typeflag != tar.TypeReg {
+		return nil, false, fmt.Errorf("%s is not a regular file", filePath)
+	}
+	if limit <= 0 || header.Size <= limit {
+		data, err := io.ReadAll(tr)
+		return data, false, err
+	}
+	data, err := io.ReadAll(io.LimitReader(tr, limit))
+	return data, true, err
+}
+
+// CopyDir copies the host directory srcDir into dstDir inside the sandbox, creating dstDir when needed.
+// Only regular files and directories are copied.
+func CopyDir(ctx context.Context, sb Sandbox, srcDir, dstDir string) error {
+	if !path.IsAbs(dstDir) {
+		return fmt.Errorf("path must be absolute: %s", dstDir)
+	}
+	archive, err := directoryArchive(srcDir)
+	if err != nil {
+		return err
+	}
+	res, err := sb.Exec(ctx, []string{"mkdir", "-p", "--", dstDir}, ExecOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", dstDir, err)
+	}
+	if !res.Success() {
+		return fmt.Errorf("failed to create %s: %s", dstDir, strings.TrimSpace(res.Stderr))
+	}
+	return sb.CopyIn(dstDir, bytes.NewReader(archive))
+}
+
+func directoryArchive(root string) ([]byte, error) {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	err := filepath.WalkDir(root, func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if name == root || !(d.IsDir() || d.Type().IsRegular()) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, name)
+		if err != nil {
+			return err
+		}
+		header, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		header.Name = filepath.ToSlash(rel)
+		header.Uid, header.Gid, header.Uname, header.Gname = 0, 0, "", ""
+		if d.IsDir() {
+			header.Name += "/"
+			return tw.WriteHeader(header)
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		f, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(tw, f)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func singleFileArchive(name string, data []byte, mode int64) ([]byte, error) {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	header := &tar.Header{
+		Name:    name,
+		Mode:    mode,
+		Size:    int64(len(data)),
+		ModTime: time.Now(),
+	}
+	if err := tw.WriteHeader(header); err != nil {
+		return nil, err
+	}
+	if _, err := tw.Write(data); err != nil {
+		return nil, err
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,32 @@
+package sandbox_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/docker/docker/client"
+
+	"github.com/example/back-end-tcc/pkg/sandbox"
+	"github.com/example/back-end-tcc/pkg/sandbox/sandboxtest"
+)
+
+func TestDockerSandboxConformance(t *testing.T) {
+	cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+	if err != nil {
+		t.Skipf("docker client not available: %v", err)
+	}
+	if _, err := cli.Ping(context.Background()); err != nil {
+		t.Skipf("docker daemon not available: %v", err)
+	}
+
+	sandboxtest.Run(t, func(t *testing.T) sandbox.Sandbox {
+		sb, err := sandbox.NewDockerSandbox(sandbox.DefaultImage)
+		if err != nil {
+			t.Fatalf("failed to create sandbox: %v", err)
+		}
+		if err := sb.Start(); err != nil {
+			t.Fatalf("failed to start sandbox: %v", err)
+		}
+		return sb
+	})
+}
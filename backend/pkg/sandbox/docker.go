@@ -1,35 +1,45 @@
 package sandbox
 
 import (
-	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"sync/atomic"
 	"time"
 
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/docker/api/types/strslice"
 	"github.com/docker/docker/client"
 	"github.com/docker/docker/pkg/stdcopy"
 )
 
+// execSeq makes exec PID file names unique within the process.
+var execSeq uint64
+
 // DockerSandbox implements Sandbox using Docker containers.
 type DockerSandbox struct {
 	cli         *client.Client
 	containerID string
 	image       string
+	spec        Spec
 	ctx         context.Context
 }
 
-// NewDockerSandbox creates a new DockerSandbox.
-func NewDockerSandbox(image string) (*DockerSandbox, error) {
+// NewDockerSandbox creates a new DockerSandbox. Containers are hardened by default (see DefaultSpec);
+// options relax or tighten individual settings.
+func NewDockerSandbox(image string, opts ...Option) (*DockerSandbox, error) {
 	cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
 	if err != nil {
 		return nil, err
 	}
+	spec := NewSpec(image, opts...)
 	return &DockerSandbox{
 		cli:   cli,
-		image: image,
+		image: spec.Image,
+		spec:  spec,
 		ctx:   context.Background(),
 	}, nil
 }
@@ -50,11 +60,8 @@ func (s *DockerSandbox) Start() error {
 	}
 
 	// Create container that stays alive
-	resp, err := s.cli.ContainerCreate(s.ctx, &container.Config{
-		Image: s.image,
-		Cmd:   []string{"tail", "-f", "/dev/null"}, // Keep running
-		Tty:   false,
-	}, nil, nil, nil, "")
+	config, hostConfig := s.containerConfig()
+	resp, err := s.cli.ContainerCreate(s.ctx, config, hostConfig, nil, nil, "")
 	if err != nil {
 		return fmt.Errorf("failed to create container: %w", err)
 	}
@@ -73,57 +80,284 @@ func (s *DockerSandbox) Stop() error {
 	if s.containerID == "" {
 		return nil
 	}
-	// Force remove
-	return s.cli.ContainerRemove(s.ctx, s.containerID, types.ContainerRemoveOptions{Force: true})
+	// Force remove, including the anonymous workdir volume
+	return s.cli.ContainerRemove(s.ctx, s.containerID, types.ContainerRemoveOptions{Force: true, RemoveVolumes: true})
+}
+
+// Reset kills every process left behind by earlier commands and wipes the workdir and /tmp,
+// so a pooled container can be leased again. The root filesystem is read-only, so nothing else persists.
+func (s *DockerSandbox) Reset(ctx context.Context) error {
+	if s.containerID == "" {
+		return fmt.Errorf("sandbox not started")
+	}
+	// kill -1 signals everything but PID 1 (the keep-alive process) and the shell itself.
+	if _, err := s.Exec(ctx, []string{"sh", "-c", "kill -KILL -1 2>/dev/null; true"}, ExecOptions{}); err != nil {
+		return err
+	}
+	return wipe(ctx, s, s.spec.Workdir, "/tmp")
+}
+
+// containerConfig translates the spec into Docker settings: read-only root filesystem, tmpfs-backed
+// workdir and /tmp, no capabilities, no privilege escalation, bounded resources and no network
+// unless enabled.
+func (s *DockerSandbox) containerConfig() (*container.Config, *container.HostConfig) {
+	config := &container.Config{
+		Image:           s.image,
+		Cmd:             []string{"tail", "-f", "/dev/null"}, // Keep running
+		Tty:             false,
+		User:            s.spec.User,
+		WorkingDir:      s.spec.Workdir,
+		Env:             []string{"HOME=" + s.spec.Workdir},
+		NetworkDisabled: !s.spec.Network,
+	}
+
+	limits := s.spec.Limits
+	hostConfig := &container.HostConfig{
+		ReadonlyRootfs: true,
+		CapDrop:        strslice.StrSlice{"ALL"},
+		SecurityOpt:    []string{"no-new-privileges"},
+		Tmpfs:          map[string]string{"/tmp": "rw,nosuid,nodev,size=64m,mode=1777"},
+		Mounts: []mount.Mount{{
+			// A tmpfs-backed volume rather than a plain tmpfs mount, so the archive API can write into it.
+			Type:   mount.TypeVolume,
+			Target: s.spec.Workdir,
+			VolumeOptions: &mount.VolumeOptions{
+				DriverConfig: &mount.Driver{
+					Name: "local",
+					Options: map[string]string{
+						"type":   "tmpfs",
+						"device": "tmpfs",
+						"o":      fmt.Sprintf("size=%dm,mode=1777", limits.DiskMB),
+					},
+				},
+			},
+		}},
+		Resources: container.Resources{
+			NanoCPUs:   int64(limits.CPUs * 1e9),
+			Memory:     limits.MemoryMB << 20,
+			MemorySwap: limits.MemoryMB << 20, // No swap on top of the memory limit
+		},
+	}
+	if limits.PidsLimit > 0 {
+		pids := limits.PidsLimit
+		hostConfig.Resources.PidsLimit = &pids
+	}
+	if !s.spec.Network {
+		hostConfig.NetworkMode = "none"
+	}
+	return config, hostConfig
 }
 
-// Exec executes a command inside the container.
-func (s *DockerSandbox) Exec(cmd []string) (string, string, error) {
+// execWrapper records the PID of the command in the file named by $0 before exec'ing it,
+// so an expired command can be killed from a second exec. The file is removed by killScript,
+// or by forget once the command exits on its own.
+const execWrapper = `echo $$ > "$0"; exec "$@"`
+
+// Exec executes a command inside the container. The command is killed when opts.Timeout
+// expires or ctx is cancelled; a non-zero exit code is reported in the result, not as an error.
+func (s *DockerSandbox) Exec(ctx context.Context, cmd []string, opts ExecOptions) (ExecResult, error) {
 	if s.containerID == "" {
-		return "", "", fmt.Errorf("sandbox not started")
+		return ExecResult{}, fmt.Errorf("sandbox not started")
 	}
+	opts = opts.withDefaults()
+	ctx, cancel := context.WithTimeout(ctx, opts.Timeout)
+	defer cancel()
 
+	pidFile := fmt.Sprintf("/tmp/.sandbox-exec-%d.pid", atomic.AddUint64(&execSeq, 1))
 	execConfig := types.ExecConfig{
-		Cmd:          cmd,
+		Cmd:          append([]string{"sh", "-c", execWrapper, pidFile}, cmd...),
+		Env:          opts.Env,
 		AttachStdout: true,
 		AttachStderr: true,
 	}
 
-	resp, err := s.cli.ContainerExecCreate(s.ctx, s.containerID, execConfig)
+	start := time.Now()
+	resp, err := s.cli.ContainerExecCreate(ctx, s.containerID, execConfig)
 	if err != nil {
-		return "", "", fmt.Errorf("failed to create exec: %w", err)
+		return ExecResult{}, fmt.Errorf("failed to create exec: %w", err)
 	}
 
-	hijackedResp, err := s.cli.ContainerExecAttach(s.ctx, resp.ID, types.ExecStartCheck{})
+	hijackedResp, err := s.cli.ContainerExecAttach(ctx, resp.ID, types.ExecStartCheck{})
 	if err != nil {
-		return "", "", fmt.Errorf("failed to attach exec: %w", err)
+		return ExecResult{}, fmt.Errorf("failed to attach exec: %w", err)
 	}
 	defer hijackedResp.Close()
 
-	var stdout, stderr bytes.Buffer
-	// stdcopy.StdCopy demultiplexes the stream
-	_, err = stdcopy.StdCopy(&stdout, &stderr, hijackedResp.Reader)
-	if err != nil {
-		return "", "", fmt.Errorf("failed to copy output: %w", err)
+	stdout := newCappedBuffer(opts.MaxOutputBytes)
+	stderr := newCappedBuffer(opts.MaxOutputBytes)
+	copied := make(chan error, 1)
+	go func() {
+		// stdcopy.StdCopy demultiplexes the stream
+		_, err := stdcopy.StdCopy(stdout, stderr, hijackedResp.Reader)
+		copied <- err
+	}()
+
+	result := func() ExecResult {
+		return ExecResult{
+			Stdout:          stdout.String(),
+			Stderr:          stderr.String(),
+			Duration:        time.Since(start),
+			StdoutTruncated: stdout.truncated,
+			StderrTruncated: stderr.truncated,
+		}
+	}
+	expired := func() (ExecResult, error) {
+		s.kill(pidFile)
+		hijackedResp.Close()
+		<-copied
+		res := result()
+		res.ExitCode = TimedOutExitCode
+		res.TimedOut = true
+		if errors.Is(ctx.Err(), context.Canceled) {
+			return res, ctx.Err()
+		}
+		return res, nil
+	}
+
+	select {
+	case err := <-copied:
+		if err != nil {
+			return result(), fmt.Errorf("failed to copy output: %w", err)
+		}
+	case <-ctx.Done():
+		return expired()
 	}
 
-	// Wait for exec to finish to get exit code?
-	// ContainerExecInspect can check exit code.
+	// The output stream is closed once the process exits; wait for the exit code to be published.
+	backoff := 5 * time.Millisecond
 	for {
-		inspect, err := s.cli.ContainerExecInspect(s.ctx, resp.ID)
+		inspect, err := s.cli.ContainerExecInspect(ctx, resp.ID)
 		if err != nil {
-			return stdout.String(), stderr.String(), err
+			if ctx.Err() != nil {
+				return expired()
+			}
+			return result(), fmt.Errorf("failed to inspect exec: %w", err)
 		}
 		if !inspect.Running {
-			if inspect.ExitCode != 0 {
-				return stdout.String(), stderr.String(), fmt.Errorf("command exited with code %d: %s", inspect.ExitCode, stderr.String())
-			}
-			break
+			res := result()
+			res.ExitCode = inspect.ExitCode
+			s.forget(pidFile)
+			return res, nil
+		}
+		select {
+		case <-ctx.Done():
+			return expired()
+		case <-time.After(backoff):
+		}
+		if backoff < 200*time.Millisecond {
+			backoff *= 2
 		}
-		time.Sleep(100 * time.Millisecond)
 	}
+}
+
+// OpenSession starts an interactive shell in the container through an exec with stdin attached.
+// Close kills the shell like an expired Exec; processes it moved to other groups live until Reset or Stop.
+func (s *DockerSandbox) OpenSession(ctx context.Context, opts SessionOptions) (Session, error) {
+	if s.containerID == "" {
+		return nil, fmt.Errorf("sandbox not started")
+	}
+	pidFile := fmt.Sprintf("/tmp/.sandbox-exec-%d.pid", atomic.AddUint64(&execSeq, 1))
+	resp, err := s.cli.ContainerExecCreate(ctx, s.containerID, types.ExecConfig{
+		Cmd:          []string{"sh", "-c", execWrapper, pidFile, "sh"},
+		Env:          opts.Env,
+		AttachStdin:  true,
+		AttachStdout: true,
+		AttachStderr: true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create exec: %w", err)
+	}
+	hijackedResp, err := s.cli.ContainerExecAttach(ctx, resp.ID, types.ExecStartCheck{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to attach exec: %w", err)
+	}
+
+	out := newSessionBuffer(opts.MaxBufferBytes)
+	go func() {
+		stdcopy.StdCopy(out, out, hijackedResp.Reader)
+		code := TimedOutExitCode
+		inspectCtx, cancel := context.WithTimeout(s.ctx, 5*time.Second)
+		defer cancel()
+		if inspect, err := s.cli.ContainerExecInspect(inspectCtx, resp.ID); err == nil && !inspect.Running {
+			code = inspect.ExitCode
+			s.forget(pidFile)
+		}
+		out.finish(code)
+	}()
+	return newStreamSession(hijackedConn{hijackedResp}, out, func() error {
+		s.kill(pidFile)
+		hijackedResp.Close()
+		return nil
+	}), nil
+}
+
+// hijackedConn adapts an attached exec to the stdin of a session; closing it signals EOF to the shell.
+type hijackedConn struct {
+	resp types.HijackedResponse
+}
+
+func (c hijackedConn) Write(p []byte) (int, error) { return c.resp.Conn.Write(p) }
+
+func (c hijackedConn) Close() error { return c.resp.CloseWrite() }
+
+// killScript kills the process group whose PID execWrapper recorded in the file named by $0.
+const killScript = `pid=$(cat "$0" 2>/dev/null) && { kill -KILL -- "-$pid" 2>/dev/null || kill -KILL "$pid"; }; rm -f "$0"`
+
+// kill terminates the process group recorded in pidFile, falling back to the single process.
+func (s *DockerSandbox) kill(pidFile string) {
+	s.execDetached("sh", "-c", killScript, pidFile)
+}
+
+// forget removes the PID file of a command that exited on its own.
+func (s *DockerSandbox) forget(pidFile string) {
+	s.execDetached("rm", "-f", "--", pidFile)
+}
+
+// execDetached starts a housekeeping command without waiting for it.
+func (s *DockerSandbox) execDetached(cmd ...string) {
+	ctx, cancel := context.WithTimeout(s.ctx, 5*time.Second)
+	defer cancel()
+	resp, err := s.cli.ContainerExecCreate(ctx, s.containerID, types.ExecConfig{Cmd: cmd})
+	if err != nil {
+		return
+	}
+	_ = s.cli.ContainerExecStart(ctx, resp.ID, types.ExecStartCheck{Detach: true})
+}
+
+// CopyIn extracts a tar archive into dstDir using the Docker archive API.
+func (s *DockerSandbox) CopyIn(dstDir string, archive io.Reader) error {
+	if s.containerID == "" {
+		return fmt.Errorf("sandbox not started")
+	}
+	// CopyUIDGID chowns extracted files to the sandbox user instead of the uid/gid in the archive.
+	err := s.cli.CopyToContainer(s.ctx, s.containerID, dstDir, archive, types.CopyToContainerOptions{CopyUIDGID: true})
+	if client.IsErrNotFound(err) {
+		return fmt.Errorf("%s: %w", dstDir, ErrNotFound)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to copy into container: %w", err)
+	}
+	return nil
+}
+
+// CopyOut streams srcPath out of the container as a tar archive.
+func (s *DockerSandbox) CopyOut(srcPath string) (io.ReadCloser, error) {
+	if s.containerID == "" {
+		return nil, fmt.Errorf("sandbox not started")
+	}
+	reader, _, err := s.cli.CopyFromContainer(s.ctx, s.containerID, srcPath)
+	if client.IsErrNotFound(err) {
+		return nil, fmt.Errorf("%s: %w", srcPath, ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to copy from container: %w", err)
+	}
+	return reader, nil
+}
 
-	return stdout.String(), stderr.String(), nil
+// Workdir returns the writable working directory inside the container.
+func (s *DockerSandbox) Workdir() string {
+	return s.spec.Workdir
 }
 
 // ID returns the container ID.
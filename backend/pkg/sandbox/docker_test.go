@@ -1,44 +1,135 @@
 package sandbox
 
 import (
+	"context"
 	"strings"
 	"testing"
+	"time"
 )
 
+// requireDocker skips the test when no Docker daemon is reachable.
+func requireDocker(t *testing.T, sb *DockerSandbox) {
+	t.Helper()
+	if _, err := sb.cli.Ping(context.Background()); err != nil {
+		t.Skipf("docker daemon not available: %v", err)
+	}
+}
+
 func TestDockerSandbox(t *testing.T) {
-	// Skip if docker is not available?
-	// For now, we assume it is since we are in verification.
-	
 	sb, err := NewDockerSandbox("python:3.9-slim")
 	if err != nil {
 		t.Fatalf("failed to create sandbox: %v", err)
 	}
+	requireDocker(t, sb)
 
 	if err := sb.Start(); err != nil {
 		t.Fatalf("failed to start sandbox: %v", err)
 	}
 	defer sb.Stop()
+	ctx := context.Background()
 
 	// Test 1: Simple Echo
-	stdout, stderr, err := sb.Exec([]string{"echo", "hello"})
+	res, err := sb.Exec(ctx, []string{"echo", "hello"}, ExecOptions{})
 	if err != nil {
 		t.Fatalf("exec failed: %v", err)
 	}
-	if strings.TrimSpace(stdout) != "hello" {
-		t.Errorf("expected 'hello', got '%s'", stdout)
+	if strings.TrimSpace(res.Stdout) != "hello" || res.ExitCode != 0 {
+		t.Errorf("expected 'hello', got '%s' (exit %d)", res.Stdout, res.ExitCode)
 	}
 
 	// Test 2: Write File and Read It
-	_, _, err = sb.Exec([]string{"sh", "-c", "echo 'secret data' > /tmp/secret.txt"})
-	if err != nil {
-		t.Fatalf("write file failed: %v", err)
+	res, err = sb.Exec(ctx, []string{"sh", "-c", "echo 'secret data' > /tmp/secret.txt"}, ExecOptions{})
+	if err != nil || !res.Success() {
+		t.Fatalf("write file failed: %v (stderr: %s)", err, res.Stderr)
 	}
 
-	stdout, stderr, err = sb.Exec([]string{"cat", "/tmp/secret.txt"})
+	res, err = sb.Exec(ctx, []string{"cat", "/tmp/secret.txt"}, ExecOptions{})
 	if err != nil {
 		t.Fatalf("read file failed: %v", err)
 	}
-	if strings.TrimSpace(stdout) != "secret data" {
-		t.Errorf("expected 'secret data', got '%s' (stderr: %s)", stdout, stderr)
+	if strings.TrimSpace(res.Stdout) != "secret data" {
+		t.Errorf("expected 'secret data', got '%s' (stderr: %s)", res.Stdout, res.Stderr)
+	}
+
+	// Test 3: Exit codes are reported, not returned as errors
+	res, err = sb.Exec(ctx, []string{"sh", "-c", "echo oops >&2; exit 3"}, ExecOptions{})
+	if err != nil {
+		t.Fatalf("exec failed: %v", err)
+	}
+	if res.ExitCode != 3 || strings.TrimSpace(res.Stderr) != "oops" {
+		t.Errorf("expected exit code 3 with stderr, got %d %q", res.ExitCode, res.Stderr)
+	}
+
+	// Test 4: Timeouts kill the command
+	res, err = sb.Exec(ctx, []string{"sleep", "30"}, ExecOptions{Timeout: 500 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("exec failed: %v", err)
+	}
+	if !res.TimedOut || res.Duration > 10*time.Second {
+		t.Errorf("expected timeout, got %+v", res)
+	}
+
+	// Test 5: Output is capped
+	res, err = sb.Exec(ctx, []string{"sh", "-c", "yes | head -c 10000"}, ExecOptions{MaxOutputBytes: 100})
+	if err != nil {
+		t.Fatalf("exec failed: %v", err)
+	}
+	if len(res.Stdout) != 100 || !res.StdoutTruncated {
+		t.Errorf("expected truncated stdout of 100 bytes, got %d (truncated=%v)", len(res.Stdout), res.StdoutTruncated)
+	}
+}
+
+func TestDockerSandboxHardenedDefaults(t *testing.T) {
+	sb, err := NewDockerSandbox("python:3.9-slim")
+	if err != nil {
+		t.Fatalf("failed to create sandbox: %v", err)
+	}
+	config, hostConfig := sb.containerConfig()
+
+	if !config.NetworkDisabled || hostConfig.NetworkMode != "none" {
+		t.Errorf("expected networking to be disabled by default")
+	}
+	if config.User != DefaultUser || config.WorkingDir != DefaultWorkdir {
+		t.Errorf("unexpected user/workdir: %q %q", config.User, config.WorkingDir)
+	}
+	if !hostConfig.ReadonlyRootfs {
+		t.Errorf("expected read-only root filesystem")
+	}
+	if len(hostConfig.CapDrop) != 1 || hostConfig.CapDrop[0] != "ALL" {
+		t.Errorf("expected all capabilities to be dropped, got %v", hostConfig.CapDrop)
+	}
+	if len(hostConfig.SecurityOpt) != 1 || hostConfig.SecurityOpt[0] != "no-new-privileges" {
+		t.Errorf("expected no-new-privileges, got %v", hostConfig.SecurityOpt)
+	}
+	if hostConfig.Memory != DefaultMemoryMB<<20 || hostConfig.PidsLimit == nil || *hostConfig.PidsLimit != DefaultPidsLimit {
+		t.Errorf("unexpected resource limits: %+v", hostConfig.Resources)
+	}
+	if len(hostConfig.Mounts) != 1 || hostConfig.Mounts[0].Target != DefaultWorkdir {
+		t.Errorf("expected a writable workdir mount, got %+v", hostConfig.Mounts)
+	}
+}
+
+func TestDockerSandboxOptionsOverrideDefaults(t *testing.T) {
+	sb, err := NewDockerSandbox("alpine:3",
+		WithNetwork(true),
+		WithLimits(Limits{CPUs: 2, MemoryMB: 1024}),
+		WithWorkdir("/work"),
+	)
+	if err != nil {
+		t.Fatalf("failed to create sandbox: %v", err)
+	}
+	config, hostConfig := sb.containerConfig()
+
+	if config.NetworkDisabled || hostConfig.NetworkMode == "none" {
+		t.Errorf("expected networking to be enabled")
+	}
+	if hostConfig.NanoCPUs != 2e9 || hostConfig.Memory != 1024<<20 {
+		t.Errorf("unexpected limits: cpus=%d memory=%d", hostConfig.NanoCPUs, hostConfig.Memory)
+	}
+	if *hostConfig.PidsLimit != DefaultPidsLimit {
+		t.Errorf("expected untouched pids limit to keep its default")
+	}
+	if sb.Workdir() != "/work" || hostConfig.Mounts[0].Target != "/work" {
+		t.Errorf("expected workdir override, got %s", sb.Workdir())
 	}
 }
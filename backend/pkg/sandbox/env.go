@@ -0,0 +1,35 @@
+package sandbox
+
+import (
+	"context"
+	"sort"
+)
+
+// envSandbox injects a fixed set of environment variables into every command.
+type envSandbox struct {
+	Sandbox
+	env []string
+}
+
+// WithEnv wraps sb so every Exec sees env. Variables passed in ExecOptions take precedence.
+// Release the original sandbox, not the wrapper, when it came from a Provider.
+func WithEnv(sb Sandbox, env map[string]string) Sandbox {
+	if len(env) == 0 {
+		return sb
+	}
+	keys := make([]string, 0, len(env))
+	for k := range env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	vars := make([]string, 0, len(keys))
+	for _, k := range keys {
+		vars = append(vars, k+"="+env[k])
+	}
+	return &envSandbox{Sandbox: sb, env: vars}
+}
+
+func (s *envSandbox) Exec(ctx context.Context, cmd []string, opts ExecOptions) (ExecResult, error) {
+	opts.Env = append(append([]string{}, s.env...), opts.Env...)
+	return s.Sandbox.Exec(ctx, cmd, opts)
+}
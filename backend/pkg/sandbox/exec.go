@@ -0,0 +1,82 @@
+package sandbox
+
+import (
+	"bytes"
+	"time"
+)
+
+// Exec defaults used when ExecOptions leaves a field unset.
+const (
+	DefaultExecTimeout    = 60 * time.Second
+	DefaultMaxOutputBytes = 1 << 20
+)
+
+// TimedOutExitCode is reported for commands killed because their deadline expired.
+const TimedOutExitCode = -1
+
+// ExecOptions tunes a single command execution.
+type ExecOptions struct {
+	// Timeout bounds the command run time; the process is killed when it expires.
+	Timeout time.Duration
+	// MaxOutputBytes caps stdout and stderr independently; extra output is discarded.
+	MaxOutputBytes int64
+	// Env holds extra KEY=VALUE variables for the command.
+	Env []string
+}
+
+func (o ExecOptions) withDefaults() ExecOptions {
+	if o.Timeout <= 0 {
+		o.Timeout = DefaultExecTimeout
+	}
+	if o.MaxOutputBytes <= 0 {
+		o.MaxOutputBytes = DefaultMaxOutputBytes
+	}
+	return o
+}
+
+// ExecResult is the structured outcome of a command. A non-zero exit code is not an error.
+type ExecResult struct {
+	ExitCode        int           `json:"exitCode"`
+	Stdout          string        `json:"stdout"`
+	Stderr          string        `json:"stderr"`
+	Duration        time.Duration `json:"duration"`
+	StdoutTruncated bool          `json:"stdoutTruncated"`
+	StderrTruncated bool          `json:"stderrTruncated"`
+	TimedOut        bool          `json:"timedOut"`
+}
+
+// Success reports whether the command ran to completion with exit code 0.
+func (r ExecResult) Success() bool {
+	return r.ExitCode == 0 && !r.TimedOut
+}
+
+// cappedBuffer keeps the first limit bytes written to it and remembers whether anything was dropped.
+type cappedBuffer struct {
+	buf       bytes.Buffer
+	limit     int64
+	truncated bool
+}
+
+func newCappedBuffer(limit int64) *cappedBuffer {
+	return &cappedBuffer{limit: limit}
+}
+
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	remaining := b.limit - int64(b.buf.Len())
+	if remaining <= 0 {
+		if len(p) > 0 {
+			b.truncated = true
+		}
+		return len(p), nil
+	}
+	if int64(len(p)) > remaining {
+		b.buf.Write(p[:remaining])
+		b.truncated = true
+		return len(p), nil
+	}
+	return b.buf.Write(p)
+}
+
+func (b *cappedBuffer) String() string {
+	return b.buf.String()
+}
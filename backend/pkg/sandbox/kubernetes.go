@@ -0,0 +1,557 @@
+package sandbox
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/remotecommand"
+)
+
+// Kubernetes defaults.
+const (
+	DefaultKubernetesNamespace   = "default"
+	DefaultKubernetesStartWait   = 2 * time.Minute
+	DefaultKubernetesMaxLifetime = 6 * time.Hour
+)
+
+// Labels set on sandbox jobs and pods. Pods have no per-pod switch to disable networking, so
+// sandboxes without network rely on a deny-all NetworkPolicy selecting KubernetesNetworkLabel=none
+// (see DenyAllNetworkPolicy); Start refuses to create them when the namespace has none.
+const (
+	KubernetesSandboxLabel = "sandbox.back-end-tcc/id"
+	KubernetesNetworkLabel = "sandbox.back-end-tcc/network"
+	kubernetesContainer    = "sandbox"
+	kubernetesTmpMB        = 64
+)
+
+// ErrNoNetworkPolicy is returned by Start for sandboxes without network when no NetworkPolicy in
+// the namespace denies all traffic of KubernetesNetworkLabel=none pods.
+var ErrNoNetworkPolicy = errors.New("no deny-all network policy for sandbox pods")
+
+// copyNotFoundExit is the exit code the copy scripts use when the path is missing.
+const copyNotFoundExit = 100
+
+// KubernetesConfig selects where and with which identity sandbox pods run.
+type KubernetesConfig struct {
+	Namespace      string
+	ServiceAccount string
+	// CPURequest and MemoryRequestMB are reserved by the scheduler; zero requests the spec limits.
+	CPURequest      float64
+	MemoryRequestMB int64
+	NodeSelector    map[string]string
+	// StartTimeout bounds how long Start waits for the pod to run.
+	StartTimeout time.Duration
+	// MaxLifetime is the job's active deadline, after which Kubernetes kills a leaked sandbox.
+	MaxLifetime time.Duration
+}
+
+// PodExecutor runs a command in a pod container and streams its stdio. A non-zero exit is reported
+// as an error implementing ExitStatus() int, like client-go's exec.CodeExitError.
+type PodExecutor interface {
+	Exec(ctx context.Context, namespace, pod, container string, cmd []string, stdin io.Reader, stdout, stderr io.Writer) error
+}
+
+// KubernetesOption customises a KubernetesSandbox.
+type KubernetesOption func(*KubernetesSandbox)
+
+// WithPodExecutor replaces the SPDY executor, e.g. in tests using the fake clientset.
+func WithPodExecutor(e PodExecutor) KubernetesOption {
+	return func(s *KubernetesSandbox) {
+		s.executor = e
+	}
+}
+
+// KubernetesSandbox implements Sandbox with a single-pod Job. Commands run through the pod exec API,
+// so the image needs sh and tar.
+type KubernetesSandbox struct {
+	client   kubernetes.Interface
+	executor PodExecutor
+	cfg      KubernetesConfig
+	spec     Spec
+	name     string
+	pod      string
+	ctx      context.Context
+}
+
+// NewKubernetesSandbox creates a sandbox; nothing is created in the cluster until Start.
+func NewKubernetesSandbox(client kubernetes.Interface, restConfig *rest.Config, spec Spec, cfg KubernetesConfig, opts ...KubernetesOption) *KubernetesSandbox {
+	if cfg.Namespace == "" {
+		cfg.Namespace = DefaultKubernetesNamespace
+	}
+	if cfg.StartTimeout <= 0 {
+		cfg.StartTimeout = DefaultKubernetesStartWait
+	}
+	if cfg.MaxLifetime <= 0 {
+		cfg.MaxLifetime = DefaultKubernetesMaxLifetime
+	}
+	s := &KubernetesSandbox{
+		client:   client,
+		executor: spdyExecutor{client: client, config: restConfig},
+		cfg:      cfg,
+		spec:     spec,
+		name:     "sandbox-" + randomSuffix(),
+		ctx:      context.Background(),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// KubernetesFactory returns a Factory creating Kubernetes sandboxes.
+func KubernetesFactory(client kubernetes.Interface, restConfig *rest.Config, cfg KubernetesConfig, opts ...KubernetesOption) Factory {
+	return func(spec Spec) (Sandbox, error) {
+		return NewKubernetesSandbox(client, restConfig, spec, cfg, opts...), nil
+	}
+}
+
+// Start creates the job and waits until its pod runs. The job is deleted again if the pod fails to start.
+func (s *KubernetesSandbox) Start() error {
+	if !s.spec.Network {
+		if err := s.checkNetworkPolicy(); err != nil {
+			return err
+		}
+	}
+	jobs := s.client.BatchV1().Jobs(s.cfg.Namespace)
+	if _, err := jobs.Create(s.ctx, s.job(), metav1.CreateOptions{}); err != nil {
+		return fmt.Errorf("failed to create job: %w", err)
+	}
+	if err := s.waitForPod(); err != nil {
+		s.Stop()
+		return err
+	}
+	return nil
+}
+
+func (s *KubernetesSandbox) waitForPod() error {
+	selector := KubernetesSandboxLabel + "=" + s.name
+	var reason string
+	err := wait.PollUntilContextTimeout(s.ctx, 500*time.Millisecond, s.cfg.StartTimeout, true, func(ctx context.Context) (bool, error) {
+		pods, err := s.client.CoreV1().Pods(s.cfg.Namespace).List(ctx, metav1.ListOptions{LabelSelector: selector})
+		if err != nil {
+			return false, err
+		}
+		for _, pod := range pods.Items {
+			switch pod.Status.Phase {
+			case corev1.PodRunning:
+				if containerReady(pod) {
+					s.pod = pod.Name
+					return true, nil
+				}
+			case corev1.PodFailed, corev1.PodSucceeded:
+				return false, fmt.Errorf("pod %s exited before the sandbox started: %s", pod.Name, pod.Status.Reason)
+			}
+			reason = waitingReason(pod)
+			if reason == "ErrImagePull" || reason == "ImagePullBackOff" || reason == "InvalidImageName" {
+				return false, fmt.Errorf("pod %s cannot start: %s", pod.Name, reason)
+			}
+		}
+		return false, nil
+	})
+	if wait.Interrupted(err) {
+		if reason != "" {
+			return fmt.Errorf("pod did not start within %s: %s", s.cfg.StartTimeout, reason)
+		}
+		return fmt.Errorf("pod did not start within %s", s.cfg.StartTimeout)
+	}
+	return err
+}
+
+func containerReady(pod corev1.Pod) bool {
+	for _, status := range pod.Status.ContainerStatuses {
+		if status.Name == kubernetesContainer {
+			return status.Ready
+		}
+	}
+	return false
+}
+
+func waitingReason(pod corev1.Pod) string {
+	for _, status := range pod.Status.ContainerStatuses {
+		if status.State.Waiting != nil {
+			return status.State.Waiting.Reason
+		}
+	}
+	return ""
+}
+
+// Stop deletes the job and its pod without a grace period.
+func (s *KubernetesSandbox) Stop() error {
+	background := metav1.DeletePropagationBackground
+	var grace int64
+	err := s.client.BatchV1().Jobs(s.cfg.Namespace).Delete(s.ctx, s.name, metav1.DeleteOptions{PropagationPolicy: &background})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete job: %w", err)
+	}
+	if s.pod != "" {
+		err := s.client.CoreV1().Pods(s.cfg.Namespace).Delete(s.ctx, s.pod, metav1.DeleteOptions{GracePeriodSeconds: &grace})
+		if err != nil && !apierrors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete pod: %w", err)
+		}
+		s.pod = ""
+	}
+	return nil
+}
+
+// Reset kills every process left behind by earlier commands and wipes the workdir and /tmp.
+func (s *KubernetesSandbox) Reset(ctx context.Context) error {
+	if s.pod == "" {
+		return fmt.Errorf("sandbox not started")
+	}
+	if _, err := s.Exec(ctx, []string{"sh", "-c", "kill -KILL -1 2>/dev/null; true"}, ExecOptions{}); err != nil {
+		return err
+	}
+	return wipe(ctx, s, s.spec.Workdir, "/tmp")
+}
+
+// checkNetworkPolicy looks for a NetworkPolicy that selects pods without network and allows them
+// neither ingress nor egress.
+func (s *KubernetesSandbox) checkNetworkPolicy() error {
+	policies, err := s.client.NetworkingV1().NetworkPolicies(s.cfg.Namespace).List(s.ctx, metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to list network policies: %w", err)
+	}
+	pod := labels.Set{KubernetesNetworkLabel: "none"}
+	for _, policy := range policies.Items {
+		selector, err := metav1.LabelSelectorAsSelector(&policy.Spec.PodSelector)
+		if err != nil || !selector.Matches(pod) || len(policy.Spec.Ingress) > 0 || len(policy.Spec.Egress) > 0 {
+			continue
+		}
+		var ingress, egress bool
+		for _, policyType := range policy.Spec.PolicyTypes {
+			ingress = ingress || policyType == networkingv1.PolicyTypeIngress
+			egress = egress || policyType == networkingv1.PolicyTypeEgress
+		}
+		if ingress && egress {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w in namespace %s: apply deploy/kubernetes/sandbox-network-policy.yaml", ErrNoNetworkPolicy, s.cfg.Namespace)
+}
+
+// DenyAllNetworkPolicy returns the NetworkPolicy sandboxes without network require, the one in
+// deploy/kubernetes/sandbox-network-policy.yaml.
+func DenyAllNetworkPolicy(namespace string) *networkingv1.NetworkPolicy {
+	return &networkingv1.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "sandbox-deny-all", Namespace: namespace},
+		Spec: networkingv1.NetworkPolicySpec{
+			PodSelector: metav1.LabelSelector{MatchLabels: map[string]string{KubernetesNetworkLabel: "none"}},
+			PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress, networkingv1.PolicyTypeEgress},
+		},
+	}
+}
+
+// job translates the spec into a single-pod Job with the same hardening as Docker sandboxes:
+// read-only root filesystem, size-limited workdir and /tmp, no capabilities and no service account token.
+// Limits.PidsLimit is not applied: pods have no per-pod process limit, only the kubelet's
+// podPidsLimit, which cluster administrators set per node.
+func (s *KubernetesSandbox) job() *batchv1.Job {
+	limits := s.spec.Limits
+	network := "none"
+	if s.spec.Network {
+		network = "allowed"
+	}
+	labels := map[string]string{
+		KubernetesSandboxLabel:         s.name,
+		KubernetesNetworkLabel:         network,
+		"app.kubernetes.io/managed-by": "back-end-tcc",
+	}
+	resources := corev1.ResourceRequirements{
+		Limits:   corev1.ResourceList{},
+		Requests: corev1.ResourceList{},
+	}
+	if limits.CPUs > 0 {
+		resources.Limits[corev1.ResourceCPU] = *resource.NewMilliQuantity(int64(limits.CPUs*1000), resource.DecimalSI)
+		request := limits.CPUs
+		if s.cfg.CPURequest > 0 && s.cfg.CPURequest < request {
+			request = s.cfg.CPURequest
+		}
+		resources.Requests[corev1.ResourceCPU] = *resource.NewMilliQuantity(int64(request*1000), resource.DecimalSI)
+	}
+	if limits.MemoryMB > 0 {
+		resources.Limits[corev1.ResourceMemory] = *resource.NewQuantity(limits.MemoryMB<<20, resource.BinarySI)
+		request := limits.MemoryMB
+		if s.cfg.MemoryRequestMB > 0 && s.cfg.MemoryRequestMB < request {
+			request = s.cfg.MemoryRequestMB
+		}
+		resources.Requests[corev1.ResourceMemory] = *resource.NewQuantity(request<<20, resource.BinarySI)
+	}
+
+	no, yes := false, true
+	backoff := int32(0)
+	ttl := int32(60)
+	deadline := int64(s.cfg.MaxLifetime.Seconds())
+	workdirSize := resource.NewQuantity(limits.DiskMB<<20, resource.BinarySI)
+	tmpSize := resource.NewQuantity(kubernetesTmpMB<<20, resource.BinarySI)
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{Name: s.name, Namespace: s.cfg.Namespace, Labels: labels},
+		Spec: batchv1.JobSpec{
+			BackoffLimit:            &backoff,
+			ActiveDeadlineSeconds:   &deadline,
+			TTLSecondsAfterFinished: &ttl,
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: labels},
+				Spec: corev1.PodSpec{
+					RestartPolicy:                corev1.RestartPolicyNever,
+					ServiceAccountName:           s.cfg.ServiceAccount,
+					AutomountServiceAccountToken: &no,
+					EnableServiceLinks:           &no,
+					NodeSelector:                 s.cfg.NodeSelector,
+					SecurityContext:              s.podSecurityContext(),
+					Containers: []corev1.Container{{
+						Name:       kubernetesContainer,
+						Image:      s.spec.Image,
+						Command:    []string{"tail", "-f", "/dev/null"}, // Keep running
+						WorkingDir: s.spec.Workdir,
+						Env:        []corev1.EnvVar{{Name: "HOME", Value: s.spec.Workdir}},
+						Resources:  resources,
+						SecurityContext: &corev1.SecurityContext{
+							AllowPrivilegeEscalation: &no,
+							ReadOnlyRootFilesystem:   &yes,
+							Capabilities:             &corev1.Capabilities{Drop: []corev1.Capability{"ALL"}},
+						},
+						VolumeMounts: []corev1.VolumeMount{
+							{Name: "workdir", MountPath: s.spec.Workdir},
+							{Name: "tmp", MountPath: "/tmp"},
+						},
+					}},
+					Volumes: []corev1.Volume{
+						{Name: "workdir", VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{SizeLimit: workdirSize}}},
+						{Name: "tmp", VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{SizeLimit: tmpSize}}},
+					},
+				},
+			},
+		},
+	}
+}
+
+// podSecurityContext maps a numeric "uid[:gid]" spec user onto the pod; the group also owns the volumes.
+// Named users cannot be resolved outside the image and leave the image default.
+func (s *KubernetesSandbox) podSecurityContext() *corev1.PodSecurityContext {
+	sc := &corev1.PodSecurityContext{SeccompProfile: &corev1.SeccompProfile{Type: corev1.SeccompProfileTypeRuntimeDefault}}
+	uidPart, gidPart, hasGID := strings.Cut(s.spec.User, ":")
+	uid, err := strconv.ParseInt(uidPart, 10, 64)
+	if err != nil {
+		return sc
+	}
+	nonRoot := uid != 0
+	sc.RunAsUser = &uid
+	sc.RunAsNonRoot = &nonRoot
+	if gid, err := strconv.ParseInt(gidPart, 10, 64); hasGID && err == nil {
+		sc.RunAsGroup = &gid
+		sc.FSGroup = &gid
+	}
+	return sc
+}
+
+// Exec runs the command through the pod exec API. Like Docker sandboxes, the command records its PID
+// so it can be killed from a second exec when opts.Timeout expires or ctx is cancelled.
+func (s *KubernetesSandbox) Exec(ctx context.Context, cmd []string, opts ExecOptions) (ExecResult, error) {
+	if s.pod == "" {
+		return ExecResult{}, fmt.Errorf("sandbox not started")
+	}
+	if len(cmd) == 0 {
+		return ExecResult{}, fmt.Errorf("empty command")
+	}
+	opts = opts.withDefaults()
+	ctx, cancel := context.WithTimeout(ctx, opts.Timeout)
+	defer cancel()
+
+	pidFile := fmt.Sprintf("/tmp/.sandbox-exec-%d.pid", atomic.AddUint64(&execSeq, 1))
+	stdout := newCappedBuffer(opts.MaxOutputBytes)
+	stderr := newCappedBuffer(opts.MaxOutputBytes)
+	start := time.Now()
+	err := s.executor.Exec(ctx, s.cfg.Namespace, s.pod, kubernetesContainer, s.wrap(pidFile, opts.Env, cmd), nil, stdout, stderr)
+
+	res := ExecResult{
+		Stdout:          stdout.String(),
+		Stderr:          stderr.String(),
+		Duration:        time.Since(start),
+		StdoutTruncated: stdout.truncated,
+		StderrTruncated: stderr.truncated,
+	}
+	if ctx.Err() != nil {
+		s.kill(pidFile)
+		res.ExitCode = TimedOutExitCode
+		res.TimedOut = true
+		if errors.Is(ctx.Err(), context.Canceled) {
+			return res, ctx.Err()
+		}
+		return res, nil
+	}
+	code, err := execExitCode(err)
+	if err != nil {
+		return res, fmt.Errorf("failed to exec: %w", err)
+	}
+	res.ExitCode = code
+	s.forget(pidFile)
+	return res, nil
+}
+
+// wrap prefixes cmd with the PID recording wrapper and, since pod exec takes no environment, env.
+func (s *KubernetesSandbox) wrap(pidFile string, env, cmd []string) []string {
+	wrapped := []string{"sh", "-c", execWrapper, pidFile}
+	if len(env) > 0 {
+		wrapped = append(append(wrapped, "env"), env...)
+	}
+	return append(wrapped, cmd...)
+}
+
+// execExitCode maps an executor error onto an exit code; other errors are infrastructure failures.
+func execExitCode(err error) (int, error) {
+	if err == nil {
+		return 0, nil
+	}
+	var exitErr interface{ ExitStatus() int }
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitStatus(), nil
+	}
+	return 0, err
+}
+
+// kill terminates the process group recorded in pidFile, falling back to the single process.
+func (s *KubernetesSandbox) kill(pidFile string) {
+	s.execQuiet("sh", "-c", killScript, pidFile)
+}
+
+// forget removes the PID file of a command that exited on its own.
+func (s *KubernetesSandbox) forget(pidFile string) {
+	s.execQuiet("rm", "-f", "--", pidFile)
+}
+
+// execQuiet runs a housekeeping command, discarding its output and errors.
+func (s *KubernetesSandbox) execQuiet(cmd ...string) {
+	ctx, cancel := context.WithTimeout(s.ctx, 5*time.Second)
+	defer cancel()
+	_ = s.executor.Exec(ctx, s.cfg.Namespace, s.pod, kubernetesContainer, cmd, nil, io.Discard, io.Discard)
+}
+
+// OpenSession starts an interactive shell through an exec with stdin attached.
+func (s *KubernetesSandbox) OpenSession(ctx context.Context, opts SessionOptions) (Session, error) {
+	if s.pod == "" {
+		return nil, fmt.Errorf("sandbox not started")
+	}
+	pidFile := fmt.Sprintf("/tmp/.sandbox-exec-%d.pid", atomic.AddUint64(&execSeq, 1))
+	stdin, stdinWriter := io.Pipe()
+	out := newSessionBuffer(opts.MaxBufferBytes)
+	sessionCtx, cancel := context.WithCancel(s.ctx)
+	exited := make(chan struct{})
+	go func() {
+		defer close(exited)
+		err := s.executor.Exec(sessionCtx, s.cfg.Namespace, s.pod, kubernetesContainer, s.wrap(pidFile, opts.Env, []string{"sh"}), stdin, out, out)
+		code, err := execExitCode(err)
+		if err != nil {
+			code = TimedOutExitCode
+		} else {
+			s.forget(pidFile)
+		}
+		out.finish(code)
+	}()
+	return newStreamSession(stdinWriter, out, func() error {
+		s.kill(pidFile)
+		cancel()
+		<-exited
+		return nil
+	}), nil
+}
+
+// CopyIn extracts the archive with tar inside the pod.
+func (s *KubernetesSandbox) CopyIn(dstDir string, archive io.Reader) error {
+	if s.pod == "" {
+		return fmt.Errorf("sandbox not started")
+	}
+	script := fmt.Sprintf(`[ -d "$0" ] || exit %d; exec tar -x -m -f - -C "$0"`, copyNotFoundExit)
+	var stderr bytes.Buffer
+	err := s.executor.Exec(s.ctx, s.cfg.Namespace, s.pod, kubernetesContainer, []string{"sh", "-c", script, dstDir}, archive, io.Discard, &stderr)
+	return copyError(dstDir, err, &stderr)
+}
+
+// CopyOut archives the path with tar inside the pod.
+func (s *KubernetesSandbox) CopyOut(srcPath string) (io.ReadCloser, error) {
+	if s.pod == "" {
+		return nil, fmt.Errorf("sandbox not started")
+	}
+	script := fmt.Sprintf(`[ -e "$0" ] || exit %d; cd "$(dirname "$0")" && exec tar -c -f - "$(basename "$0")"`, copyNotFoundExit)
+	var stdout, stderr bytes.Buffer
+	err := s.executor.Exec(s.ctx, s.cfg.Namespace, s.pod, kubernetesContainer, []string{"sh", "-c", script, srcPath}, nil, &stdout, &stderr)
+	if err := copyError(srcPath, err, &stderr); err != nil {
+		return nil, err
+	}
+	return io.NopCloser(&stdout), nil
+}
+
+func copyError(p string, err error, stderr *bytes.Buffer) error {
+	code, err := execExitCode(err)
+	switch {
+	case err != nil:
+		return fmt.Errorf("failed to exec tar: %w", err)
+	case code == copyNotFoundExit:
+		return fmt.Errorf("%s: %w", p, ErrNotFound)
+	case code != 0:
+		return fmt.Errorf("tar exited with code %d: %s", code, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+// Workdir returns the working directory inside the pod.
+func (s *KubernetesSandbox) Workdir() string {
+	return s.spec.Workdir
+}
+
+// ID returns the job name.
+func (s *KubernetesSandbox) ID() string {
+	return s.name
+}
+
+// spdyExecutor runs commands through the pods/exec subresource.
+type spdyExecutor struct {
+	client kubernetes.Interface
+	config *rest.Config
+}
+
+func (e spdyExecutor) Exec(ctx context.Context, namespace, pod, container string, cmd []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	if e.config == nil {
+		return errors.New("no REST config for pod exec")
+	}
+	req := e.client.CoreV1().RESTClient().Post().
+		Resource("pods").Namespace(namespace).Name(pod).SubResource("exec").
+		VersionedParams(&corev1.PodExecOptions{
+			Container: container,
+			Command:   cmd,
+			Stdin:     stdin != nil,
+			Stdout:    true,
+			Stderr:    true,
+		}, scheme.ParameterCodec)
+	executor, err := remotecommand.NewSPDYExecutor(e.config, http.MethodPost, req.URL())
+	if err != nil {
+		return err
+	}
+	return executor.StreamWithContext(ctx, remotecommand.StreamOptions{Stdin: stdin, Stdout: stdout, Stderr: stderr})
+}
+
+func randomSuffix() string {
+	b := make([]byte, 5)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,188 @@
+package sandbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+type exitError int
+
+func (e exitError) Error() string   { return fmt.Sprintf("command terminated with exit code %d", int(e)) }
+func (e exitError) ExitStatus() int { return int(e) }
+
+// recordingExecutor answers pod execs with respond and records the commands.
+type recordingExecutor struct {
+	mu      sync.Mutex
+	cmds    [][]string
+	respond func(cmd []string, stdin io.Reader, stdout, stderr io.Writer) error
+}
+
+func (e *recordingExecutor) Exec(ctx context.Context, namespace, pod, container string, cmd []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	e.mu.Lock()
+	e.cmds = append(e.cmds, cmd)
+	e.mu.Unlock()
+	if e.respond == nil {
+		return nil
+	}
+	return e.respond(cmd, stdin, stdout, stderr)
+}
+
+// newFakeCluster returns a clientset that schedules a pod in the given phase for every created job.
+// Its namespaces have the deny-all policy sandboxes without network require.
+func newFakeCluster(phase corev1.PodPhase, waiting string) *fake.Clientset {
+	client := fake.NewSimpleClientset(DenyAllNetworkPolicy("default"), DenyAllNetworkPolicy("evals"))
+	client.PrependReactor("create", "jobs", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		job := action.(k8stesting.CreateAction).GetObject().(*batchv1.Job)
+		status := corev1.ContainerStatus{Name: kubernetesContainer, Ready: phase == corev1.PodRunning}
+		if waiting != "" {
+			status.State.Waiting = &corev1.ContainerStateWaiting{Reason: waiting}
+		}
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: job.Name + "-x7k2p", Namespace: job.Namespace, Labels: job.Spec.Template.Labels},
+			Status:     corev1.PodStatus{Phase: phase, ContainerStatuses: []corev1.ContainerStatus{status}},
+		}
+		return false, nil, client.Tracker().Add(pod)
+	})
+	return client
+}
+
+func TestKubernetesSandboxCreatesJobAndCleansUp(t *testing.T) {
+	client := newFakeCluster(corev1.PodRunning, "")
+	exec := &recordingExecutor{respond: func(cmd []string, _ io.Reader, stdout, _ io.Writer) error {
+		switch cmd[len(cmd)-1] {
+		case "fail":
+			return exitError(3)
+		case "hello":
+			io.WriteString(stdout, "hello\n")
+		}
+		return nil
+	}}
+	spec := NewSpec(DefaultImage, WithUser("1000:1000"), WithLimits(Limits{CPUs: 2, MemoryMB: 1024, DiskMB: 512}))
+	sb := NewKubernetesSandbox(client, nil, spec, KubernetesConfig{
+		Namespace:       "evals",
+		ServiceAccount:  "sandbox-runner",
+		CPURequest:      0.5,
+		MemoryRequestMB: 4096,
+	}, WithPodExecutor(exec))
+
+	if err := sb.Start(); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	job, err := client.BatchV1().Jobs("evals").Get(context.Background(), sb.ID(), metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("get job: %v", err)
+	}
+	pod := job.Spec.Template.Spec
+	container := pod.Containers[0]
+	if pod.ServiceAccountName != "sandbox-runner" || *pod.AutomountServiceAccountToken {
+		t.Errorf("unexpected service account settings: %q automount=%v", pod.ServiceAccountName, *pod.AutomountServiceAccountToken)
+	}
+	if got := container.Resources.Limits.Cpu().String(); got != "2" {
+		t.Errorf("expected cpu limit 2, got %s", got)
+	}
+	if got := container.Resources.Requests.Cpu().String(); got != "500m" {
+		t.Errorf("expected cpu request 500m, got %s", got)
+	}
+	// Requests above the limit are clamped to it.
+	if got := container.Resources.Requests.Memory().String(); got != "1Gi" {
+		t.Errorf("expected memory request 1Gi, got %s", got)
+	}
+	if *pod.SecurityContext.RunAsUser != 1000 || !*container.SecurityContext.ReadOnlyRootFilesystem {
+		t.Errorf("expected hardened pod running as 1000, got %+v", pod.SecurityContext)
+	}
+	if job.Spec.Template.Labels[KubernetesNetworkLabel] != "none" {
+		t.Errorf("expected network label none, got %v", job.Spec.Template.Labels)
+	}
+
+	ctx := context.Background()
+	res, err := sb.Exec(ctx, []string{"echo", "hello"}, ExecOptions{Env: []string{"A=1"}})
+	if err != nil || res.Stdout != "hello\n" || res.ExitCode != 0 {
+		t.Fatalf("unexpected exec result %+v (err %v)", res, err)
+	}
+	if got := strings.Join(exec.cmds[0][4:], " "); got != "env A=1 echo hello" {
+		t.Errorf("expected env to wrap the command, got %q", got)
+	}
+	if got := strings.Join(exec.cmds[1], " "); got != "rm -f -- "+exec.cmds[0][3] {
+		t.Errorf("expected the PID file to be removed after the command, got %q", got)
+	}
+	if res, err := sb.Exec(ctx, []string{"fail"}, ExecOptions{}); err != nil || res.ExitCode != 3 {
+		t.Fatalf("expected exit code 3, got %+v (err %v)", res, err)
+	}
+
+	if err := sb.Stop(); err != nil {
+		t.Fatalf("stop: %v", err)
+	}
+	jobs, _ := client.BatchV1().Jobs("evals").List(ctx, metav1.ListOptions{})
+	pods, _ := client.CoreV1().Pods("evals").List(ctx, metav1.ListOptions{})
+	if len(jobs.Items) != 0 || len(pods.Items) != 0 {
+		t.Fatalf("expected job and pod deleted, got %d jobs and %d pods", len(jobs.Items), len(pods.Items))
+	}
+}
+
+func TestKubernetesSandboxRequiresNetworkPolicy(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	sb := NewKubernetesSandbox(client, nil, DefaultSpec(DefaultImage), KubernetesConfig{StartTimeout: time.Second}, WithPodExecutor(&recordingExecutor{}))
+	if err := sb.Start(); !errors.Is(err, ErrNoNetworkPolicy) {
+		t.Fatalf("expected a missing network policy error, got %v", err)
+	}
+	jobs, _ := client.BatchV1().Jobs("default").List(context.Background(), metav1.ListOptions{})
+	if len(jobs.Items) != 0 {
+		t.Fatalf("expected no job without the policy, got %d", len(jobs.Items))
+	}
+	// Sandboxes with network do not need the policy.
+	cluster := newFakeCluster(corev1.PodRunning, "")
+	if err := cluster.NetworkingV1().NetworkPolicies("default").Delete(context.Background(), "sandbox-deny-all", metav1.DeleteOptions{}); err != nil {
+		t.Fatalf("delete policy: %v", err)
+	}
+	networked := NewKubernetesSandbox(cluster, nil, NewSpec(DefaultImage, WithNetwork(true)), KubernetesConfig{StartTimeout: 5 * time.Second}, WithPodExecutor(&recordingExecutor{}))
+	if err := networked.Start(); err != nil {
+		t.Fatalf("start with network: %v", err)
+	}
+	networked.Stop()
+}
+
+func TestKubernetesSandboxStartFailureDeletesJob(t *testing.T) {
+	client := newFakeCluster(corev1.PodPending, "ImagePullBackOff")
+	sb := NewKubernetesSandbox(client, nil, DefaultSpec("missing:latest"), KubernetesConfig{StartTimeout: 5 * time.Second}, WithPodExecutor(&recordingExecutor{}))
+
+	err := sb.Start()
+	if err == nil || !strings.Contains(err.Error(), "ImagePullBackOff") {
+		t.Fatalf("expected image pull error, got %v", err)
+	}
+	jobs, _ := client.BatchV1().Jobs(DefaultKubernetesNamespace).List(context.Background(), metav1.ListOptions{})
+	if len(jobs.Items) != 0 {
+		t.Fatalf("expected job deleted after failed start, got %d", len(jobs.Items))
+	}
+}
+
+func TestKubernetesSandboxCopyMapsMissingPaths(t *testing.T) {
+	client := newFakeCluster(corev1.PodRunning, "")
+	exec := &recordingExecutor{respond: func(cmd []string, _ io.Reader, _, _ io.Writer) error {
+		return exitError(copyNotFoundExit)
+	}}
+	sb := NewKubernetesSandbox(client, nil, DefaultSpec(DefaultImage), KubernetesConfig{}, WithPodExecutor(exec))
+	if err := sb.Start(); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	defer sb.Stop()
+
+	if _, err := sb.CopyOut("/workspace/missing"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound from CopyOut, got %v", err)
+	}
+	if err := sb.CopyIn("/workspace/missing", strings.NewReader("")); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound from CopyIn, got %v", err)
+	}
+}
@@ -0,0 +1,406 @@
+package sandbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/example/back-end-tcc/pkg/logger"
+	"github.com/example/back-end-tcc/pkg/observability/metrics"
+)
+
+// Pool defaults used when no option overrides them.
+const (
+	DefaultPoolSize    = 2
+	DefaultPoolMaxUses = 20
+)
+
+const (
+	poolHealthTimeout = 5 * time.Second
+	poolResetTimeout  = 30 * time.Second
+)
+
+// ErrPoolClosed is returned by Acquire once the pool has been closed.
+var ErrPoolClosed = errors.New("sandbox: pool closed")
+
+// PoolOption customises a Pool.
+type PoolOption func(*Pool)
+
+// WithPoolSize sets how many idle sandboxes are kept warm per spec.
+func WithPoolSize(n int) PoolOption {
+	return func(p *Pool) {
+		if n >= 0 {
+			p.size = n
+		}
+	}
+}
+
+// WithMaxUses sets how many leases a sandbox serves before it is recycled. 1 disables reuse.
+func WithMaxUses(n int) PoolOption {
+	return func(p *Pool) {
+		if n > 0 {
+			p.maxUses = n
+		}
+	}
+}
+
+// WithPoolLogger attaches a logger.
+func WithPoolLogger(l logger.Logger) PoolOption {
+	return func(p *Pool) {
+		p.log = l
+	}
+}
+
+// WithPoolMetrics attaches a metrics recorder.
+func WithPoolMetrics(rec metrics.Recorder) PoolOption {
+	return func(p *Pool) {
+		p.metrics = rec
+	}
+}
+
+// PoolStats is a point-in-time view of the pool.
+type PoolStats struct {
+	Idle          int    `json:"idle"`
+	Starting      int    `json:"starting"`
+	Leased        int    `json:"leased"`
+	WarmLeases    uint64 `json:"warmLeases"`
+	ColdLeases    uint64 `json:"coldLeases"`
+	Created       uint64 `json:"created"`
+	Destroyed     uint64 `json:"destroyed"`
+	Resets        uint64 `json:"resets"`
+	ResetFailures uint64 `json:"resetFailures"`
+}
+
+type pooledSandbox struct {
+	sb   Sandbox
+	spec Spec
+	uses int
+}
+
+type specPool struct {
+	idle     []*pooledSandbox
+	starting int
+}
+
+// Pool keeps pre-started sandboxes per spec and leases them to tasks. Released sandboxes are
+// reset and reused until they reach the use limit, then replaced by a freshly warmed one.
+type Pool struct {
+	factory Factory
+	size    int
+	maxUses int
+	log     logger.Logger
+	metrics metrics.Recorder
+
+	mu     sync.Mutex
+	pools  map[Spec]*specPool
+	leased map[Sandbox]*pooledSandbox
+	stats  PoolStats
+	closed bool
+	wg     sync.WaitGroup
+}
+
+// NewPool creates a pool. Nothing is started until Warm or the first Acquire for a spec.
+func NewPool(factory Factory, opts ...PoolOption) *Pool {
+	p := &Pool{
+		factory: factory,
+		size:    DefaultPoolSize,
+		maxUses: DefaultPoolMaxUses,
+		pools:   make(map[Spec]*specPool),
+		leased:  make(map[Sandbox]*pooledSandbox),
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// Warm starts filling the pool for each spec in the background.
+func (p *Pool) Warm(specs ...Spec) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, spec := range specs {
+		p.fillLocked(spec)
+	}
+}
+
+// Acquire leases an idle sandbox for spec, or starts one inline when none is warm. An inline start
+// counts as one of the pool's sandboxes, so a cold acquire starts at most the pool size in all.
+func (p *Pool) Acquire(ctx context.Context, spec Spec) (Sandbox, error) {
+	start := time.Now()
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		p.mu.Lock()
+		if p.closed {
+			p.mu.Unlock()
+			return nil, ErrPoolClosed
+		}
+		sp := p.specPoolLocked(spec)
+		var ps *pooledSandbox
+		if n := len(sp.idle); n > 0 {
+			ps = sp.idle[n-1]
+			sp.idle = sp.idle[:n-1]
+		}
+		if ps == nil {
+			// The inline start below counts towards the pool size, so the warm-ups only top it up.
+			sp.starting++
+		}
+		if ps == nil || ps.uses+1 >= p.maxUses {
+			// Start a replacement unless the leased sandbox will come back after a reset.
+			p.fillLocked(spec)
+		}
+		p.mu.Unlock()
+
+		if ps == nil {
+			break
+		}
+		if healthy(ctx, ps.sb) {
+			p.lease(ps, true)
+			p.observeAcquire(start, "warm")
+			return ps.sb, nil
+		}
+		p.destroy(ps.sb)
+		p.refill(spec)
+	}
+
+	sb, err := p.create(spec)
+	p.mu.Lock()
+	p.specPoolLocked(spec).starting--
+	p.mu.Unlock()
+	if err != nil {
+		p.observeAcquire(start, "error")
+		return nil, err
+	}
+	p.lease(&pooledSandbox{sb: sb, spec: spec}, false)
+	p.observeAcquire(start, "cold")
+	return sb, nil
+}
+
+// Release returns a leased sandbox. Resetting or recycling happens in the background.
+func (p *Pool) Release(sb Sandbox) {
+	if sb == nil {
+		return
+	}
+	p.mu.Lock()
+	ps, ok := p.leased[sb]
+	if ok {
+		delete(p.leased, sb)
+		p.stats.Leased--
+	}
+	p.mu.Unlock()
+	if !ok {
+		// Not leased by this pool.
+		sb.Stop()
+		return
+	}
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		p.recycle(ps)
+	}()
+}
+
+// Stats returns current pool counters.
+func (p *Pool) Stats() PoolStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	stats := p.stats
+	for _, sp := range p.pools {
+		stats.Idle += len(sp.idle)
+		stats.Starting += sp.starting
+	}
+	return stats
+}
+
+// Close stops idle sandboxes and waits for background work. Leased sandboxes are stopped on release.
+func (p *Pool) Close() error {
+	p.mu.Lock()
+	p.closed = true
+	var idle []*pooledSandbox
+	for _, sp := range p.pools {
+		idle = append(idle, sp.idle...)
+		sp.idle = nil
+	}
+	p.mu.Unlock()
+	for _, ps := range idle {
+		p.destroy(ps.sb)
+	}
+	p.wg.Wait()
+	return nil
+}
+
+func (p *Pool) recycle(ps *pooledSandbox) {
+	result := "reused"
+	defer func() { p.observeRelease(result) }()
+
+	if ps.uses >= p.maxUses {
+		result = "recycled"
+		p.destroy(ps.sb)
+		p.refill(ps.spec)
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), poolResetTimeout)
+	defer cancel()
+	if err := reset(ctx, ps.sb); err != nil {
+		result = "reset_failed"
+		if p.log != nil {
+			p.log.Printf("sandbox: reset of %s failed: %v", ps.sb.ID(), err)
+		}
+		p.mu.Lock()
+		p.stats.ResetFailures++
+		p.mu.Unlock()
+		p.destroy(ps.sb)
+		p.refill(ps.spec)
+		return
+	}
+
+	p.mu.Lock()
+	sp := p.specPoolLocked(ps.spec)
+	if p.closed || len(sp.idle) >= p.size {
+		p.mu.Unlock()
+		result = "recycled"
+		p.destroy(ps.sb)
+		return
+	}
+	sp.idle = append(sp.idle, ps)
+	p.stats.Resets++
+	p.mu.Unlock()
+}
+
+func (p *Pool) refill(spec Spec) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.fillLocked(spec)
+}
+
+// fillLocked starts background warm-ups until idle plus starting sandboxes reach the pool size.
+func (p *Pool) fillLocked(spec Spec) {
+	if p.closed {
+		return
+	}
+	sp := p.specPoolLocked(spec)
+	for len(sp.idle)+sp.starting < p.size {
+		sp.starting++
+		p.wg.Add(1)
+		go p.warm(spec)
+	}
+}
+
+func (p *Pool) warm(spec Spec) {
+	defer p.wg.Done()
+	sb, err := p.create(spec)
+
+	p.mu.Lock()
+	sp := p.specPoolLocked(spec)
+	sp.starting--
+	if err != nil {
+		p.mu.Unlock()
+		if p.log != nil {
+			p.log.Printf("sandbox: failed to warm %s: %v", spec.Image, err)
+		}
+		return
+	}
+	if p.closed {
+		p.mu.Unlock()
+		p.destroy(sb)
+		return
+	}
+	sp.idle = append(sp.idle, &pooledSandbox{sb: sb, spec: spec})
+	p.mu.Unlock()
+}
+
+func (p *Pool) create(spec Spec) (Sandbox, error) {
+	start := time.Now()
+	sb, err := startSandbox(p.factory, spec)
+	if err != nil {
+		return nil, err
+	}
+	p.mu.Lock()
+	p.stats.Created++
+	p.mu.Unlock()
+	if p.metrics != nil {
+		labels := map[string]string{"image": spec.Image}
+		p.metrics.AddCounter("sandbox_pool_created_total", labels, 1)
+		p.metrics.ObserveHistogram("sandbox_pool_start_ms", labels, float64(time.Since(start).Milliseconds()))
+	}
+	return sb, nil
+}
+
+func (p *Pool) destroy(sb Sandbox) {
+	if err := sb.Stop(); err != nil && p.log != nil {
+		p.log.Printf("sandbox: failed to stop %s: %v", sb.ID(), err)
+	}
+	p.mu.Lock()
+	p.stats.Destroyed++
+	p.mu.Unlock()
+}
+
+func (p *Pool) lease(ps *pooledSandbox, warm bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	ps.uses++
+	p.leased[ps.sb] = ps
+	p.stats.Leased++
+	if warm {
+		p.stats.WarmLeases++
+	} else {
+		p.stats.ColdLeases++
+	}
+}
+
+func (p *Pool) specPoolLocked(spec Spec) *specPool {
+	sp, ok := p.pools[spec]
+	if !ok {
+		sp = &specPool{}
+		p.pools[spec] = sp
+	}
+	return sp
+}
+
+func (p *Pool) observeAcquire(start time.Time, result string) {
+	if p.metrics == nil {
+		return
+	}
+	labels := map[string]string{"result": result}
+	p.metrics.AddCounter("sandbox_pool_acquire_total", labels, 1)
+	p.metrics.ObserveHistogram("sandbox_pool_acquire_ms", labels, float64(time.Since(start).Milliseconds()))
+}
+
+func (p *Pool) observeRelease(result string) {
+	if p.metrics == nil {
+		return
+	}
+	p.metrics.AddCounter("sandbox_pool_release_total", map[string]string{"result": result}, 1)
+}
+
+// healthy reports whether an idle sandbox still answers commands.
+func healthy(ctx context.Context, sb Sandbox) bool {
+	res, err := sb.Exec(ctx, []string{"true"}, ExecOptions{Timeout: poolHealthTimeout})
+	return err == nil && res.Success()
+}
+
+// reset returns a sandbox to a clean state. Sandboxes without their own Reset get their workdir wiped.
+func reset(ctx context.Context, sb Sandbox) error {
+	if r, ok := sb.(Resetter); ok {
+		return r.Reset(ctx)
+	}
+	return wipe(ctx, sb, sb.Workdir())
+}
+
+// wipe deletes the contents of the given directories, keeping the directories themselves.
+func wipe(ctx context.Context, sb Sandbox, dirs ...string) error {
+	cmd := append([]string{"find"}, dirs...)
+	cmd = append(cmd, "-mindepth", "1", "-delete")
+	res, err := sb.Exec(ctx, cmd, ExecOptions{Timeout: poolResetTimeout})
+	if err != nil {
+		return err
+	}
+	if !res.Success() {
+		return fmt.Errorf("wipe exited with code %d: %s", res.ExitCode, strings.TrimSpace(res.Stderr))
+	}
+	return nil
+}
@@ -0,0 +1,412 @@
+package sandbox
+
+import (
+	"archive/tar"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/user"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ProcessOption customises a ProcessSandbox.
+type ProcessOption func(*processOptions)
+
+type processOptions struct {
+	userNamespace    bool
+	networkNamespace bool
+}
+
+// WithUserNamespace runs commands in a new user namespace mapping only the sandbox user.
+func WithUserNamespace(enabled bool) ProcessOption {
+	return func(o *processOptions) {
+		o.userNamespace = enabled
+	}
+}
+
+// WithNetworkNamespace runs commands in a new network namespace (loopback only) unless the spec
+// enables network access. Unprivileged hosts also get a user namespace, which the kernel requires.
+func WithNetworkNamespace(enabled bool) ProcessOption {
+	return func(o *processOptions) {
+		o.networkNamespace = enabled
+	}
+}
+
+// ProcessFactory creates process sandboxes with the given options.
+func ProcessFactory(opts ...ProcessOption) Factory {
+	return func(spec Spec) (Sandbox, error) {
+		return NewProcessSandbox(spec, opts...)
+	}
+}
+
+// ProcessSandbox runs commands as host processes in a private temporary directory. It needs no
+// container runtime, but offers weaker isolation than DockerSandbox:
+//   - the image and the spec workdir are ignored; Workdir returns the host path of the temp directory;
+//   - memory, file size and process limits are rlimits, CPU limits are not enforced;
+//   - commands can read the host filesystem, only CopyIn and CopyOut are confined to the sandbox;
+//   - the spec user is honoured only when the runner itself runs as root;
+//   - background processes are killed when the command that started them returns.
+type ProcessSandbox struct {
+	spec    Spec
+	opts    processOptions
+	runAs   *ids
+	root    string
+	workdir string
+	tmpdir  string
+
+	mu     sync.Mutex
+	groups map[int]struct{} // Process groups of running commands
+}
+
+type ids struct {
+	uid, gid int
+}
+
+// NewProcessSandbox creates a process sandbox. Nothing touches the host until Start.
+func NewProcessSandbox(spec Spec, opts ...ProcessOption) (*ProcessSandbox, error) {
+	s := &ProcessSandbox{spec: spec, groups: make(map[int]struct{})}
+	for _, opt := range opts {
+		opt(&s.opts)
+	}
+	if os.Geteuid() == 0 && spec.User != "" {
+		runAs, err := lookupIDs(spec.User)
+		if err != nil {
+			return nil, err
+		}
+		s.runAs = runAs
+	}
+	return s, nil
+}
+
+// Start creates the sandbox directories and checks that commands can be spawned.
+func (s *ProcessSandbox) Start() error {
+	root, err := os.MkdirTemp("", "sandbox-")
+	if err != nil {
+		return fmt.Errorf("failed to create sandbox directory: %w", err)
+	}
+	s.root = root
+	s.workdir = filepath.Join(root, "workspace")
+	s.tmpdir = filepath.Join(root, "tmp")
+	if err := s.prepareDirs(); err != nil {
+		s.Stop()
+		return err
+	}
+	res, err := s.Exec(context.Background(), []string{"true"}, ExecOptions{Timeout: poolHealthTimeout})
+	if err == nil && !res.Success() {
+		err = fmt.Errorf("exit code %d: %s", res.ExitCode, strings.TrimSpace(res.Stderr))
+	}
+	if err != nil {
+		s.Stop()
+		return fmt.Errorf("failed to start process sandbox: %w", err)
+	}
+	return nil
+}
+
+func (s *ProcessSandbox) prepareDirs() error {
+	if err := os.Mkdir(s.workdir, 0o755); err != nil {
+		return err
+	}
+	if err := os.Mkdir(s.tmpdir, 0o777); err != nil {
+		return err
+	}
+	if err := os.Chmod(s.tmpdir, 0o1777); err != nil {
+		return err
+	}
+	if s.runAs == nil {
+		return nil
+	}
+	// The temp root is private to the runner; the sandbox user may only traverse it.
+	if err := os.Chmod(s.root, 0o711); err != nil {
+		return err
+	}
+	return os.Chown(s.workdir, s.runAs.uid, s.runAs.gid)
+}
+
+// Stop kills running commands and removes the sandbox directory.
+func (s *ProcessSandbox) Stop() error {
+	s.killAll()
+	if s.root == "" {
+		return nil
+	}
+	err := os.RemoveAll(s.root)
+	s.root = ""
+	return err
+}
+
+// Reset kills running commands and empties the workdir and temp directory.
+func (s *ProcessSandbox) Reset(ctx context.Context) error {
+	if s.root == "" {
+		return fmt.Errorf("sandbox not started")
+	}
+	s.killAll()
+	for _, dir := range []string{s.workdir, s.tmpdir} {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			if err := os.RemoveAll(filepath.Join(dir, entry.Name())); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Workdir returns the host path of the sandbox working directory.
+func (s *ProcessSandbox) Workdir() string {
+	return s.workdir
+}
+
+// ID returns the sandbox identifier.
+func (s *ProcessSandbox) ID() string {
+	return "process-" + strings.TrimPrefix(filepath.Base(s.root), "sandbox-")
+}
+
+// environ builds the command environment from scratch so runner secrets never leak into the sandbox.
+func (s *ProcessSandbox) environ(extra []string) []string {
+	env := []string{
+		"PATH=/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin",
+		"HOME=" + s.workdir,
+		"TMPDIR=" + s.tmpdir,
+	}
+	return append(env, extra...)
+}
+
+func (s *ProcessSandbox) track(pgid int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.groups[pgid] = struct{}{}
+}
+
+func (s *ProcessSandbox) untrack(pgid int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.groups, pgid)
+}
+
+func (s *ProcessSandbox) killAll() {
+	s.mu.Lock()
+	groups := make([]int, 0, len(s.groups))
+	for pgid := range s.groups {
+		groups = append(groups, pgid)
+	}
+	s.mu.Unlock()
+	for _, pgid := range groups {
+		killGroup(pgid)
+	}
+}
+
+// CopyIn extracts a tar archive into dstDir, which must be inside the sandbox.
+func (s *ProcessSandbox) CopyIn(dstDir string, archive io.Reader) error {
+	rel, err := s.relative(dstDir)
+	if err != nil {
+		return err
+	}
+	root, err := os.OpenRoot(s.root)
+	if err != nil {
+		return err
+	}
+	defer root.Close()
+	info, err := root.Stat(rel)
+	if errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("%s: %w", dstDir, ErrNotFound)
+	}
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", dstDir)
+	}
+
+	tr := tar.NewReader(archive)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read archive: %w", err)
+		}
+		name := path.Clean(strings.TrimPrefix(header.Name, "/"))
+		if !filepath.IsLocal(filepath.FromSlash(name)) {
+			return fmt.Errorf("invalid archive entry %q", header.Name)
+		}
+		target := filepath.Join(rel, filepath.FromSlash(name))
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := s.mkdirAll(root, target, header.FileInfo().Mode().Perm()); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := s.mkdirAll(root, filepath.Dir(target), 0o755); err != nil {
+				return err
+			}
+			if err := s.extractFile(root, target, header, tr); err != nil {
+				return err
+			}
+		default:
+			// Links and special files are not supported.
+		}
+	}
+}
+
+func (s *ProcessSandbox) mkdirAll(root *os.Root, dir string, perm fs.FileMode) error {
+	if dir == "." {
+		return nil
+	}
+	if info, err := root.Stat(dir); err == nil {
+		if !info.IsDir() {
+			return fmt.Errorf("%s is not a directory", dir)
+		}
+		return nil
+	}
+	if err := s.mkdirAll(root, filepath.Dir(dir), 0o755); err != nil {
+		return err
+	}
+	if err := root.Mkdir(dir, perm); err != nil && !errors.Is(err, fs.ErrExist) {
+		return err
+	}
+	return s.chown(root, dir)
+}
+
+func (s *ProcessSandbox) extractFile(root *os.Root, target string, header *tar.Header, r io.Reader) error {
+	f, err := root.OpenFile(target, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, header.FileInfo().Mode().Perm())
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if _, err := io.Copy(f, r); err != nil {
+		return err
+	}
+	if s.runAs != nil {
+		return f.Chown(s.runAs.uid, s.runAs.gid)
+	}
+	return nil
+}
+
+func (s *ProcessSandbox) chown(root *os.Root, name string) error {
+	if s.runAs == nil {
+		return nil
+	}
+	f, err := root.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return f.Chown(s.runAs.uid, s.runAs.gid)
+}
+
+// CopyOut returns a tar archive of the file or directory at srcPath, which must be inside the sandbox.
+func (s *ProcessSandbox) CopyOut(srcPath string) (io.ReadCloser, error) {
+	rel, err := s.relative(srcPath)
+	if err != nil {
+		return nil, err
+	}
+	root, err := os.OpenRoot(s.root)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := root.Lstat(rel); err != nil {
+		root.Close()
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("%s: %w", srcPath, ErrNotFound)
+		}
+		return nil, err
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		defer root.Close()
+		pw.CloseWithError(writeArchive(pw, root.FS(), filepath.ToSlash(rel)))
+	}()
+	return pr, nil
+}
+
+// writeArchive writes src and, for directories, everything below it. Entry names start with the base name of src.
+func writeArchive(w io.Writer, fsys fs.FS, src string) error {
+	tw := tar.NewWriter(w)
+	prefix := path.Dir(src)
+	err := fs.WalkDir(fsys, src, func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		header, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		header.Name = strings.TrimPrefix(strings.TrimPrefix(name, prefix), "/")
+		if d.IsDir() {
+			header.Name += "/"
+			return tw.WriteHeader(header)
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		f, err := fsys.Open(name)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(tw, f)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return tw.Close()
+}
+
+// relative maps an absolute host path to a path relative to the sandbox root.
+func (s *ProcessSandbox) relative(p string) (string, error) {
+	if s.root == "" {
+		return "", fmt.Errorf("sandbox not started")
+	}
+	if !filepath.IsAbs(p) {
+		return "", fmt.Errorf("path must be absolute: %s", p)
+	}
+	rel, err := filepath.Rel(s.root, filepath.Clean(p))
+	if err != nil || !filepath.IsLocal(rel) {
+		return "", fmt.Errorf("%s is outside the sandbox", p)
+	}
+	return rel, nil
+}
+
+// lookupIDs resolves "uid[:gid]" or a user name.
+func lookupIDs(spec string) (*ids, error) {
+	name, group, hasGroup := strings.Cut(spec, ":")
+	uid, err := strconv.Atoi(name)
+	gid := uid
+	if err != nil {
+		u, lookupErr := user.Lookup(name)
+		if lookupErr != nil {
+			return nil, fmt.Errorf("unknown sandbox user %q: %w", name, lookupErr)
+		}
+		uid, _ = strconv.Atoi(u.Uid)
+		gid, _ = strconv.Atoi(u.Gid)
+	}
+	if hasGroup {
+		if gid, err = strconv.Atoi(group); err != nil {
+			g, lookupErr := user.LookupGroup(group)
+			if lookupErr != nil {
+				return nil, fmt.Errorf("unknown sandbox group %q: %w", group, lookupErr)
+			}
+			gid, _ = strconv.Atoi(g.Gid)
+		}
+	}
+	return &ids{uid: uid, gid: gid}, nil
+}
@@ -0,0 +1,182 @@
+//go:build linux
+
+package sandbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// processWaitDelay bounds how long output pipes held open by background processes delay a finished command.
+const processWaitDelay = 250 * time.Millisecond
+
+// Exec runs the command in the sandbox workdir with a fresh environment, in its own process group.
+// The whole group is killed when opts.Timeout expires, ctx is cancelled or the command returns.
+func (s *ProcessSandbox) Exec(ctx context.Context, cmd []string, opts ExecOptions) (ExecResult, error) {
+	if s.root == "" {
+		return ExecResult{}, fmt.Errorf("sandbox not started")
+	}
+	if len(cmd) == 0 {
+		return ExecResult{}, fmt.Errorf("empty command")
+	}
+	opts = opts.withDefaults()
+	ctx, cancel := context.WithTimeout(ctx, opts.Timeout)
+	defer cancel()
+
+	stdout := newCappedBuffer(opts.MaxOutputBytes)
+	stderr := newCappedBuffer(opts.MaxOutputBytes)
+	c := exec.CommandContext(ctx, "/bin/sh", append([]string{"-c", s.limitsWrapper(), "sh"}, cmd...)...)
+	c.Dir = s.workdir
+	c.Env = s.environ(opts.Env)
+	c.Stdout = stdout
+	c.Stderr = stderr
+	c.SysProcAttr = s.sysProcAttr()
+	c.Cancel = func() error { return killGroup(c.Process.Pid) }
+	c.WaitDelay = processWaitDelay
+
+	start := time.Now()
+	if err := c.Start(); err != nil {
+		return ExecResult{}, fmt.Errorf("failed to start command: %w", err)
+	}
+	pgid := c.Process.Pid
+	s.track(pgid)
+	defer s.untrack(pgid)
+
+	err := c.Wait()
+	// Reap background processes left in the group.
+	killGroup(pgid)
+
+	res := ExecResult{
+		Stdout:          stdout.String(),
+		Stderr:          stderr.String(),
+		Duration:        time.Since(start),
+		StdoutTruncated: stdout.truncated,
+		StderrTruncated: stderr.truncated,
+	}
+	if ctx.Err() != nil {
+		res.ExitCode = TimedOutExitCode
+		res.TimedOut = true
+		if errors.Is(ctx.Err(), context.Canceled) {
+			return res, ctx.Err()
+		}
+		return res, nil
+	}
+	var exitErr *exec.ExitError
+	if err != nil && !errors.As(err, &exitErr) && !errors.Is(err, exec.ErrWaitDelay) {
+		return res, fmt.Errorf("failed to wait for command: %w", err)
+	}
+	res.ExitCode = exitCode(c.ProcessState)
+	return res, nil
+}
+
+// OpenSession starts an interactive shell in the workdir, with the same environment, limits and
+// isolation as Exec. The shell keeps running until it exits, Close is called or the sandbox stops.
+func (s *ProcessSandbox) OpenSession(ctx context.Context, opts SessionOptions) (Session, error) {
+	if s.root == "" {
+		return nil, fmt.Errorf("sandbox not started")
+	}
+	out := newSessionBuffer(opts.MaxBufferBytes)
+	c := exec.Command("/bin/sh", "-c", s.limitsWrapper(), "sh", "/bin/sh")
+	c.Dir = s.workdir
+	c.Env = s.environ(opts.Env)
+	// One writer for both streams keeps stdout and stderr in order.
+	c.Stdout = out
+	c.Stderr = out
+	c.SysProcAttr = s.sysProcAttr()
+	c.WaitDelay = processWaitDelay
+	stdin, err := c.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := c.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start shell: %w", err)
+	}
+	pgid := c.Process.Pid
+	s.track(pgid)
+
+	exited := make(chan struct{})
+	go func() {
+		defer close(exited)
+		c.Wait()
+		killGroup(pgid)
+		s.untrack(pgid)
+		out.finish(exitCode(c.ProcessState))
+	}()
+	return newStreamSession(stdin, out, func() error {
+		err := killGroup(pgid)
+		<-exited
+		return err
+	}), nil
+}
+
+func (s *ProcessSandbox) sysProcAttr() *syscall.SysProcAttr {
+	attr := &syscall.SysProcAttr{Setpgid: true, Pdeathsig: syscall.SIGKILL}
+	if s.runAs != nil {
+		attr.Credential = &syscall.Credential{Uid: uint32(s.runAs.uid), Gid: uint32(s.runAs.gid)}
+	}
+
+	netns := s.opts.networkNamespace && !s.spec.Network
+	if netns {
+		attr.Cloneflags |= syscall.CLONE_NEWNET
+	}
+	if s.opts.userNamespace || (netns && os.Geteuid() != 0) {
+		uid, gid := os.Getuid(), os.Getgid()
+		if s.runAs != nil {
+			uid, gid = s.runAs.uid, s.runAs.gid
+		}
+		// Only the sandbox user is mapped, so nothing else on the host is reachable by id.
+		attr.Cloneflags |= syscall.CLONE_NEWUSER
+		attr.UidMappings = []syscall.SysProcIDMap{{ContainerID: uid, HostID: uid, Size: 1}}
+		attr.GidMappings = []syscall.SysProcIDMap{{ContainerID: gid, HostID: gid, Size: 1}}
+		attr.GidMappingsEnableSetgroups = false
+		if attr.Credential != nil {
+			attr.Credential.NoSetGroups = true
+		}
+	}
+	return attr
+}
+
+// limitsWrapper returns a shell script that lowers rlimits and execs the command; the command and its
+// children inherit them. Lowering limits needs no privileges, unlike prlimit on a process of another user.
+func (s *ProcessSandbox) limitsWrapper() string {
+	limits := s.spec.Limits
+	script := []string{"ulimit -c 0"}
+	if limits.MemoryMB > 0 {
+		script = append(script, fmt.Sprintf("ulimit -v %d", limits.MemoryMB<<10))
+	}
+	if limits.DiskMB > 0 {
+		// POSIX counts file sizes in 512-byte blocks.
+		script = append(script, fmt.Sprintf("ulimit -f %d", limits.DiskMB<<11))
+	}
+	// RLIMIT_NPROC counts every process of the user, so it only applies to a dedicated sandbox user.
+	// dash spells it -p, bash -u.
+	if limits.PidsLimit > 0 && s.runAs != nil {
+		script = append(script, fmt.Sprintf("{ ulimit -u %[1]d || ulimit -p %[1]d; } 2>/dev/null", limits.PidsLimit))
+	}
+	return fmt.Sprintf(`%s || exit 126; exec "$@"`, strings.Join(script, " && "))
+}
+
+func killGroup(pgid int) error {
+	err := syscall.Kill(-pgid, syscall.SIGKILL)
+	if errors.Is(err, syscall.ESRCH) {
+		return nil
+	}
+	return err
+}
+
+func exitCode(state *os.ProcessState) int {
+	if state == nil {
+		return TimedOutExitCode
+	}
+	if ws, ok := state.Sys().(syscall.WaitStatus); ok && ws.Signaled() {
+		// Match the shell convention for commands killed by a signal.
+		return 128 + int(ws.Signal())
+	}
+	return state.ExitCode()
+}
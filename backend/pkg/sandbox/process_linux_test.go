@@ -0,0 +1,90 @@
+//go:build linux
+
+package sandbox_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/example/back-end-tcc/pkg/sandbox"
+	"github.com/example/back-end-tcc/pkg/sandbox/sandboxtest"
+)
+
+func newProcessSandbox(t *testing.T, opts ...sandbox.ProcessOption) sandbox.Sandbox {
+	t.Helper()
+	sb, err := sandbox.NewProcessSandbox(sandbox.DefaultSpec(""), opts...)
+	if err != nil {
+		t.Fatalf("failed to create sandbox: %v", err)
+	}
+	if err := sb.Start(); err != nil {
+		t.Fatalf("failed to start sandbox: %v", err)
+	}
+	return sb
+}
+
+func TestProcessSandboxConformance(t *testing.T) {
+	sandboxtest.Run(t, func(t *testing.T) sandbox.Sandbox {
+		return newProcessSandbox(t)
+	})
+}
+
+func TestProcessSandboxNamespacesConformance(t *testing.T) {
+	probe, err := sandbox.NewProcessSandbox(sandbox.DefaultSpec(""), sandbox.WithUserNamespace(true), sandbox.WithNetworkNamespace(true))
+	if err != nil {
+		t.Fatalf("failed to create sandbox: %v", err)
+	}
+	if err := probe.Start(); err != nil {
+		t.Skipf("namespaces not available: %v", err)
+	}
+	probe.Stop()
+
+	sandboxtest.Run(t, func(t *testing.T) sandbox.Sandbox {
+		return newProcessSandbox(t, sandbox.WithUserNamespace(true), sandbox.WithNetworkNamespace(true))
+	})
+}
+
+func TestProcessSandboxIsolation(t *testing.T) {
+	t.Setenv("RUNNER_SECRET", "do-not-leak")
+	sb := newProcessSandbox(t, sandbox.WithNetworkNamespace(true))
+	defer sb.Stop()
+	ctx := context.Background()
+
+	res, err := sb.Exec(ctx, []string{"sh", "-c", "echo ${RUNNER_SECRET:-unset}"}, sandbox.ExecOptions{})
+	if err != nil || strings.TrimSpace(res.Stdout) != "unset" {
+		t.Errorf("runner environment leaked into the sandbox: %+v, %v", res, err)
+	}
+
+	// Background processes die with the command that started them, even on timeout.
+	marker := filepath.Join(sb.Workdir(), "marker")
+	res, err = sb.Exec(ctx, []string{"sh", "-c", "(sleep 1; touch " + marker + ") & sleep 30"}, sandbox.ExecOptions{Timeout: 200 * time.Millisecond})
+	if err != nil || !res.TimedOut {
+		t.Fatalf("expected timeout, got %+v, %v", res, err)
+	}
+	time.Sleep(1500 * time.Millisecond)
+	if _, err := os.Stat(marker); err == nil {
+		t.Errorf("background process survived the timeout")
+	}
+
+	// Files cannot be copied outside the sandbox directory.
+	if err := sandbox.WriteFile(ctx, sb, filepath.Join(os.TempDir(), "escape.txt"), []byte("x"), 0o644); err == nil {
+		t.Errorf("expected writes outside the sandbox to fail")
+	}
+
+	// Memory is bounded by rlimits.
+	res, err = sb.Exec(ctx, []string{"sh", "-c", "ulimit -v"}, sandbox.ExecOptions{})
+	if err != nil || strings.TrimSpace(res.Stdout) != "524288" {
+		t.Errorf("expected a 512MB address space limit, got %+v, %v", res, err)
+	}
+
+	// Without network access only an unconfigured loopback interface exists.
+	if ifaces, err := os.ReadFile("/proc/net/dev"); err == nil && strings.Count(string(ifaces), ":") > 1 {
+		res, err = sb.Exec(ctx, []string{"cat", "/proc/net/dev"}, sandbox.ExecOptions{})
+		if err != nil || strings.Count(res.Stdout, ":") != 1 {
+			t.Errorf("expected an isolated network namespace, got %q, %v", res.Stdout, err)
+		}
+	}
+}
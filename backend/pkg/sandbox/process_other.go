@@ -0,0 +1,24 @@
+//go:build !linux
+
+package sandbox
+
+import (
+	"context"
+	"errors"
+)
+
+var errProcessUnsupported = errors.New("sandbox: process sandbox requires linux")
+
+// Exec is not supported on this platform.
+func (s *ProcessSandbox) Exec(ctx context.Context, cmd []string, opts ExecOptions) (ExecResult, error) {
+	return ExecResult{}, errProcessUnsupported
+}
+
+// OpenSession is not supported on this platform.
+func (s *ProcessSandbox) OpenSession(ctx context.Context, opts SessionOptions) (Session, error) {
+	return nil, errProcessUnsupported
+}
+
+func killGroup(pgid int) error {
+	return errProcessUnsupported
+}
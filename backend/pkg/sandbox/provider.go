@@ -0,0 +1,61 @@
+package sandbox
+
+import "context"
+
+// Factory builds an unstarted sandbox for a spec.
+type Factory func(spec Spec) (Sandbox, error)
+
+// DockerFactory creates Docker sandboxes.
+func DockerFactory(spec Spec) (Sandbox, error) {
+	return NewDockerSandbox(spec.Image, WithSpec(spec))
+}
+
+// Provider leases started sandboxes to tasks and takes them back once the task is done.
+type Provider interface {
+	// Acquire returns a started sandbox matching spec.
+	Acquire(ctx context.Context, spec Spec) (Sandbox, error)
+	// Release hands a sandbox back. The caller must not use it afterwards.
+	Release(sb Sandbox)
+}
+
+// Resetter is implemented by sandboxes that can be returned to a clean state for reuse.
+type Resetter interface {
+	Reset(ctx context.Context) error
+}
+
+// DirectProvider starts a fresh sandbox for every lease and stops it on release.
+type DirectProvider struct {
+	factory Factory
+}
+
+// NewDirectProvider creates a provider without pooling.
+func NewDirectProvider(factory Factory) *DirectProvider {
+	return &DirectProvider{factory: factory}
+}
+
+// Acquire creates and starts a sandbox.
+func (p *DirectProvider) Acquire(ctx context.Context, spec Spec) (Sandbox, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return startSandbox(p.factory, spec)
+}
+
+// Release stops the sandbox.
+func (p *DirectProvider) Release(sb Sandbox) {
+	if sb != nil {
+		sb.Stop()
+	}
+}
+
+func startSandbox(factory Factory, spec Spec) (Sandbox, error) {
+	sb, err := factory(spec)
+	if err != nil {
+		return nil, err
+	}
+	if err := sb.Start(); err != nil {
+		sb.Stop()
+		return nil, err
+	}
+	return sb, nil
+}
@@ -1,13 +1,29 @@
 package sandbox
 
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+// ErrNotFound is returned when a path does not exist inside the sandbox.
+var ErrNotFound = errors.New("sandbox: path not found")
+
 // Sandbox defines the interface for an isolated execution environment.
 type Sandbox interface {
 	// Start starts the sandbox.
 	Start() error
 	// Stop stops and cleans up the sandbox.
 	Stop() error
-	// Exec executes a command inside the sandbox and returns stdout, stderr, and error.
-	Exec(cmd []string) (string, string, error)
+	// Exec runs a command inside the sandbox. The error is reserved for infrastructure failures;
+	// exit codes, timeouts and truncated output are reported in the result.
+	Exec(ctx context.Context, cmd []string, opts ExecOptions) (ExecResult, error)
+	// CopyIn extracts a tar archive into the destination directory inside the sandbox.
+	CopyIn(dstDir string, archive io.Reader) error
+	// CopyOut returns a tar archive holding the file or directory at srcPath.
+	CopyOut(srcPath string) (io.ReadCloser, error)
+	// Workdir returns the writable working directory commands start in.
+	Workdir() string
 	// ID returns the sandbox identifier (e.g., container ID).
 	ID() string
 }
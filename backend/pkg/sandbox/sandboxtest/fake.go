@@ -0,0 +1,581 @@
+package sandboxtest
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/example/back-end-tcc/pkg/sandbox"
+)
+
+// Responder simulates a command. Returning a nil result and a nil error passes the command on to
+// the next responder and finally to the builtins.
+type Responder func(ctx context.Context, cmd []string, opts sandbox.ExecOptions) (*sandbox.ExecResult, error)
+
+// Call records one Exec invocation.
+type Call struct {
+	Cmd    []string
+	Opts   sandbox.ExecOptions
+	Result sandbox.ExecResult
+	Err    error
+}
+
+// Command renders the call the way Fake matches it: the script of `sh -c`, otherwise the argv joined by spaces.
+func (c Call) Command() string {
+	return commandString(c.Cmd)
+}
+
+type node struct {
+	dir  bool
+	data []byte
+	mode int64
+}
+
+// Fake is an in-memory sandbox.Sandbox for unit tests. Files live in a virtual filesystem shared by
+// CopyIn, CopyOut and the builtin commands (echo, cat, ls, pwd, mkdir, rm, true, false); anything
+// else is answered by responders or fails with exit code 127. Every Exec is recorded.
+type Fake struct {
+	mu         sync.Mutex
+	id         string
+	workdir    string
+	files      map[string]*node
+	responders []Responder
+	calls      []Call
+	started    bool
+	stopped    bool
+}
+
+var fakeSeq atomic.Int64
+
+// NewFake creates a fake sandbox with an empty workdir at sandbox.DefaultWorkdir.
+func NewFake() *Fake {
+	f := &Fake{
+		id:      fmt.Sprintf("fake-%d", fakeSeq.Add(1)),
+		workdir: sandbox.DefaultWorkdir,
+		files:   map[string]*node{"/": {dir: true, mode: 0o755}},
+	}
+	f.mkdirAll(f.workdir)
+	f.mkdirAll("/tmp")
+	return f
+}
+
+// Factory returns a sandbox.Factory producing fresh fakes and reporting each one to created, if not nil.
+func Factory(created func(*Fake)) sandbox.Factory {
+	return func(spec sandbox.Spec) (sandbox.Sandbox, error) {
+		f := NewFake()
+		if created != nil {
+			created(f)
+		}
+		return f, nil
+	}
+}
+
+// Handle registers a responder. Responders registered later are consulted first.
+func (f *Fake) Handle(r Responder) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.responders = append(f.responders, r)
+}
+
+// On makes every command matching command (see Call.Command) return res.
+func (f *Fake) On(command string, res sandbox.ExecResult) {
+	f.Handle(func(ctx context.Context, cmd []string, opts sandbox.ExecOptions) (*sandbox.ExecResult, error) {
+		if commandString(cmd) != command {
+			return nil, nil
+		}
+		return &res, nil
+	})
+}
+
+// Start marks the sandbox as started.
+func (f *Fake) Start() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.started = true
+	return nil
+}
+
+// Stop marks the sandbox as stopped.
+func (f *Fake) Stop() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.stopped = true
+	return nil
+}
+
+// Started reports whether Start was called.
+func (f *Fake) Started() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.started
+}
+
+// Stopped reports whether Stop was called.
+func (f *Fake) Stopped() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.stopped
+}
+
+// Reset empties the workdir and /tmp.
+func (f *Fake) Reset(ctx context.Context) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for p := range f.files {
+		if strings.HasPrefix(p, f.workdir+"/") || strings.HasPrefix(p, "/tmp/") {
+			delete(f.files, p)
+		}
+	}
+	return nil
+}
+
+// Workdir returns the virtual working directory.
+func (f *Fake) Workdir() string {
+	return f.workdir
+}
+
+// ID returns the fake identifier.
+func (f *Fake) ID() string {
+	return f.id
+}
+
+// Exec runs the command through the responders, then the builtins, and records the call.
+func (f *Fake) Exec(ctx context.Context, cmd []string, opts sandbox.ExecOptions) (sandbox.ExecResult, error) {
+	start := time.Now()
+	res, err := f.dispatch(ctx, cmd, opts)
+	res.Duration = time.Since(start)
+	if limit := opts.MaxOutputBytes; limit > 0 {
+		res.Stdout, res.StdoutTruncated = capOutput(res.Stdout, limit, res.StdoutTruncated)
+		res.Stderr, res.StderrTruncated = capOutput(res.Stderr, limit, res.StderrTruncated)
+	}
+
+	f.mu.Lock()
+	f.calls = append(f.calls, Call{Cmd: append([]string(nil), cmd...), Opts: opts, Result: res, Err: err})
+	f.mu.Unlock()
+	return res, err
+}
+
+func (f *Fake) dispatch(ctx context.Context, cmd []string, opts sandbox.ExecOptions) (sandbox.ExecResult, error) {
+	if err := ctx.Err(); err != nil {
+		res := sandbox.ExecResult{ExitCode: sandbox.TimedOutExitCode, TimedOut: true}
+		if errors.Is(err, context.Canceled) {
+			return res, err
+		}
+		return res, nil
+	}
+	f.mu.Lock()
+	responders := append([]Responder(nil), f.responders...)
+	f.mu.Unlock()
+	for i := len(responders) - 1; i >= 0; i-- {
+		res, err := responders[i](ctx, cmd, opts)
+		if res != nil || err != nil {
+			if res == nil {
+				return sandbox.ExecResult{}, err
+			}
+			return *res, err
+		}
+	}
+	return f.builtin(cmd), nil
+}
+
+// Calls returns the recorded Exec calls in order.
+func (f *Fake) Calls() []Call {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]Call(nil), f.calls...)
+}
+
+// Commands returns the recorded commands rendered as by Call.Command.
+func (f *Fake) Commands() []string {
+	calls := f.Calls()
+	commands := make([]string, len(calls))
+	for i, c := range calls {
+		commands[i] = c.Command()
+	}
+	return commands
+}
+
+// Executed reports whether a command matching command (see Call.Command) ran.
+func (f *Fake) Executed(command string) bool {
+	for _, c := range f.Commands() {
+		if c == command {
+			return true
+		}
+	}
+	return false
+}
+
+// AssertExecuted fails the test unless command ran.
+func (f *Fake) AssertExecuted(t testing.TB, command string) {
+	t.Helper()
+	if !f.Executed(command) {
+		t.Errorf("expected %q to be executed, got %q", command, f.Commands())
+	}
+}
+
+// AssertNotExecuted fails the test if command ran.
+func (f *Fake) AssertNotExecuted(t testing.TB, command string) {
+	t.Helper()
+	if f.Executed(command) {
+		t.Errorf("expected %q not to be executed", command)
+	}
+}
+
+// SetFile creates or replaces a file, creating parent directories. Relative paths resolve against the workdir.
+func (f *Fake) SetFile(name, content string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	p := f.abs(name)
+	f.mkdirAll(path.Dir(p))
+	f.files[p] = &node{data: []byte(content), mode: 0o644}
+}
+
+// File returns the content of a file and whether it exists.
+func (f *Fake) File(name string) (string, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	n, ok := f.files[f.abs(name)]
+	if !ok || n.dir {
+		return "", false
+	}
+	return string(n.data), true
+}
+
+// Exists reports whether a file or directory exists.
+func (f *Fake) Exists(name string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	_, ok := f.files[f.abs(name)]
+	return ok
+}
+
+// Files returns the paths of all regular files, sorted.
+func (f *Fake) Files() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var files []string
+	for p, n := range f.files {
+		if !n.dir {
+			files = append(files, p)
+		}
+	}
+	sort.Strings(files)
+	return files
+}
+
+// CopyIn extracts a tar archive into an existing directory of the virtual filesystem.
+func (f *Fake) CopyIn(dstDir string, archive io.Reader) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	dst := path.Clean(dstDir)
+	if n, ok := f.files[dst]; !ok || !n.dir {
+		return fmt.Errorf("%s: %w", dstDir, sandbox.ErrNotFound)
+	}
+	tr := tar.NewReader(archive)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read archive: %w", err)
+		}
+		p := path.Join(dst, header.Name)
+		switch header.Typeflag {
+		case tar.TypeDir:
+			f.mkdirAll(p)
+		case tar.TypeReg:
+			data, err := io.ReadAll(tr)
+			if err != nil {
+				return err
+			}
+			f.mkdirAll(path.Dir(p))
+			f.files[p] = &node{data: data, mode: header.Mode}
+		}
+	}
+}
+
+// CopyOut returns a tar archive of a file or directory; entry names start with its base name.
+func (f *Fake) CopyOut(srcPath string) (io.ReadCloser, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	src := path.Clean(srcPath)
+	if _, ok := f.files[src]; !ok {
+		return nil, fmt.Errorf("%s: %w", srcPath, sandbox.ErrNotFound)
+	}
+	var paths []string
+	for p := range f.files {
+		if p == src || strings.HasPrefix(p, strings.TrimSuffix(src, "/")+"/") {
+			paths = append(paths, p)
+		}
+	}
+	sort.Strings(paths)
+
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	parent := path.Dir(src)
+	for _, p := range paths {
+		n := f.files[p]
+		name := strings.TrimPrefix(strings.TrimPrefix(p, parent), "/")
+		header := &tar.Header{Name: name, Mode: n.mode, Size: int64(len(n.data)), Typeflag: tar.TypeReg}
+		if n.dir {
+			header.Name += "/"
+			header.Typeflag = tar.TypeDir
+			header.Size = 0
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			return nil, err
+		}
+		if !n.dir {
+			if _, err := tw.Write(n.data); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	return io.NopCloser(&buf), nil
+}
+
+// OpenSession opens a simulated shell. Every complete line written runs like Exec of `sh -c line`
+// and is recorded; `exit [code]` ends the session. Shell state such as cd or exported variables is
+// not simulated.
+func (f *Fake) OpenSession(ctx context.Context, opts sandbox.SessionOptions) (sandbox.Session, error) {
+	return &fakeSession{fake: f, env: opts.Env}, nil
+}
+
+type fakeSession struct {
+	fake     *Fake
+	env      []string
+	mu       sync.Mutex
+	pending  string
+	out      strings.Builder
+	exited   bool
+	exitCode int
+}
+
+func (s *fakeSession) Write(input string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.exited {
+		return sandbox.ErrSessionClosed
+	}
+	s.pending += input
+	for !s.exited {
+		line, rest, ok := strings.Cut(s.pending, "\n")
+		if !ok {
+			break
+		}
+		s.pending = rest
+		fields := strings.Fields(line)
+		switch {
+		case len(fields) == 0:
+		case fields[0] == "exit":
+			s.exited = true
+			if len(fields) > 1 {
+				fmt.Sscanf(fields[1], "%d", &s.exitCode)
+			}
+		default:
+			res, _ := s.fake.Exec(context.Background(), []string{"sh", "-c", line}, sandbox.ExecOptions{Env: s.env})
+			s.out.WriteString(res.Stdout + res.Stderr)
+		}
+	}
+	return nil
+}
+
+// Read returns the output of the lines run so far without waiting.
+func (s *fakeSession) Read(ctx context.Context, wait time.Duration) (sandbox.SessionOutput, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := sandbox.SessionOutput{Output: s.out.String(), Exited: s.exited, ExitCode: s.exitCode}
+	s.out.Reset()
+	return out, nil
+}
+
+func (s *fakeSession) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.exited = true
+	return nil
+}
+
+// builtin runs the small set of simulated commands. `sh -c` scripts are supported when they are a
+// single simple command without shell syntax.
+func (f *Fake) builtin(cmd []string) sandbox.ExecResult {
+	if len(cmd) == 3 && cmd[0] == "sh" && cmd[1] == "-c" {
+		if strings.ContainsAny(cmd[2], "|&;<>()$`\"'*?\\\n") {
+			return notFound(cmd[2])
+		}
+		cmd = strings.Fields(cmd[2])
+	}
+	if len(cmd) == 0 {
+		return sandbox.ExecResult{}
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	flags, args := splitFlags(cmd[1:])
+	var out, errOut strings.Builder
+	code := 0
+	switch cmd[0] {
+	case "true":
+	case "false":
+		code = 1
+	case "pwd":
+		out.WriteString(f.workdir + "\n")
+	case "echo":
+		if len(cmd) > 1 && cmd[1] == "-n" {
+			out.WriteString(strings.Join(cmd[2:], " "))
+		} else {
+			out.WriteString(strings.Join(cmd[1:], " ") + "\n")
+		}
+	case "cat":
+		for _, a := range args {
+			n, ok := f.files[f.abs(a)]
+			switch {
+			case !ok:
+				fmt.Fprintf(&errOut, "cat: %s: No such file or directory\n", a)
+				code = 1
+			case n.dir:
+				fmt.Fprintf(&errOut, "cat: %s: Is a directory\n", a)
+				code = 1
+			default:
+				out.Write(n.data)
+			}
+		}
+	case "ls":
+		if len(args) == 0 {
+			args = []string{"."}
+		}
+		for _, a := range args {
+			entries, ok := f.list(f.abs(a))
+			if !ok {
+				fmt.Fprintf(&errOut, "ls: cannot access '%s': No such file or directory\n", a)
+				code = 2
+				continue
+			}
+			for _, e := range entries {
+				out.WriteString(e + "\n")
+			}
+		}
+	case "mkdir":
+		for _, a := range args {
+			p := f.abs(a)
+			if _, ok := f.files[p]; ok && !strings.Contains(flags, "p") {
+				fmt.Fprintf(&errOut, "mkdir: cannot create directory '%s': File exists\n", a)
+				code = 1
+				continue
+			}
+			if parent, ok := f.files[path.Dir(p)]; (!ok || !parent.dir) && !strings.Contains(flags, "p") {
+				fmt.Fprintf(&errOut, "mkdir: cannot create directory '%s': No such file or directory\n", a)
+				code = 1
+				continue
+			}
+			f.mkdirAll(p)
+		}
+	case "rm":
+		for _, a := range args {
+			p := f.abs(a)
+			n, ok := f.files[p]
+			switch {
+			case !ok:
+				if !strings.Contains(flags, "f") {
+					fmt.Fprintf(&errOut, "rm: cannot remove '%s': No such file or directory\n", a)
+					code = 1
+				}
+			case n.dir && !strings.ContainsAny(flags, "rR"):
+				fmt.Fprintf(&errOut, "rm: cannot remove '%s': Is a directory\n", a)
+				code = 1
+			default:
+				for other := range f.files {
+					if other == p || strings.HasPrefix(other, p+"/") {
+						delete(f.files, other)
+					}
+				}
+			}
+		}
+	default:
+		return notFound(cmd[0])
+	}
+	return sandbox.ExecResult{ExitCode: code, Stdout: out.String(), Stderr: errOut.String()}
+}
+
+// list returns the entry names of a directory, or the path itself for a file.
+func (f *Fake) list(p string) ([]string, bool) {
+	n, ok := f.files[p]
+	if !ok {
+		return nil, false
+	}
+	if !n.dir {
+		return []string{path.Base(p)}, true
+	}
+	prefix := strings.TrimSuffix(p, "/") + "/"
+	var entries []string
+	for other := range f.files {
+		if rest, ok := strings.CutPrefix(other, prefix); ok && rest != "" && !strings.Contains(rest, "/") {
+			entries = append(entries, rest)
+		}
+	}
+	sort.Strings(entries)
+	return entries, true
+}
+
+func (f *Fake) abs(name string) string {
+	if path.IsAbs(name) {
+		return path.Clean(name)
+	}
+	return path.Join(f.workdir, name)
+}
+
+func (f *Fake) mkdirAll(p string) {
+	for ; p != "/"; p = path.Dir(p) {
+		if _, ok := f.files[p]; ok {
+			return
+		}
+		f.files[p] = &node{dir: true, mode: 0o755}
+	}
+}
+
+// splitFlags separates leading -flags from operands, honouring "--".
+func splitFlags(args []string) (string, []string) {
+	var flags strings.Builder
+	for i, a := range args {
+		if a == "--" {
+			return flags.String(), args[i+1:]
+		}
+		if !strings.HasPrefix(a, "-") || a == "-" {
+			return flags.String(), args[i:]
+		}
+		flags.WriteString(strings.TrimPrefix(a, "-"))
+	}
+	return flags.String(), nil
+}
+
+func notFound(name string) sandbox.ExecResult {
+	return sandbox.ExecResult{ExitCode: 127, Stderr: fmt.Sprintf("sh: 1: %s: not found\n", name)}
+}
+
+func commandString(cmd []string) string {
+	if len(cmd) == 3 && cmd[0] == "sh" && cmd[1] == "-c" {
+		return cmd[2]
+	}
+	return strings.Join(cmd, " ")
+}
+
+func capOutput(s string, limit int64, truncated bool) (string, bool) {
+	if int64(len(s)) > limit {
+		return s[:limit], true
+	}
+	return s, truncated
+}
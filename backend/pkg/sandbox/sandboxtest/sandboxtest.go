@@ -0,0 +1,270 @@
+// Package sandboxtest provides the conformance suite every sandbox.Sandbox implementation must pass
+// and an in-memory Fake for hermetic unit tests.
+package sandboxtest
+
+import (
+	"archive/tar"
+	"context"
+	"errors"
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/example/back-end-tcc/pkg/sandbox"
+)
+
+// Run executes the suite. newSandbox must return a started sandbox; the suite stops it.
+// Session tests are skipped for sandboxes that do not implement sandbox.SessionOpener.
+func Run(t *testing.T, newSandbox func(t *testing.T) sandbox.Sandbox) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, sb sandbox.Sandbox)
+	}{
+		{"ExecStdout", testExecStdout},
+		{"ExecExitCodeAndStderr", testExecExitCodeAndStderr},
+		{"ExecStartsInWorkdir", testExecStartsInWorkdir},
+		{"ExecEnv", testExecEnv},
+		{"ExecTimeout", testExecTimeout},
+		{"ExecCancelled", testExecCancelled},
+		{"ExecOutputCap", testExecOutputCap},
+		{"WriteAndReadFile", testWriteAndReadFile},
+		{"ReadFileNotFound", testReadFileNotFound},
+		{"CommandsSeeCopiedFiles", testCommandsSeeCopiedFiles},
+		{"CopyOutDirectory", testCopyOutDirectory},
+		{"SessionKeepsState", testSessionKeepsState},
+		{"SessionExit", testSessionExit},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			sb := newSandbox(t)
+			defer sb.Stop()
+			tc.fn(t, sb)
+		})
+	}
+}
+
+func exec(t *testing.T, sb sandbox.Sandbox, opts sandbox.ExecOptions, cmd ...string) sandbox.ExecResult {
+	t.Helper()
+	res, err := sb.Exec(context.Background(), cmd, opts)
+	if err != nil {
+		t.Fatalf("exec %v: %v", cmd, err)
+	}
+	return res
+}
+
+func testExecStdout(t *testing.T, sb sandbox.Sandbox) {
+	res := exec(t, sb, sandbox.ExecOptions{}, "echo", "hello")
+	if !res.Success() || res.Stdout != "hello\n" {
+		t.Fatalf("expected hello with exit 0, got %+v", res)
+	}
+}
+
+func testExecExitCodeAndStderr(t *testing.T, sb sandbox.Sandbox) {
+	res := exec(t, sb, sandbox.ExecOptions{}, "sh", "-c", "echo oops >&2; exit 3")
+	if res.ExitCode != 3 || res.Success() || strings.TrimSpace(res.Stderr) != "oops" || res.Stdout != "" {
+		t.Fatalf("expected exit 3 with stderr, got %+v", res)
+	}
+}
+
+func testExecStartsInWorkdir(t *testing.T, sb sandbox.Sandbox) {
+	res := exec(t, sb, sandbox.ExecOptions{}, "pwd")
+	if strings.TrimSpace(res.Stdout) != sb.Workdir() {
+		t.Fatalf("expected commands to start in %s, got %q", sb.Workdir(), res.Stdout)
+	}
+}
+
+func testExecEnv(t *testing.T, sb sandbox.Sandbox) {
+	res := exec(t, sb, sandbox.ExecOptions{Env: []string{"SANDBOX_TEST=42"}}, "sh", "-c", "echo $SANDBOX_TEST")
+	if strings.TrimSpace(res.Stdout) != "42" {
+		t.Fatalf("expected env var to be set, got %+v", res)
+	}
+}
+
+func testExecTimeout(t *testing.T, sb sandbox.Sandbox) {
+	res := exec(t, sb, sandbox.ExecOptions{Timeout: 300 * time.Millisecond}, "sh", "-c", "echo started; sleep 30")
+	if !res.TimedOut || res.ExitCode != sandbox.TimedOutExitCode || res.Success() {
+		t.Fatalf("expected a timed out result, got %+v", res)
+	}
+	if res.Duration > 10*time.Second {
+		t.Fatalf("command was not killed promptly: %s", res.Duration)
+	}
+	if !strings.Contains(res.Stdout, "started") {
+		t.Fatalf("expected output produced before the timeout, got %q", res.Stdout)
+	}
+	// The sandbox stays usable.
+	if res := exec(t, sb, sandbox.ExecOptions{}, "true"); !res.Success() {
+		t.Fatalf("sandbox unusable after timeout: %+v", res)
+	}
+}
+
+func testExecCancelled(t *testing.T, sb sandbox.Sandbox) {
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(200*time.Millisecond, cancel)
+	res, err := sb.Exec(ctx, []string{"sleep", "30"}, sandbox.ExecOptions{})
+	if !errors.Is(err, context.Canceled) || !res.TimedOut {
+		t.Fatalf("expected context.Canceled and a killed command, got %+v, %v", res, err)
+	}
+}
+
+func testExecOutputCap(t *testing.T, sb sandbox.Sandbox) {
+	res := exec(t, sb, sandbox.ExecOptions{MaxOutputBytes: 100}, "sh", "-c", "yes | head -c 10000; yes | head -c 50 >&2")
+	if len(res.Stdout) != 100 || !res.StdoutTruncated {
+		t.Fatalf("expected 100 bytes of truncated stdout, got %d (truncated=%v)", len(res.Stdout), res.StdoutTruncated)
+	}
+	if res.StderrTruncated {
+		t.Fatalf("stderr under the cap must not be truncated")
+	}
+}
+
+func testWriteAndReadFile(t *testing.T, sb sandbox.Sandbox) {
+	ctx := context.Background()
+	file := path.Join(sb.Workdir(), "nested", "dir", "hello.txt")
+	if err := sandbox.WriteFile(ctx, sb, file, []byte("hello world"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	data, truncated, err := sandbox.ReadFile(sb, file, 0)
+	if err != nil || truncated || string(data) != "hello world" {
+		t.Fatalf("read back %q (truncated=%v): %v", data, truncated, err)
+	}
+	data, truncated, err = sandbox.ReadFile(sb, file, 5)
+	if err != nil || !truncated || string(data) != "hello" {
+		t.Fatalf("limited read %q (truncated=%v): %v", data, truncated, err)
+	}
+}
+
+func testReadFileNotFound(t *testing.T, sb sandbox.Sandbox) {
+	_, _, err := sandbox.ReadFile(sb, path.Join(sb.Workdir(), "missing.txt"), 0)
+	if !errors.Is(err, sandbox.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func testCommandsSeeCopiedFiles(t *testing.T, sb sandbox.Sandbox) {
+	ctx := context.Background()
+	file := path.Join(sb.Workdir(), "notes.txt")
+	if err := sandbox.WriteFile(ctx, sb, file, []byte("one\n"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	// Files copied in must be owned by the user commands run as.
+	res := exec(t, sb, sandbox.ExecOptions{}, "sh", "-c", "echo two >> notes.txt && cat notes.txt")
+	if !res.Success() || res.Stdout != "one\ntwo\n" {
+		t.Fatalf("expected command to append to the copied file, got %+v", res)
+	}
+	data, _, err := sandbox.ReadFile(sb, file, 0)
+	if err != nil || string(data) != "one\ntwo\n" {
+		t.Fatalf("expected command output to be visible to CopyOut, got %q: %v", data, err)
+	}
+}
+
+func testCopyOutDirectory(t *testing.T, sb sandbox.Sandbox) {
+	res := exec(t, sb, sandbox.ExecOptions{}, "sh", "-c", "mkdir -p out/sub && echo a > out/a.txt && echo b > out/sub/b.txt")
+	if !res.Success() {
+		t.Fatalf("setup failed: %+v", res)
+	}
+	reader, err := sb.CopyOut(path.Join(sb.Workdir(), "out"))
+	if err != nil {
+		t.Fatalf("copy out: %v", err)
+	}
+	defer reader.Close()
+
+	var files []string
+	tr := tar.NewReader(reader)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("read archive: %v", err)
+		}
+		if header.Typeflag == tar.TypeReg {
+			files = append(files, header.Name)
+		}
+	}
+	sort.Strings(files)
+	if strings.Join(files, ",") != "out/a.txt,out/sub/b.txt" {
+		t.Fatalf("unexpected archive entries: %v", files)
+	}
+}
+
+func openSession(t *testing.T, sb sandbox.Sandbox) sandbox.Session {
+	t.Helper()
+	session, err := sandbox.OpenSession(context.Background(), sb, sandbox.SessionOptions{Env: []string{"SANDBOX_TEST=42"}})
+	if errors.Is(err, sandbox.ErrSessionsUnsupported) {
+		t.Skip("sandbox does not support sessions")
+	}
+	if err != nil {
+		t.Fatalf("open session: %v", err)
+	}
+	t.Cleanup(func() { session.Close() })
+	return session
+}
+
+// readUntil reads session output until it contains want or ten seconds passed.
+func readUntil(t *testing.T, session sandbox.Session, want string) sandbox.SessionOutput {
+	t.Helper()
+	var all sandbox.SessionOutput
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) && !strings.Contains(all.Output, want) && !all.Exited {
+		out, err := session.Read(context.Background(), time.Second)
+		if err != nil {
+			t.Fatalf("read: %v", err)
+		}
+		all.Output += out.Output
+		all.Exited, all.ExitCode = out.Exited, out.ExitCode
+	}
+	if !strings.Contains(all.Output, want) {
+		t.Fatalf("expected session output to contain %q, got %+v", want, all)
+	}
+	return all
+}
+
+func testSessionKeepsState(t *testing.T, sb sandbox.Sandbox) {
+	session := openSession(t, sb)
+	if err := session.Write("mkdir -p sub && cd sub\nexport GREETING=hi\n"); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if err := session.Write("echo $GREETING $SANDBOX_TEST $(pwd)\n"); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	readUntil(t, session, "hi 42 "+path.Join(sb.Workdir(), "sub"))
+
+	if err := session.Write("sleep 30 &\necho started\n"); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	readUntil(t, session, "started")
+	start := time.Now()
+	if err := session.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	if time.Since(start) > 5*time.Second {
+		t.Fatalf("close did not kill the shell promptly")
+	}
+}
+
+func testSessionExit(t *testing.T, sb sandbox.Sandbox) {
+	session := openSession(t, sb)
+	if err := session.Write("echo bye >&2; exit 3\n"); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	out := readUntil(t, session, "bye")
+	for !out.Exited {
+		next, err := session.Read(context.Background(), 5*time.Second)
+		if err != nil {
+			t.Fatalf("read: %v", err)
+		}
+		if !next.Exited && next.Output == "" {
+			t.Fatalf("shell did not exit")
+		}
+		out = next
+	}
+	if out.ExitCode != 3 {
+		t.Fatalf("expected exit code 3, got %d", out.ExitCode)
+	}
+	if err := session.Write("echo again\n"); !errors.Is(err, sandbox.ErrSessionClosed) {
+		t.Fatalf("expected ErrSessionClosed after exit, got %v", err)
+	}
+}
@@ -0,0 +1,189 @@
+package sandbox
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"time"
+)
+
+// DefaultSessionBufferBytes bounds the unread output a session keeps; older output is dropped first.
+const DefaultSessionBufferBytes = 1 << 20
+
+// sessionSettle is how long output must be quiet before Read returns what arrived so far.
+const sessionSettle = 150 * time.Millisecond
+
+// ErrSessionsUnsupported is returned by OpenSession for sandboxes without interactive shells.
+var ErrSessionsUnsupported = errors.New("sandbox does not support interactive sessions")
+
+// ErrSessionClosed is returned when writing to a session whose shell has exited.
+var ErrSessionClosed = errors.New("session closed")
+
+// SessionOptions configures an interactive shell.
+type SessionOptions struct {
+	Env []string
+	// MaxBufferBytes bounds unread output; 0 uses DefaultSessionBufferBytes.
+	MaxBufferBytes int
+}
+
+// SessionOutput is the output a session produced since the previous Read. Stdout and stderr are interleaved.
+type SessionOutput struct {
+	Output string
+	// Dropped counts bytes discarded because nobody read them before the buffer filled up.
+	Dropped  int64
+	Exited   bool
+	ExitCode int
+}
+
+// Session is an interactive shell running in a sandbox. Unlike Exec, the working directory,
+// exported variables and background jobs persist between inputs.
+type Session interface {
+	// Write sends input to the shell. Commands run once a newline is written.
+	Write(input string) error
+	// Read returns the output produced since the previous Read. It waits up to wait for output
+	// and returns as soon as output has been quiet for a moment or the shell exited.
+	Read(ctx context.Context, wait time.Duration) (SessionOutput, error)
+	// Close kills the shell and the processes it started.
+	Close() error
+}
+
+// SessionOpener is implemented by sandboxes that support interactive sessions.
+type SessionOpener interface {
+	OpenSession(ctx context.Context, opts SessionOptions) (Session, error)
+}
+
+// OpenSession opens an interactive shell in sb, which must implement SessionOpener.
+func OpenSession(ctx context.Context, sb Sandbox, opts SessionOptions) (Session, error) {
+	opener, ok := sb.(SessionOpener)
+	if !ok {
+		return nil, ErrSessionsUnsupported
+	}
+	return opener.OpenSession(ctx, opts)
+}
+
+func (s *envSandbox) OpenSession(ctx context.Context, opts SessionOptions) (Session, error) {
+	opts.Env = append(append([]string{}, s.env...), opts.Env...)
+	return OpenSession(ctx, s.Sandbox, opts)
+}
+
+// streamSession implements Session on top of a shell's stdin and an output buffer fed by the caller.
+type streamSession struct {
+	stdin io.WriteCloser
+	out   *sessionBuffer
+	kill  func() error
+	once  sync.Once
+	err   error
+}
+
+func newStreamSession(stdin io.WriteCloser, out *sessionBuffer, kill func() error) *streamSession {
+	return &streamSession{stdin: stdin, out: out, kill: kill}
+}
+
+func (s *streamSession) Write(input string) error {
+	if s.out.exited() {
+		return ErrSessionClosed
+	}
+	if _, err := io.WriteString(s.stdin, input); err != nil {
+		if s.out.exited() {
+			return ErrSessionClosed
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *streamSession) Read(ctx context.Context, wait time.Duration) (SessionOutput, error) {
+	return s.out.read(ctx, wait)
+}
+
+func (s *streamSession) Close() error {
+	s.once.Do(func() {
+		s.stdin.Close()
+		s.err = s.kill()
+	})
+	return s.err
+}
+
+// sessionBuffer collects shell output until it is read, keeping at most max bytes.
+type sessionBuffer struct {
+	mu       sync.Mutex
+	buf      []byte
+	max      int
+	dropped  int64
+	last     time.Time
+	done     bool
+	exitCode int
+	changed  chan struct{}
+}
+
+func newSessionBuffer(max int) *sessionBuffer {
+	if max <= 0 {
+		max = DefaultSessionBufferBytes
+	}
+	return &sessionBuffer{max: max, changed: make(chan struct{})}
+}
+
+func (b *sessionBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.buf = append(b.buf, p...)
+	if over := len(b.buf) - b.max; over > 0 {
+		b.buf = b.buf[over:]
+		b.dropped += int64(over)
+	}
+	b.last = time.Now()
+	b.notify()
+	return len(p), nil
+}
+
+// finish records the shell's exit once all of its output was written.
+func (b *sessionBuffer) finish(exitCode int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.done = true
+	b.exitCode = exitCode
+	b.notify()
+}
+
+func (b *sessionBuffer) exited() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.done
+}
+
+// notify wakes up readers; b.mu must be held.
+func (b *sessionBuffer) notify() {
+	close(b.changed)
+	b.changed = make(chan struct{})
+}
+
+func (b *sessionBuffer) read(ctx context.Context, wait time.Duration) (SessionOutput, error) {
+	deadline := time.Now().Add(wait)
+	for {
+		b.mu.Lock()
+		settled := len(b.buf) > 0 && time.Since(b.last) >= sessionSettle
+		if b.done || settled || !time.Now().Before(deadline) {
+			out := SessionOutput{Output: string(b.buf), Dropped: b.dropped, Exited: b.done, ExitCode: b.exitCode}
+			b.buf, b.dropped = nil, 0
+			b.mu.Unlock()
+			return out, nil
+		}
+		next := deadline
+		if len(b.buf) > 0 && b.last.Add(sessionSettle).Before(next) {
+			next = b.last.Add(sessionSettle)
+		}
+		changed := b.changed
+		b.mu.Unlock()
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-changed:
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return SessionOutput{}, ctx.Err()
+		}
+		timer.Stop()
+	}
+}
@@ -0,0 +1,113 @@
+package sandbox
+
+// Hardened defaults applied to every sandbox unless a benchmark overrides them.
+const (
+	DefaultImage     = "python:3.9-slim"
+	DefaultWorkdir   = "/workspace"
+	DefaultUser      = "1000:1000"
+	DefaultCPUs      = 1.0
+	DefaultMemoryMB  = 512
+	DefaultPidsLimit = 128
+	DefaultDiskMB    = 512
+)
+
+// Limits caps the resources available to a sandbox.
+type Limits struct {
+	CPUs      float64
+	MemoryMB  int64
+	PidsLimit int64
+	DiskMB    int64 // Size of the writable workdir
+}
+
+// Spec describes how a sandbox is provisioned. It only holds comparable fields so it can key sandbox caches.
+type Spec struct {
+	Image   string
+	Workdir string
+	User    string
+	Network bool
+	Limits  Limits
+}
+
+// Option customises a sandbox Spec.
+type Option func(*Spec)
+
+// DefaultSpec returns the hardened spec for an image: no network, non-root user and bounded resources.
+func DefaultSpec(image string) Spec {
+	if image == "" {
+		image = DefaultImage
+	}
+	return Spec{
+		Image:   image,
+		Workdir: DefaultWorkdir,
+		User:    DefaultUser,
+		Limits: Limits{
+			CPUs:      DefaultCPUs,
+			MemoryMB:  DefaultMemoryMB,
+			PidsLimit: DefaultPidsLimit,
+			DiskMB:    DefaultDiskMB,
+		},
+	}
+}
+
+// NewSpec applies options on top of the default spec for an image.
+func NewSpec(image string, opts ...Option) Spec {
+	spec := DefaultSpec(image)
+	for _, opt := range opts {
+		opt(&spec)
+	}
+	return spec
+}
+
+// WithLimits overrides resource limits. Zero fields keep the current value.
+func WithLimits(l Limits) Option {
+	return func(s *Spec) {
+		if l.CPUs > 0 {
+			s.Limits.CPUs = l.CPUs
+		}
+		if l.MemoryMB > 0 {
+			s.Limits.MemoryMB = l.MemoryMB
+		}
+		if l.PidsLimit > 0 {
+			s.Limits.PidsLimit = l.PidsLimit
+		}
+		if l.DiskMB > 0 {
+			s.Limits.DiskMB = l.DiskMB
+		}
+	}
+}
+
+// WithNetwork enables or disables network access.
+func WithNetwork(enabled bool) Option {
+	return func(s *Spec) {
+		s.Network = enabled
+	}
+}
+
+// WithUser sets the user (name, uid or uid:gid) commands run as.
+func WithUser(user string) Option {
+	return func(s *Spec) {
+		if user != "" {
+			s.User = user
+		}
+	}
+}
+
+// WithWorkdir sets the writable working directory.
+func WithWorkdir(dir string) Option {
+	return func(s *Spec) {
+		if dir != "" {
+			s.Workdir = dir
+		}
+	}
+}
+
+// WithSpec replaces the whole spec, keeping the image passed to the constructor when the spec has none.
+func WithSpec(spec Spec) Option {
+	return func(s *Spec) {
+		image := s.Image
+		*s = spec
+		if s.Image == "" {
+			s.Image = image
+		}
+	}
+}
@@ -3,7 +3,9 @@ package storage
 import (
 	"database/sql"
 	"encoding/json"
+	"fmt"
 	"log"
+	"strings"
 
 	_ "github.com/lib/pq"
 )
@@ -116,3 +118,86 @@ func (r *PostgresRepository[T]) List() []T {
 	log.Printf("DEBUG: Found %d items for collection %s", len(values), r.collection)
 	return values
 }
+
+// Query filters, sorts and pages the collection in the database, comparing the JSONB fields as
+// the types of the fields of T.
+func (r *PostgresRepository[T]) Query(q Query) ([]T, error) {
+	fields, err := queryFields[T](q)
+	if err != nil {
+		return nil, err
+	}
+	args := []any{r.collection}
+	arg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+	where := []string{"collection = $1"}
+	for _, cond := range q.Where {
+		where = append(where, fmt.Sprintf("%s %s %s", column(cond.Field, fields[cond.Field]), cond.Op, arg(cond.Value)))
+	}
+	if len(q.After) > 0 {
+		// (a > x) OR (a = x AND b > y) ..., flipping the comparison of descending fields.
+		var keyset []string
+		for i, order := range q.OrderBy {
+			var terms []string
+			for j := 0; j < i; j++ {
+				terms = append(terms, fmt.Sprintf("%s = %s", column(q.OrderBy[j].Field, fields[q.OrderBy[j].Field]), arg(q.After[j])))
+			}
+			op := ">"
+			if order.Desc {
+				op = "<"
+			}
+			terms = append(terms, fmt.Sprintf("%s %s %s", column(order.Field, fields[order.Field]), op, arg(q.After[i])))
+			keyset = append(keyset, "("+strings.Join(terms, " AND ")+")")
+		}
+		where = append(where, "("+strings.Join(keyset, " OR ")+")")
+	}
+	query := "SELECT data FROM items WHERE " + strings.Join(where, " AND ")
+	if len(q.OrderBy) > 0 {
+		orders := make([]string, len(q.OrderBy))
+		for i, order := range q.OrderBy {
+			orders[i] = column(order.Field, fields[order.Field])
+			if order.Desc {
+				orders[i] += " DESC"
+			}
+		}
+		query += " ORDER BY " + strings.Join(orders, ", ")
+	}
+	if q.Limit > 0 {
+		query += " LIMIT " + arg(q.Limit)
+	}
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var values []T
+	for rows.Next() {
+		var data []byte
+		if err := rows.Scan(&data); err != nil {
+			return nil, err
+		}
+		var v T
+		if err := json.Unmarshal(data, &v); err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+	}
+	return values, rows.Err()
+}
+
+// column returns the SQL expression of a JSONB field, cast to its type. Field names come from the
+// struct tags of T, never from user input.
+func column(name string, f field) string {
+	expr := "(data->>'" + name + "')"
+	switch f.kind {
+	case kindNumber:
+		return expr + "::numeric"
+	case kindBool:
+		return expr + "::boolean"
+	case kindTime:
+		return expr + "::timestamptz"
+	}
+	return expr
+}
@@ -0,0 +1,282 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrUnknownField is returned for queries on fields the stored type does not have.
+var ErrUnknownField = errors.New("unknown field")
+
+// Operator compares a field with a value.
+type Operator string
+
+// Operators.
+const (
+	Eq  Operator = "="
+	Lt  Operator = "<"
+	Lte Operator = "<="
+	Gt  Operator = ">"
+	Gte Operator = ">="
+)
+
+// Condition restricts a field, named by its JSON name, to values that compare with Value.
+type Condition struct {
+	Field string
+	Op    Operator
+	Value any
+}
+
+// Order sorts by a field, named by its JSON name.
+type Order struct {
+	Field string
+	Desc  bool
+}
+
+// Query selects stored values: those matching every condition, sorted by the orders, after the
+// keyset After (the values of the order fields of the last value of the previous page), at most
+// Limit of them when it is positive.
+type Query struct {
+	Where   []Condition
+	OrderBy []Order
+	After   []any
+	Limit   int
+}
+
+// Querier is implemented by repositories that filter, sort and page values in storage rather than
+// listing every value.
+type Querier[T any] interface {
+	Query(q Query) ([]T, error)
+}
+
+// Find runs q on repo, in storage when it is a Querier and over its List otherwise.
+func Find[T any](repo Repository[T], q Query) ([]T, error) {
+	if querier, ok := repo.(Querier[T]); ok {
+		return querier.Query(q)
+	}
+	return Apply(repo.List(), q)
+}
+
+// Query filters, sorts and pages the stored values.
+func (r *MemoryRepository[T]) Query(q Query) ([]T, error) {
+	return Apply(r.List(), q)
+}
+
+// Apply runs q over values in memory.
+func Apply[T any](values []T, q Query) ([]T, error) {
+	fields, err := queryFields[T](q)
+	if err != nil {
+		return nil, err
+	}
+	var matched []reflect.Value
+next:
+	for _, value := range values {
+		v := reflect.ValueOf(value)
+		for _, cond := range q.Where {
+			if !compare(fields[cond.Field].get(v), cond.Value, cond.Op) {
+				continue next
+			}
+		}
+		if len(q.After) > 0 && !after(fields, q, v) {
+			continue
+		}
+		matched = append(matched, v)
+	}
+	sort.SliceStable(matched, func(i, j int) bool {
+		for _, order := range q.OrderBy {
+			field := fields[order.Field]
+			a, b := field.get(matched[i]), field.get(matched[j])
+			if c := cmp(a, b); c != 0 {
+				return (c < 0) != order.Desc
+			}
+		}
+		return false
+	})
+	if q.Limit > 0 && len(matched) > q.Limit {
+		matched = matched[:q.Limit]
+	}
+	result := make([]T, len(matched))
+	for i, v := range matched {
+		result[i] = v.Interface().(T)
+	}
+	return result, nil
+}
+
+// after reports whether v sorts after the keyset of q.
+func after(fields map[string]field, q Query, v reflect.Value) bool {
+	for i, order := range q.OrderBy {
+		c := cmp(fields[order.Field].get(v), q.After[i])
+		if order.Desc {
+			c = -c
+		}
+		if c != 0 {
+			return c > 0
+		}
+	}
+	return false
+}
+
+func compare(a, b any, op Operator) bool {
+	c := cmp(a, b)
+	switch op {
+	case Eq:
+		return c == 0
+	case Lt:
+		return c < 0
+	case Lte:
+		return c <= 0
+	case Gt:
+		return c > 0
+	case Gte:
+		return c >= 0
+	}
+	return false
+}
+
+// cmp orders two values of a field: numbers numerically, times chronologically, false before true
+// and everything else by its string form.
+func cmp(a, b any) int {
+	switch x := a.(type) {
+	case float64:
+		if y, ok := number(b); ok {
+			switch {
+			case x < y:
+				return -1
+			case x > y:
+				return 1
+			}
+			return 0
+		}
+	case time.Time:
+		if y, ok := b.(time.Time); ok {
+			return x.Compare(y)
+		}
+	case bool:
+		if y, ok := b.(bool); ok {
+			switch {
+			case x == y:
+				return 0
+			case y:
+				return -1
+			}
+			return 1
+		}
+	}
+	return strings.Compare(fmt.Sprint(a), fmt.Sprint(b))
+}
+
+func number(v any) (float64, bool) {
+	r := reflect.ValueOf(v)
+	switch r.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(r.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(r.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return r.Float(), true
+	}
+	return 0, false
+}
+
+// kind is how storage compares a field.
+type kind int
+
+const (
+	kindText kind = iota
+	kindNumber
+	kindBool
+	kindTime
+)
+
+// field is a top-level struct field that can be queried.
+type field struct {
+	index []int
+	kind  kind
+}
+
+// get returns the field of v as a float64, bool, time.Time or string.
+func (f field) get(v reflect.Value) any {
+	fv := v.FieldByIndex(f.index)
+	switch f.kind {
+	case kindNumber:
+		n, _ := number(fv.Interface())
+		return n
+	case kindBool:
+		return fv.Bool()
+	case kindTime:
+		return fv.Interface().(time.Time)
+	}
+	return fmt.Sprint(fv.Interface())
+}
+
+var fieldCache sync.Map // reflect.Type -> map[string]field
+
+// fieldsOf maps the JSON names of the scalar fields of struct type T to their fields.
+func fieldsOf[T any]() map[string]field {
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	if cached, ok := fieldCache.Load(t); ok {
+		return cached.(map[string]field)
+	}
+	fields := map[string]field{}
+	if t.Kind() == reflect.Struct {
+		timeType := reflect.TypeOf(time.Time{})
+		for i := 0; i < t.NumField(); i++ {
+			sf := t.Field(i)
+			name, _, _ := strings.Cut(sf.Tag.Get("json"), ",")
+			if !sf.IsExported() || name == "-" {
+				continue
+			}
+			if name == "" {
+				name = sf.Name
+			}
+			f := field{index: sf.Index}
+			switch {
+			case sf.Type == timeType:
+				f.kind = kindTime
+			case sf.Type.Kind() == reflect.Bool:
+				f.kind = kindBool
+			case sf.Type.Kind() == reflect.String:
+				f.kind = kindText
+			default:
+				if _, ok := number(reflect.Zero(sf.Type).Interface()); !ok {
+					continue
+				}
+				f.kind = kindNumber
+			}
+			fields[name] = f
+		}
+	}
+	fieldCache.Store(t, fields)
+	return fields
+}
+
+// queryFields checks that q names fields of T and returns them.
+func queryFields[T any](q Query) (map[string]field, error) {
+	fields := fieldsOf[T]()
+	names := make([]string, 0, len(q.Where)+len(q.OrderBy))
+	for _, cond := range q.Where {
+		switch cond.Op {
+		case Eq, Lt, Lte, Gt, Gte:
+		default:
+			return nil, fmt.Errorf("unknown operator %q", cond.Op)
+		}
+		names = append(names, cond.Field)
+	}
+	for _, order := range q.OrderBy {
+		names = append(names, order.Field)
+	}
+	for _, name := range names {
+		if _, ok := fields[name]; !ok {
+			return nil, fmt.Errorf("%w %q", ErrUnknownField, name)
+		}
+	}
+	if len(q.After) > 0 && len(q.After) != len(q.OrderBy) {
+		return nil, errors.New("after must hold a value for each order field")
+	}
+	return fields, nil
+}
@@ -3,8 +3,13 @@ package service
 import (
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
+	"github.com/example/back-end-tcc/pkg/environment"
+	"github.com/example/back-end-tcc/pkg/formula"
+	"github.com/example/back-end-tcc/pkg/graders"
+	"github.com/example/back-end-tcc/pkg/guardrails"
 	"github.com/example/back-end-tcc/pkg/logger"
 	"github.com/example/back-end-tcc/pkg/models"
 	"github.com/example/back-end-tcc/pkg/observability/metrics"
@@ -56,6 +61,29 @@ func (s *Service) Create(b models.Benchmark) (models.Benchmark, error) {
 		s.observe("create", start, "error")
 		return models.Benchmark{}, errors.New("missing name")
 	}
+	if err := validateGuardrails(b); err != nil {
+		s.observe("create", start, "error")
+		return models.Benchmark{}, err
+	}
+	if err := validateSandbox(b.Sandbox); err != nil {
+		s.observe("create", start, "error")
+		return models.Benchmark{}, err
+	}
+	if err := validateEnvironments(b); err != nil {
+		s.observe("create", start, "error")
+		return models.Benchmark{}, err
+	}
+	if err := validateGraders(b); err != nil {
+		s.observe("create", start, "error")
+		return models.Benchmark{}, err
+	}
+	// Store the effective formula, so the benchmarks API shows how the score is composed.
+	scoreFormula, err := formula.Normalize(b.ScoreFormula)
+	if err != nil {
+		s.observe("create", start, "error")
+		return models.Benchmark{}, err
+	}
+	b.ScoreFormula = scoreFormula
 	b.CreatedAt = time.Now()
 	b.TasksCount = len(b.Tasks)
 
@@ -75,6 +103,52 @@ func (s *Service) List() []models.Benchmark {
 	return s.repo.List()
 }
 
+func validateGuardrails(b models.Benchmark) error {
+	if err := guardrails.Validate(b.Guardrails); err != nil {
+		return err
+	}
+	for _, task := range b.Tasks {
+		if err := guardrails.Validate(task.Guardrails); err != nil {
+			return fmt.Errorf("task %s: %w", task.ID, err)
+		}
+	}
+	return nil
+}
+
+func validateEnvironments(b models.Benchmark) error {
+	if err := environment.Validate(b.Environment); err != nil {
+		return fmt.Errorf("environment: %w", err)
+	}
+	for _, task := range b.Tasks {
+		if err := environment.Validate(task.Environment); err != nil {
+			return fmt.Errorf("task %s environment: %w", task.ID, err)
+		}
+	}
+	return nil
+}
+
+func validateGraders(b models.Benchmark) error {
+	for _, task := range b.Tasks {
+		if err := graders.Validate(task.Graders, task.PassThreshold); err != nil {
+			return fmt.Errorf("task %s: %w", task.ID, err)
+		}
+	}
+	return nil
+}
+
+func validateSandbox(settings *models.SandboxSettings) error {
+	if settings == nil {
+		return nil
+	}
+	if settings.CPUs < 0 || settings.MemoryMB < 0 || settings.PidsLimit < 0 || settings.DiskMB < 0 {
+		return errors.New("sandbox limits must not be negative")
+	}
+	if settings.Workdir != "" && !strings.HasPrefix(settings.Workdir, "/") {
+		return errors.New("sandbox workdir must be an absolute path")
+	}
+	return nil
+}
+
 func (s *Service) observe(operation string, start time.Time, result string) {
 	if s.metrics == nil {
 		return
@@ -1,8 +1,14 @@
 package handlers
 
 import (
+	"bytes"
+	"encoding/base64"
+	"errors"
 	"net/http"
+	"strconv"
+	"time"
 
+	"github.com/example/back-end-tcc/pkg/export"
 	pkghttp "github.com/example/back-end-tcc/pkg/http"
 	"github.com/example/back-end-tcc/services/leaderboard/service"
 )
@@ -17,7 +23,132 @@ func New(service *service.Service) *HTTP {
 	return &HTTP{service: service}
 }
 
-// List returns leaderboard entries.
+// List returns leaderboard entries filtered by ?benchmark_id= and ?domain=, ranked by ?sort= and
+// ?order=. With ?limit= the X-Next-Cursor header carries the ?cursor= of the next page, and
+// ?as_of= (RFC 3339) queries the leaderboard as it stood at that time.
 func (h *HTTP) List(w http.ResponseWriter, r *http.Request) {
-	pkghttp.JSON(w, http.StatusOK, h.service.Entries())
+	q, ok := parseQuery(w, r)
+	if !ok {
+		return
+	}
+	page, err := h.service.Query(q)
+	if err != nil {
+		pkghttp.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	w.Header().Set("X-Total-Count", strconv.Itoa(page.Total))
+	if page.Next > 0 {
+		w.Header().Set("X-Next-Cursor", encodeCursor(page.Next))
+	}
+	pkghttp.JSON(w, http.StatusOK, page.Entries)
+}
+
+// Export renders the entries List would return as ?format=csv, markdown or jsonl, with the
+// comma-separated ?columns= (see export.Leaderboard).
+func (h *HTTP) Export(w http.ResponseWriter, r *http.Request) {
+	q, ok := parseQuery(w, r)
+	if !ok {
+		return
+	}
+	format, err := export.ParseFormat(r.URL.Query().Get("format"))
+	if err != nil {
+		pkghttp.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	page, err := h.service.Query(q)
+	if err != nil {
+		pkghttp.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	var body bytes.Buffer
+	if err := export.WriteLeaderboard(&body, format, export.ParseColumns(r.URL.Query().Get("columns")), page.Entries); err != nil {
+		pkghttp.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	pkghttp.Attachment(w, format.ContentType(), "leaderboard."+format.Extension(), body.Bytes())
+}
+
+// parseQuery reads the leaderboard query parameters, answering 400 when one is invalid.
+func parseQuery(w http.ResponseWriter, r *http.Request) (service.Query, bool) {
+	query := r.URL.Query()
+	q := service.Query{
+		BenchmarkID: query.Get("benchmark_id"),
+		Domain:      query.Get("domain"),
+		Sort:        query.Get("sort"),
+		Order:       query.Get("order"),
+	}
+	if raw := query.Get("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit < 1 {
+			pkghttp.Error(w, http.StatusBadRequest, "limit must be a positive integer")
+			return q, false
+		}
+		q.Limit = limit
+	}
+	if raw := query.Get("as_of"); raw != "" {
+		asOf, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			pkghttp.Error(w, http.StatusBadRequest, "as_of must be an RFC 3339 timestamp")
+			return q, false
+		}
+		q.AsOf = asOf
+	}
+	if raw := query.Get("cursor"); raw != "" {
+		offset, err := decodeCursor(raw)
+		if err != nil {
+			pkghttp.Error(w, http.StatusBadRequest, "invalid cursor")
+			return q, false
+		}
+		q.Offset = offset
+	}
+	return q, true
+}
+
+// Overall returns the cross-benchmark ratings of every agent.
+func (h *HTTP) Overall(w http.ResponseWriter, r *http.Request) {
+	pkghttp.JSON(w, http.StatusOK, h.service.Ratings())
+}
+
+// AgentMetrics returns the leaderboard entries of the agent in the {id} path segment, optionally
+// limited to ?benchmark_id=.
+func (h *HTTP) AgentMetrics(w http.ResponseWriter, r *http.Request) {
+	metrics, err := h.service.AgentMetrics(r.PathValue("id"), r.URL.Query().Get("benchmark_id"))
+	if errors.Is(err, service.ErrAgentNotFound) {
+		pkghttp.Error(w, http.StatusNotFound, err.Error())
+		return
+	}
+	pkghttp.JSON(w, http.StatusOK, metrics)
+}
+
+// RankHistory returns how the rank of the agent in the {id} path segment moved, optionally on
+// ?benchmark_id= only.
+func (h *HTTP) RankHistory(w http.ResponseWriter, r *http.Request) {
+	pkghttp.JSON(w, http.StatusOK, h.service.RankHistory(r.PathValue("id"), r.URL.Query().Get("benchmark_id")))
+}
+
+// Cursors are opaque to clients; they encode the offset of the next page.
+func encodeCursor(offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.Itoa(offset)))
+}
+
+func decodeCursor(cursor string) (int, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, err
+	}
+	offset, err := strconv.Atoi(string(raw))
+	if err != nil || offset < 0 {
+		return 0, errors.New("invalid cursor")
+	}
+	return offset, nil
+}
+
+// Pareto returns the score/cost and score/latency Pareto frontiers of the ?benchmark_id= benchmark.
+func (h *HTTP) Pareto(w http.ResponseWriter, r *http.Request) {
+	benchmarkID := r.URL.Query().Get("benchmark_id")
+	if benchmarkID == "" {
+		pkghttp.Error(w, http.StatusBadRequest, "benchmark_id is required")
+		return
+	}
+	pkghttp.JSON(w, http.StatusOK, h.service.Pareto(benchmarkID))
 }
@@ -1,30 +1,144 @@
 package repository
 
 import (
+	"fmt"
 	"sort"
+	"time"
 
 	"github.com/example/back-end-tcc/pkg/models"
 	"github.com/example/back-end-tcc/pkg/storage"
 )
 
-// Repository stores leaderboard entries.
+// Option customises the repository.
+type Option func(*Repository)
+
+// WithSnapshots persists leaderboard snapshots. Without it they are kept in memory.
+func WithSnapshots(store storage.Repository[models.LeaderboardSnapshot]) Option {
+	return func(r *Repository) {
+		r.snapshots = store
+	}
+}
+
+// WithSummaries persists the score summaries entries are chosen from. Without it they are kept in memory.
+func WithSummaries(store storage.Repository[models.ScoreSummary]) Option {
+	return func(r *Repository) {
+		r.summaries = store
+	}
+}
+
+// Repository stores leaderboard entries, the score summaries they are chosen from and the
+// snapshots of their history.
 type Repository struct {
-	store storage.Repository[models.LeaderboardEntry]
+	store     storage.Repository[models.LeaderboardEntry]
+	summaries storage.Repository[models.ScoreSummary]
+	snapshots storage.Repository[models.LeaderboardSnapshot]
 }
 
 // New creates repository.
-func New(store storage.Repository[models.LeaderboardEntry]) *Repository {
-	return &Repository{store: store}
+func New(store storage.Repository[models.LeaderboardEntry], opts ...Option) *Repository {
+	r := &Repository{store: store}
+	for _, opt := range opts {
+		opt(r)
+	}
+	if r.summaries == nil {
+		r.summaries = storage.NewMemoryRepository[models.ScoreSummary]()
+	}
+	if r.snapshots == nil {
+		r.snapshots = storage.NewMemoryRepository[models.LeaderboardSnapshot]()
+	}
+	return r
 }
 
-// Save stores entry.
+// Save stores entry as the agent's entry on its benchmark.
 func (r *Repository) Save(entry models.LeaderboardEntry) {
-	r.store.Save(entry.SubmissionID, entry)
+	r.store.Save(key(entry.BenchmarkID, entry.AgentID), entry)
+}
+
+// Get returns the entry of an agent on a benchmark.
+func (r *Repository) Get(benchmarkID, agentID string) (models.LeaderboardEntry, bool) {
+	return r.store.Get(key(benchmarkID, agentID))
 }
 
-// List returns entries sorted by rank.
+// List returns entries sorted by benchmark and rank.
 func (r *Repository) List() []models.LeaderboardEntry {
 	entries := r.store.List()
-	sort.Slice(entries, func(i, j int) bool { return entries[i].Rank < entries[j].Rank })
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].BenchmarkID != entries[j].BenchmarkID {
+			return entries[i].BenchmarkID < entries[j].BenchmarkID
+		}
+		return entries[i].Rank < entries[j].Rank
+	})
+	return entries
+}
+
+// Find returns the entries of a benchmark sorted by rank.
+func (r *Repository) Find(benchmarkID string) []models.LeaderboardEntry {
+	var entries []models.LeaderboardEntry
+	for _, entry := range r.List() {
+		if entry.BenchmarkID == benchmarkID {
+			entries = append(entries, entry)
+		}
+	}
 	return entries
 }
+
+// SaveSummary stores a summary an agent's entry may be chosen from, replacing an earlier summary of
+// the same submission and scoring version.
+func (r *Repository) SaveSummary(summary models.ScoreSummary) {
+	r.summaries.Save(fmt.Sprintf("%s@v%d", summary.SubmissionID, summary.ScoringVersion), summary)
+}
+
+// Summaries returns the summaries of an agent on a benchmark, newest scoring version first and,
+// within a version, latest submission first.
+func (r *Repository) Summaries(benchmarkID, agentID string) ([]models.ScoreSummary, error) {
+	return storage.Find(r.summaries, storage.Query{
+		Where: []storage.Condition{
+			{Field: "benchmarkId", Op: storage.Eq, Value: benchmarkID},
+			{Field: "agentId", Op: storage.Eq, Value: agentID},
+		},
+		OrderBy: []storage.Order{{Field: "scoringVersion", Desc: true}, {Field: "submittedAt", Desc: true}, {Field: "submissionId"}},
+	})
+}
+
+// SaveSnapshot stores a snapshot of a benchmark's leaderboard.
+func (r *Repository) SaveSnapshot(snapshot models.LeaderboardSnapshot) {
+	r.snapshots.Save(fmt.Sprintf("%s@%d", snapshot.BenchmarkID, snapshot.Taken.UnixNano()), snapshot)
+}
+
+// Snapshots returns the snapshots of a benchmark, or of every benchmark for an empty ID, oldest first.
+func (r *Repository) Snapshots(benchmarkID string) []models.LeaderboardSnapshot {
+	var snapshots []models.LeaderboardSnapshot
+	for _, snapshot := range r.snapshots.List() {
+		if benchmarkID == "" || snapshot.BenchmarkID == benchmarkID {
+			snapshots = append(snapshots, snapshot)
+		}
+	}
+	sort.SliceStable(snapshots, func(i, j int) bool { return snapshots[i].Taken.Before(snapshots[j].Taken) })
+	return snapshots
+}
+
+// AsOf returns the entries of every benchmark as they stood at t, from the latest snapshot of each
+// benchmark taken at or before t.
+func (r *Repository) AsOf(t time.Time) []models.LeaderboardEntry {
+	latest := map[string]models.LeaderboardSnapshot{}
+	for _, snapshot := range r.Snapshots("") {
+		if !snapshot.Taken.After(t) {
+			latest[snapshot.BenchmarkID] = snapshot
+		}
+	}
+	var entries []models.LeaderboardEntry
+	for _, snapshot := range latest {
+		entries = append(entries, snapshot.Entries...)
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].BenchmarkID != entries[j].BenchmarkID {
+			return entries[i].BenchmarkID < entries[j].BenchmarkID
+		}
+		return entries[i].Rank < entries[j].Rank
+	})
+	return entries
+}
+
+func key(benchmarkID, agentID string) string {
+	return benchmarkID + "/" + agentID
+}
@@ -0,0 +1,48 @@
+package service
+
+import (
+	"sort"
+
+	"github.com/example/back-end-tcc/pkg/models"
+)
+
+// withEfficiency derives the efficiency metrics of an entry from its score, cost, latency and tokens.
+func withEfficiency(entry models.LeaderboardEntry) models.LeaderboardEntry {
+	entry.ScorePerDollar = ratio(entry.Score, entry.TotalCost)
+	entry.ScorePerSecond = ratio(entry.Score, entry.AvgLatency)
+	entry.TokensPerSuccess = ratio(float64(entry.TotalTokens), float64(entry.PassedTasks))
+	return entry
+}
+
+func ratio(a, b float64) *float64 {
+	if b <= 0 {
+		return nil
+	}
+	r := a / b
+	return &r
+}
+
+// pareto returns the entries no other entry dominates: none scores at least as high for at most
+// the cost while beating it on one of the two. They are ordered by increasing cost.
+func pareto(entries []models.LeaderboardEntry, cost func(models.LeaderboardEntry) float64) []models.LeaderboardEntry {
+	sorted := append([]models.LeaderboardEntry(nil), entries...)
+	// Cheapest first, and the best score first among equal costs, so a single sweep keeps every
+	// entry scoring above all cheaper ones.
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if ci, cj := cost(sorted[i]), cost(sorted[j]); ci != cj {
+			return ci < cj
+		}
+		return sorted[i].Score > sorted[j].Score
+	})
+	frontier := []models.LeaderboardEntry{}
+	for _, entry := range sorted {
+		if len(frontier) > 0 {
+			last := frontier[len(frontier)-1]
+			if entry.Score < last.Score || (entry.Score == last.Score && cost(entry) > cost(last)) {
+				continue
+			}
+		}
+		frontier = append(frontier, entry)
+	}
+	return frontier
+}
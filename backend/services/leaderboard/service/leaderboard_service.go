@@ -3,15 +3,30 @@ package service
 import (
 	"context"
 	"sort"
+	"sync"
 	"time"
 
 	"github.com/example/back-end-tcc/pkg/logger"
 	"github.com/example/back-end-tcc/pkg/models"
 	"github.com/example/back-end-tcc/pkg/observability/metrics"
 	"github.com/example/back-end-tcc/pkg/queue"
+	agentrepo "github.com/example/back-end-tcc/services/agent/repository"
+	benchrepo "github.com/example/back-end-tcc/services/benchmark/repository"
 	lbrepo "github.com/example/back-end-tcc/services/leaderboard/repository"
 )
 
+// LeaderboardUpdated queue message, carrying the models.ScoreSummary of a submission.
+const LeaderboardUpdated = "leaderboard.updated"
+
+// Selection decides which submission represents an agent on a benchmark.
+type Selection string
+
+// Selections.
+const (
+	SelectBest   Selection = "best"   // Highest score; the latest submitted wins ties
+	SelectLatest Selection = "latest" // Most recently submitted
+)
+
 // Option customises the leaderboard service.
 type Option func(*Service)
 
@@ -29,17 +44,53 @@ func WithMetrics(rec metrics.Recorder) Option {
 	}
 }
 
+// WithSelection chooses between each agent's best and latest submission. The default is SelectBest.
+func WithSelection(selection Selection) Option {
+	return func(s *Service) {
+		s.selection = selection
+	}
+}
+
+// WithAgents lets entries show agent names.
+func WithAgents(repo *agentrepo.AgentRepository) Option {
+	return func(s *Service) {
+		s.agents = repo
+	}
+}
+
+// WithBenchmarks lets entries show, and be filtered by, their benchmark's domain.
+func WithBenchmarks(repo *benchrepo.BenchmarkRepository) Option {
+	return func(s *Service) {
+		s.benchmarks = repo
+	}
+}
+
+// WithScoringVersion pins the leaderboard to summaries computed with one scoring config version,
+// so rescoring under newer versions leaves it unchanged. 0 accepts every version.
+func WithScoringVersion(version int) Option {
+	return func(s *Service) {
+		s.scoringVersion = version
+	}
+}
+
 // Service updates leaderboard projections.
 type Service struct {
-	repo    *lbrepo.Repository
-	sub     queue.Subscriber
-	log     logger.Logger
-	metrics metrics.Recorder
+	repo           *lbrepo.Repository
+	sub            queue.Subscriber
+	log            logger.Logger
+	metrics        metrics.Recorder
+	agents         *agentrepo.AgentRepository
+	benchmarks     *benchrepo.BenchmarkRepository
+	selection      Selection
+	scoringVersion int
+	ratings        *ratings
+	snapshotted    time.Time // Time of the last snapshot
+	mu             sync.Mutex
 }
 
 // New creates service.
 func New(repo *lbrepo.Repository, sub queue.Subscriber, opts ...Option) *Service {
-	svc := &Service{repo: repo, sub: sub, log: logger.New()}
+	svc := &Service{repo: repo, sub: sub, log: logger.New(), selection: SelectBest, ratings: newRatings()}
 	for _, opt := range opts {
 		opt(svc)
 	}
@@ -48,7 +99,7 @@ func New(repo *lbrepo.Repository, sub queue.Subscriber, opts ...Option) *Service
 
 // Start listens for score events.
 func (s *Service) Start() {
-	s.sub.Subscribe("leaderboard.updated", s.handleScore)
+	s.sub.Subscribe(LeaderboardUpdated, s.handleScore)
 	if s.log != nil {
 		s.log.Println("leaderboard: subscribed to leaderboard.updated")
 	}
@@ -57,35 +108,206 @@ func (s *Service) Start() {
 func (s *Service) handleScore(ctx context.Context, msg queue.Message) error {
 	start := time.Now()
 	summary, ok := msg.Data.(models.ScoreSummary)
-	if !ok {
+	if !ok || summary.SubmissionID == "" || summary.AgentID == "" || summary.BenchmarkID == "" ||
+		(s.scoringVersion != 0 && summary.ScoringVersion != s.scoringVersion) {
 		s.observe("ignored", start, 0)
 		return nil
 	}
-	entry := models.LeaderboardEntry{
-		SubmissionID: summary.Calculated.Format("20060102150405"),
-		BenchmarkID:  "default",
-		AgentID:      "agent",
-		Score:        summary.Score,
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.repo.SaveSummary(summary)
+	summaries, err := s.repo.Summaries(summary.BenchmarkID, summary.AgentID)
+	if err != nil {
+		s.observe("error", start, 0)
+		return err
 	}
-	entries := append(s.repo.List(), entry)
-	sort.Slice(entries, func(i, j int) bool { return entries[i].Score > entries[j].Score })
-	for idx := range entries {
-		entries[idx].Rank = idx + 1
-		s.repo.Save(entries[idx])
+	entry := s.entry(s.choose(summaries))
+	current, ok := s.repo.Get(entry.BenchmarkID, entry.AgentID)
+	if ok && entry.SubmissionID == current.SubmissionID && entry.ScoringVersion == current.ScoringVersion && entry.ScoredAt.Equal(current.ScoredAt) {
+		s.observe("kept", start, summary.Score)
+		return nil
+	}
+	if !s.ratings.loaded {
+		s.ratings.load(s.repo.List())
+	}
+	var old *models.LeaderboardEntry
+	if ok {
+		old = &current
 	}
+	s.ratings.replace(old, entry, s.repo.Find(entry.BenchmarkID))
+	s.repo.Save(entry)
+	s.rank(entry.BenchmarkID)
+	s.snapshot(entry.BenchmarkID)
+	s.ratings.fit(s.repo.List())
 	if s.log != nil {
-		s.log.Printf("leaderboard: updated with score %.2f", summary.Score)
+		s.log.Printf("leaderboard: agent %s scored %.2f on benchmark %s with submission %s", entry.AgentID, entry.Score, entry.BenchmarkID, entry.SubmissionID)
 	}
 	s.observe("ok", start, summary.Score)
 	return nil
 }
 
-// Entries returns leaderboard entries.
+// entry projects a score summary.
+func (s *Service) entry(summary models.ScoreSummary) models.LeaderboardEntry {
+	entry := models.LeaderboardEntry{
+		SubmissionID:    summary.SubmissionID,
+		BenchmarkID:     summary.BenchmarkID,
+		AgentID:         summary.AgentID,
+		Score:           summary.Score,
+		SuccessRate:     summary.SuccessRate,
+		ToolCorrectness: summary.ToolCorrectness,
+		Violations:      summary.Violations,
+		AvgTurns:        summary.AvgTurns,
+		TotalCost:       summary.TotalCost,
+		AvgLatency:      summary.AvgLatency,
+		TotalTokens:     summary.TotalTokens,
+		ScoredAt:        summary.Calculated,
+		SubmittedAt:     summary.SubmittedAt,
+		ScoringVersion:  summary.ScoringVersion,
+	}
+	for _, task := range summary.Tasks {
+		if task.Status == "passed" {
+			entry.PassedTasks++
+		}
+		if entry.TaskScores == nil {
+			entry.TaskScores = map[string]float64{}
+		}
+		entry.TaskScores[task.TaskID] = task.Score
+	}
+	if s.agents != nil {
+		if agent, ok := s.agents.Get(summary.AgentID); ok {
+			entry.AgentName = agent.Name
+		}
+	}
+	if s.benchmarks != nil {
+		if benchmark, ok := s.benchmarks.Get(summary.BenchmarkID); ok {
+			entry.Domain = benchmark.Domain
+		}
+	}
+	return entry
+}
+
+// choose picks the summary that represents an agent from its summaries on a benchmark, ordered as
+// Repository.Summaries returns them. Only the newest scoring version is considered, so scores
+// computed under different configs are never compared.
+func (s *Service) choose(summaries []models.ScoreSummary) models.ScoreSummary {
+	chosen := summaries[0]
+	if s.selection == SelectLatest {
+		return chosen
+	}
+	for _, summary := range summaries[1:] {
+		if summary.ScoringVersion != chosen.ScoringVersion {
+			break
+		}
+		if summary.Score > chosen.Score {
+			chosen = summary
+		}
+	}
+	return chosen
+}
+
+// rank renumbers the entries of one benchmark by score, breaking ties by lower cost and then by
+// the earlier submission.
+func (s *Service) rank(benchmarkID string) {
+	entries := s.repo.Find(benchmarkID)
+	sort.SliceStable(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if a.TotalCost != b.TotalCost {
+			return a.TotalCost < b.TotalCost
+		}
+		return a.SubmittedAt.Before(b.SubmittedAt)
+	})
+	for idx := range entries {
+		if entries[idx].Rank != idx+1 {
+			entries[idx].Rank = idx + 1
+			s.repo.Save(entries[idx])
+		}
+	}
+}
+
+// Entries returns leaderboard entries with their efficiency metrics.
 func (s *Service) Entries() []models.LeaderboardEntry {
+	entries := s.repo.List()
+	for i := range entries {
+		entries[i] = withEfficiency(entries[i])
+	}
 	if s.metrics != nil {
-		s.metrics.AddCounter("leaderboard_entries_total", map[string]string{"result": "ok"}, float64(len(s.repo.List())))
+		s.metrics.AddCounter("leaderboard_entries_total", map[string]string{"result": "ok"}, float64(len(entries)))
+	}
+	return entries
+}
+
+// snapshot records the current entries of a benchmark. Task scores are left out to keep snapshots
+// small, and snapshot times strictly increase so no two changes share one.
+func (s *Service) snapshot(benchmarkID string) {
+	entries := s.repo.Find(benchmarkID)
+	for i := range entries {
+		entries[i].TaskScores = nil
+	}
+	taken := time.Now()
+	if !taken.After(s.snapshotted) {
+		taken = s.snapshotted.Add(time.Nanosecond)
+	}
+	s.snapshotted = taken
+	s.repo.SaveSnapshot(models.LeaderboardSnapshot{BenchmarkID: benchmarkID, Taken: taken, Entries: entries})
+}
+
+// RankHistory returns how an agent's rank moved on each benchmark, or on one benchmark, oldest
+// first. Only snapshots that changed the agent's rank, score or submission are listed.
+func (s *Service) RankHistory(agentID, benchmarkID string) []models.RankChange {
+	history := []models.RankChange{}
+	last := map[string]models.RankChange{}
+	for _, snapshot := range s.repo.Snapshots(benchmarkID) {
+		for _, entry := range snapshot.Entries {
+			if entry.AgentID != agentID {
+				continue
+			}
+			change := models.RankChange{
+				BenchmarkID:  snapshot.BenchmarkID,
+				At:           snapshot.Taken,
+				Rank:         entry.Rank,
+				Agents:       len(snapshot.Entries),
+				Score:        entry.Score,
+				SubmissionID: entry.SubmissionID,
+			}
+			if prev, ok := last[snapshot.BenchmarkID]; ok && prev.Rank == change.Rank && prev.Score == change.Score && prev.SubmissionID == change.SubmissionID {
+				continue
+			}
+			last[snapshot.BenchmarkID] = change
+			history = append(history, change)
+		}
+	}
+	return history
+}
+
+// Ratings returns the overall ratings of every agent on the leaderboard, best first.
+func (s *Service) Ratings() []models.AgentRating {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.ratings.loaded {
+		s.ratings.load(s.repo.List())
+	}
+	if !s.ratings.fitted {
+		s.ratings.fit(s.repo.List())
+	}
+	return s.ratings.list()
+}
+
+// Pareto returns the Pareto frontiers of a benchmark's entries on score against cost and against latency.
+func (s *Service) Pareto(benchmarkID string) models.ParetoFrontier {
+	var entries []models.LeaderboardEntry
+	for _, entry := range s.Entries() {
+		if entry.BenchmarkID == benchmarkID {
+			entries = append(entries, entry)
+		}
+	}
+	return models.ParetoFrontier{
+		BenchmarkID: benchmarkID,
+		Cost:        pareto(entries, func(e models.LeaderboardEntry) float64 { return e.TotalCost }),
+		Latency:     pareto(entries, func(e models.LeaderboardEntry) float64 { return e.AvgLatency }),
 	}
-	return s.repo.List()
 }
 
 func (s *Service) observe(result string, start time.Time, score float64) {
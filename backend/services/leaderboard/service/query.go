@@ -0,0 +1,139 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/example/back-end-tcc/pkg/models"
+)
+
+// ErrInvalidQuery is returned for unknown sort keys or orders and negative limits or offsets.
+var ErrInvalidQuery = errors.New("invalid leaderboard query")
+
+// ErrAgentNotFound is returned for metrics of unknown agents.
+var ErrAgentNotFound = errors.New("agent not found")
+
+// Sort keys of a leaderboard query.
+const (
+	SortScore       = "score"
+	SortCost        = "cost"
+	SortLatency     = "latency"
+	SortSuccessRate = "successRate"
+)
+
+// sortKeys maps each sort key to its value and to whether lower values are better.
+var sortKeys = map[string]struct {
+	value       func(models.LeaderboardEntry) float64
+	lowerIsBest bool
+}{
+	SortScore:       {func(e models.LeaderboardEntry) float64 { return e.Score }, false},
+	SortCost:        {func(e models.LeaderboardEntry) float64 { return e.TotalCost }, true},
+	SortLatency:     {func(e models.LeaderboardEntry) float64 { return e.AvgLatency }, true},
+	SortSuccessRate: {func(e models.LeaderboardEntry) float64 { return e.SuccessRate }, false},
+}
+
+// Query filters, orders and pages leaderboard entries. Empty filters match any entry.
+type Query struct {
+	BenchmarkID string
+	Domain      string
+	Sort        string // score (default), cost, latency or successRate
+	Order       string // asc or desc; by default the best values come first
+	Limit       int    // 0 returns every entry
+	Offset      int
+	AsOf        time.Time // Queries the snapshots in force at this time instead of the current entries
+}
+
+// Page is one page of a leaderboard query. Next is the offset of the following page, or 0 on the last one.
+type Page struct {
+	Entries []models.LeaderboardEntry
+	Total   int
+	Next    int
+}
+
+// Query returns the entries matching q, ranked by its sort within each benchmark of the filtered set.
+func (s *Service) Query(q Query) (Page, error) {
+	if q.Sort == "" {
+		q.Sort = SortScore
+	}
+	key, ok := sortKeys[q.Sort]
+	if !ok {
+		return Page{}, fmt.Errorf("%w: unknown sort %q", ErrInvalidQuery, q.Sort)
+	}
+	ascending := key.lowerIsBest
+	switch q.Order {
+	case "":
+	case "asc":
+		ascending = true
+	case "desc":
+		ascending = false
+	default:
+		return Page{}, fmt.Errorf("%w: unknown order %q", ErrInvalidQuery, q.Order)
+	}
+	if q.Limit < 0 || q.Offset < 0 {
+		return Page{}, fmt.Errorf("%w: limit and cursor must not be negative", ErrInvalidQuery)
+	}
+
+	source := s.Entries()
+	if !q.AsOf.IsZero() {
+		source = s.repo.AsOf(q.AsOf)
+		for i := range source {
+			source[i] = withEfficiency(source[i])
+		}
+	}
+	entries := []models.LeaderboardEntry{}
+	for _, entry := range source {
+		if (q.BenchmarkID == "" || entry.BenchmarkID == q.BenchmarkID) && (q.Domain == "" || entry.Domain == q.Domain) {
+			entries = append(entries, entry)
+		}
+	}
+	// Ties fall back to the default ranking: higher score, lower cost, earlier submission.
+	sort.SliceStable(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if va, vb := key.value(a), key.value(b); va != vb {
+			return (va < vb) == ascending
+		}
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if a.TotalCost != b.TotalCost {
+			return a.TotalCost < b.TotalCost
+		}
+		return a.SubmittedAt.Before(b.SubmittedAt)
+	})
+	ranks := map[string]int{}
+	for i := range entries {
+		ranks[entries[i].BenchmarkID]++
+		entries[i].Rank = ranks[entries[i].BenchmarkID]
+	}
+
+	page := Page{Total: len(entries)}
+	start := min(q.Offset, len(entries))
+	end := len(entries)
+	if q.Limit > 0 && start+q.Limit < end {
+		end = start + q.Limit
+		page.Next = end
+	}
+	page.Entries = entries[start:end]
+	return page, nil
+}
+
+// AgentMetrics returns an agent's entries, one per benchmark, optionally limited to one benchmark.
+// Ranks are those within each benchmark.
+func (s *Service) AgentMetrics(agentID, benchmarkID string) (models.AgentMetrics, error) {
+	metrics := models.AgentMetrics{AgentID: agentID, Benchmarks: []models.LeaderboardEntry{}}
+	if s.agents != nil {
+		agent, ok := s.agents.Get(agentID)
+		if !ok {
+			return models.AgentMetrics{}, fmt.Errorf("%w: %s", ErrAgentNotFound, agentID)
+		}
+		metrics.AgentName = agent.Name
+	}
+	for _, entry := range s.Entries() {
+		if entry.AgentID == agentID && (benchmarkID == "" || entry.BenchmarkID == benchmarkID) {
+			metrics.Benchmarks = append(metrics.Benchmarks, entry)
+		}
+	}
+	return metrics, nil
+}
@@ -0,0 +1,215 @@
+package service
+
+import (
+	"math"
+	"sort"
+
+	"github.com/example/back-end-tcc/pkg/models"
+)
+
+// Ratings are Bradley-Terry strengths reported on the Elo scale: a 400 point gap means 10:1 odds.
+const (
+	ratingBase  = 1500
+	ratingScale = 400 / math.Ln10
+	// Every agent plays one virtual tie against an average opponent, which keeps the ratings of
+	// unbeaten or winless agents finite and anchors the scale.
+	ratingPrior = 1
+	// z of the 95% rating intervals.
+	ratingZ = 1.959964
+)
+
+// pair identifies two agents, A < B.
+type pair struct{ a, b string }
+
+// outcomes counts the task comparisons of a pair; ties count half a win for each side.
+type outcomes struct {
+	winsA, winsB float64
+	wins, losses int // For A
+	ties         int
+}
+
+// ratings fits Bradley-Terry ratings from pairwise task outcomes. Outcomes are updated
+// incrementally as entries change, and each fit warm-starts from the previous strengths.
+type ratings struct {
+	pairs    map[pair]*outcomes
+	strength map[string]float64 // Log-strengths
+	agents   map[string]models.AgentRating
+	loaded   bool
+	fitted   bool
+}
+
+func newRatings() *ratings {
+	return &ratings{pairs: map[pair]*outcomes{}, strength: map[string]float64{}, agents: map[string]models.AgentRating{}}
+}
+
+// compare adds (sign 1) or removes (sign -1) the outcomes of the tasks two entries share.
+func (r *ratings) compare(x, y models.LeaderboardEntry, sign int) {
+	if x.AgentID == y.AgentID {
+		return
+	}
+	if x.AgentID > y.AgentID {
+		x, y = y, x
+	}
+	key := pair{x.AgentID, y.AgentID}
+	o := r.pairs[key]
+	if o == nil {
+		o = &outcomes{}
+		r.pairs[key] = o
+	}
+	for taskID, scoreX := range x.TaskScores {
+		scoreY, ok := y.TaskScores[taskID]
+		if !ok {
+			continue
+		}
+		switch {
+		case math.Abs(scoreX-scoreY) < 1e-9:
+			o.winsA += 0.5 * float64(sign)
+			o.winsB += 0.5 * float64(sign)
+			o.ties += sign
+		case scoreX > scoreY:
+			o.winsA += float64(sign)
+			o.wins += sign
+		default:
+			o.winsB += float64(sign)
+			o.losses += sign
+		}
+	}
+	if o.wins == 0 && o.losses == 0 && o.ties == 0 {
+		delete(r.pairs, key)
+	}
+}
+
+// replace swaps an agent's entry on a benchmark, updating the outcomes against the other entries
+// of the benchmark. old is nil for a new entry.
+func (r *ratings) replace(old *models.LeaderboardEntry, entry models.LeaderboardEntry, others []models.LeaderboardEntry) {
+	for _, other := range others {
+		if other.AgentID == entry.AgentID {
+			continue
+		}
+		if old != nil {
+			r.compare(*old, other, -1)
+		}
+		r.compare(entry, other, 1)
+	}
+}
+
+// load rebuilds the outcomes from every stored entry.
+func (r *ratings) load(entries []models.LeaderboardEntry) {
+	byBenchmark := map[string][]models.LeaderboardEntry{}
+	for _, entry := range entries {
+		byBenchmark[entry.BenchmarkID] = append(byBenchmark[entry.BenchmarkID], entry)
+	}
+	for _, group := range byBenchmark {
+		for i := range group {
+			for j := i + 1; j < len(group); j++ {
+				r.compare(group[i], group[j], 1)
+			}
+		}
+	}
+	r.loaded = true
+}
+
+// fit runs the minorization-maximization iterations of Hunter (2004) until the strengths settle,
+// then derives each agent's rating, standard error and record. entries supplies the agents.
+func (r *ratings) fit(entries []models.LeaderboardEntry) {
+	agents := map[string]*models.AgentRating{}
+	for _, entry := range entries {
+		agent := agents[entry.AgentID]
+		if agent == nil {
+			agent = &models.AgentRating{AgentID: entry.AgentID}
+			agents[entry.AgentID] = agent
+		}
+		if entry.AgentName != "" {
+			agent.AgentName = entry.AgentName
+		}
+		agent.Benchmarks++
+	}
+	for id := range r.strength {
+		if agents[id] == nil {
+			delete(r.strength, id)
+		}
+	}
+
+	p := map[string]float64{}
+	for id := range agents {
+		p[id] = math.Exp(r.strength[id])
+	}
+	for iter := 0; iter < 1000; iter++ {
+		wins := map[string]float64{}
+		denom := map[string]float64{}
+		for id := range agents {
+			wins[id] = 0.5 * ratingPrior
+			denom[id] = ratingPrior / (p[id] + 1)
+		}
+		for key, o := range r.pairs {
+			if agents[key.a] == nil || agents[key.b] == nil {
+				continue
+			}
+			n := o.winsA + o.winsB
+			wins[key.a] += o.winsA
+			wins[key.b] += o.winsB
+			denom[key.a] += n / (p[key.a] + p[key.b])
+			denom[key.b] += n / (p[key.a] + p[key.b])
+		}
+		change := 0.0
+		for id := range agents {
+			next := wins[id] / denom[id]
+			change = max(change, math.Abs(math.Log(next)-math.Log(p[id])))
+			p[id] = next
+		}
+		if change < 1e-9 {
+			break
+		}
+	}
+
+	info := map[string]float64{}
+	for id := range agents {
+		info[id] = ratingPrior * p[id] / ((p[id] + 1) * (p[id] + 1))
+	}
+	for key, o := range r.pairs {
+		if agents[key.a] == nil || agents[key.b] == nil {
+			continue
+		}
+		n := o.winsA + o.winsB
+		v := n * p[key.a] * p[key.b] / ((p[key.a] + p[key.b]) * (p[key.a] + p[key.b]))
+		info[key.a] += v
+		info[key.b] += v
+		a, b := agents[key.a], agents[key.b]
+		a.Wins += o.wins
+		a.Losses += o.losses
+		a.Ties += o.ties
+		b.Wins += o.losses
+		b.Losses += o.wins
+		b.Ties += o.ties
+	}
+
+	r.agents = map[string]models.AgentRating{}
+	for id, agent := range agents {
+		r.strength[id] = math.Log(p[id])
+		agent.Rating = ratingBase + ratingScale*r.strength[id]
+		// Standard error from the diagonal of the Fisher information, ignoring covariances.
+		agent.StdErr = ratingScale / math.Sqrt(info[id])
+		agent.Interval = models.Interval{Lower: agent.Rating - ratingZ*agent.StdErr, Upper: agent.Rating + ratingZ*agent.StdErr, Level: 0.95}
+		agent.Comparisons = agent.Wins + agent.Losses + agent.Ties
+		r.agents[id] = *agent
+	}
+	r.fitted = true
+}
+
+// list returns the fitted ratings, best first.
+func (r *ratings) list() []models.AgentRating {
+	list := make([]models.AgentRating, 0, len(r.agents))
+	for _, agent := range r.agents {
+		list = append(list, agent)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Rating != list[j].Rating {
+			return list[i].Rating > list[j].Rating
+		}
+		return list[i].AgentID < list[j].AgentID
+	})
+	for i := range list {
+		list[i].Rank = i + 1
+	}
+	return list
+}
@@ -1,8 +1,12 @@
 package handlers
 
 import (
+	"errors"
+	"io"
 	"net/http"
+	"strconv"
 
+	"github.com/example/back-end-tcc/pkg/artifacts"
 	pkghttp "github.com/example/back-end-tcc/pkg/http"
 	"github.com/example/back-end-tcc/services/runner/service"
 )
@@ -21,3 +25,40 @@ func New(service *service.Service) *HTTP {
 func (h *HTTP) Results(w http.ResponseWriter, r *http.Request) {
 	pkghttp.JSON(w, http.StatusOK, h.service.Results())
 }
+
+// SandboxPool returns the occupancy and counters of the sandbox pool.
+func (h *HTTP) SandboxPool(w http.ResponseWriter, r *http.Request) {
+	stats, ok := h.service.PoolStats()
+	if !ok {
+		pkghttp.Error(w, http.StatusNotFound, "sandbox pool disabled")
+		return
+	}
+	pkghttp.JSON(w, http.StatusOK, stats)
+}
+
+// Artifacts lists the artifacts of a submission (?submission_id=) or downloads one (?id=).
+func (h *HTTP) Artifacts(w http.ResponseWriter, r *http.Request) {
+	if id := r.URL.Query().Get("id"); id != "" {
+		meta, content, err := h.service.OpenArtifact(id)
+		if errors.Is(err, artifacts.ErrNotFound) {
+			pkghttp.Error(w, http.StatusNotFound, "artifact not found")
+			return
+		}
+		if err != nil {
+			pkghttp.Error(w, http.StatusInternalServerError, "failed to open artifact")
+			return
+		}
+		defer content.Close()
+		w.Header().Set("Content-Type", meta.ContentType)
+		w.Header().Set("Content-Length", strconv.FormatInt(meta.Size, 10))
+		w.Header().Set("Content-Disposition", `attachment; filename="`+meta.Name+`"`)
+		_, _ = io.Copy(w, content)
+		return
+	}
+	submissionID := r.URL.Query().Get("submission_id")
+	if submissionID == "" {
+		pkghttp.Error(w, http.StatusBadRequest, "submission_id or id is required")
+		return
+	}
+	pkghttp.JSON(w, http.StatusOK, h.service.Artifacts(submissionID))
+}
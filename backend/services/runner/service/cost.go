@@ -0,0 +1,58 @@
+package service
+
+import "strings"
+
+// modelPrice is the price in USD per million prompt and completion tokens.
+type modelPrice struct {
+	prompt     float64
+	completion float64
+}
+
+// modelPrices lists list prices of common models, matched by the longest model name prefix.
+// Unknown models cost nothing unless the provider reports the cost itself.
+var modelPrices = map[string]modelPrice{
+	"gpt-4":         {prompt: 30, completion: 60},
+	"gpt-4-turbo":   {prompt: 10, completion: 30},
+	"gpt-4o":        {prompt: 2.5, completion: 10},
+	"gpt-4o-mini":   {prompt: 0.15, completion: 0.6},
+	"gpt-3.5-turbo": {prompt: 0.5, completion: 1.5},
+}
+
+// usage is the token usage a chat completion response reports.
+type usage struct {
+	PromptTokens     int
+	CompletionTokens int
+	// Cost is set by providers that report the charged amount, e.g. OpenRouter.
+	Cost *float64
+}
+
+// parseUsage reads the usage object of a chat completion response.
+func parseUsage(response map[string]interface{}) usage {
+	raw, _ := response["usage"].(map[string]interface{})
+	var u usage
+	if n, ok := raw["prompt_tokens"].(float64); ok {
+		u.PromptTokens = int(n)
+	}
+	if n, ok := raw["completion_tokens"].(float64); ok {
+		u.CompletionTokens = int(n)
+	}
+	if c, ok := raw["cost"].(float64); ok {
+		u.Cost = &c
+	}
+	return u
+}
+
+// cost returns the reported cost, or prices the tokens with the model's list price.
+func (u usage) cost(model string) float64 {
+	if u.Cost != nil {
+		return *u.Cost
+	}
+	var price modelPrice
+	matched := ""
+	for name, p := range modelPrices {
+		if strings.HasPrefix(model, name) && len(name) > len(matched) {
+			price, matched = p, name
+		}
+	}
+	return (float64(u.PromptTokens)*price.prompt + float64(u.CompletionTokens)*price.completion) / 1e6
+}
@@ -4,12 +4,18 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
+	"strconv"
 	"time"
 
+	"github.com/example/back-end-tcc/pkg/artifacts"
+	"github.com/example/back-end-tcc/pkg/environment"
+	"github.com/example/back-end-tcc/pkg/graders"
+	"github.com/example/back-end-tcc/pkg/guardrails"
 	"github.com/example/back-end-tcc/pkg/logger"
 	"github.com/example/back-end-tcc/pkg/models"
 	"github.com/example/back-end-tcc/pkg/observability/metrics"
@@ -17,11 +23,28 @@ import (
 	"github.com/example/back-end-tcc/pkg/sandbox"
 	agentrepo "github.com/example/back-end-tcc/services/agent/repository"
 	benchrepo "github.com/example/back-end-tcc/services/benchmark/repository"
-	runnerrepo "github.com/example/back-end-tcc/services/runner/repository"
 	"github.com/example/back-end-tcc/services/runner/patterns"
+	runnerrepo "github.com/example/back-end-tcc/services/runner/repository"
 	"github.com/example/back-end-tcc/services/runner/tools"
 )
 
+// Tool call outcomes recorded on "tool" trace events.
+const (
+	toolStatusOK               = "ok"
+	toolStatusError            = "error"
+	toolStatusInvalidArguments = "invalid_arguments"
+)
+
+// Task outcomes recorded on task results.
+const (
+	taskStatusPassed = "passed"
+	taskStatusFailed = "failed"
+	taskStatusError  = "error"
+)
+
+// errTaskAborted marks a task stopped by a guardrail with the abort action.
+var errTaskAborted = errors.New("task aborted by guardrail")
+
 // Option allows customizing service dependencies.
 type Option func(*Service)
 
@@ -39,6 +62,36 @@ func WithMetrics(rec metrics.Recorder) Option {
 	}
 }
 
+// WithSandboxProvider sets where task sandboxes come from, e.g. a warm sandbox.Pool.
+// By default every task starts a fresh Docker container.
+func WithSandboxProvider(p sandbox.Provider) Option {
+	return func(s *Service) {
+		s.sandboxes = p
+	}
+}
+
+// WithFixturesDir sets the host directory environment fixture sources are resolved against.
+func WithFixturesDir(dir string) Option {
+	return func(s *Service) {
+		s.preparer = environment.NewPreparer(dir)
+	}
+}
+
+// WithArtifactStore sets where workdir diffs and artifact archives of finished tasks are stored.
+// By default they are kept in memory.
+func WithArtifactStore(store artifacts.Store) Option {
+	return func(s *Service) {
+		s.artifacts = store
+	}
+}
+
+// WithJudge sets the model that grades llm_judge graders. By default the OpenAI API is called.
+func WithJudge(judge graders.Judge) Option {
+	return func(s *Service) {
+		s.judge = judge
+	}
+}
+
 // Service consumes submissions and produces results.
 type Service struct {
 	repo          *runnerrepo.ResultRepository
@@ -48,6 +101,10 @@ type Service struct {
 	publisher     queue.Publisher
 	log           logger.Logger
 	metrics       metrics.Recorder
+	sandboxes     sandbox.Provider
+	preparer      *environment.Preparer
+	artifacts     artifacts.Store
+	judge         graders.Judge
 }
 
 // New creates service.
@@ -59,6 +116,9 @@ func New(repo *runnerrepo.ResultRepository, agentRepo *agentrepo.AgentRepository
 		subscriber:    subscriber,
 		publisher:     publisher,
 		log:           logger.New(),
+		sandboxes:     sandbox.NewDirectProvider(sandbox.DockerFactory),
+		preparer:      environment.NewPreparer(""),
+		artifacts:     artifacts.NewMemoryStore(),
 	}
 	for _, opt := range opts {
 		opt(s)
@@ -98,33 +158,126 @@ func (s *Service) handleSubmission(ctx context.Context, msg queue.Message) error
 	}
 
 	// Execute Tasks
-	totalScore := 0.0
-	tasksCount := len(benchmark.Tasks)
-	if tasksCount == 0 {
+	tasks := benchmark.Tasks
+	if len(tasks) == 0 {
 		// Fallback if no tasks defined in benchmark
-		tasksCount = 1
+		tasks = []models.Task{{ID: "default", Prompt: "Hello, are you working?"}}
+	}
+
+	totals := &taskRun{}
+	passed := 0
+	for _, task := range tasks {
+		result, run := s.runTask(ctx, submission.ID, &agent, &benchmark, task)
+		submission.TaskResults = append(submission.TaskResults, result)
+		if result.Status == taskStatusPassed {
+			passed++
+		}
+		totals.toolCalls += run.toolCalls
+		totals.argumentErrors += run.argumentErrors
+		totals.guardrailViolations += run.guardrailViolations
 	}
 
-	// For simplicity, we'll execute the first task or a generic prompt if no tasks
-	prompt := "Hello, are you working?"
-	if len(benchmark.Tasks) > 0 {
-		prompt = benchmark.Tasks[0].Prompt
+	submission.Status = "failed"
+	if passed > 0 {
+		submission.Status = "completed"
+	}
+	totalScore := float64(passed) / float64(len(tasks))
+
+	now := time.Now()
+	submission.CompletedAt = &now
+	submission.ScoreSummary = &models.ScoreSummary{
+		Score:       totalScore,
+		SuccessRate: totalScore,
+		Violations:  totals.argumentErrors + totals.guardrailViolations,
+		Metrics: map[string]float64{
+			"accuracy":          totalScore,
+			"argument_accuracy": totals.argumentAccuracy(),
+		},
+		Calculated: now,
+	}
+
+	// Important: Save to the shared repository
+	s.repo.Save(submission)
+
+	if err := s.publisher.Publish(ctx, queue.Message{Type: "score.calculated", Data: submission}); err != nil {
+		if s.log != nil {
+			s.log.Printf("runner: failed to publish score for submission %s: %v", submission.ID, err)
+		}
+		s.observeRun(start, "error")
+		return err
+	}
+	if s.log != nil {
+		s.log.Printf("runner: completed submission %s", submission.ID)
+	}
+	s.observeRun(start, "ok")
+	return nil
+}
+
+// runTask runs one task in its own sandbox: the environment is prepared, the agent works through
+// the plan/execute/reflect loop, the workdir changes are captured, then verify and teardown commands run.
+func (s *Service) runTask(ctx context.Context, submissionID string, agent *models.User, benchmark *models.Benchmark, task models.Task) (result models.TaskResult, run *taskRun) {
+	run = &taskRun{submissionID: submissionID, taskID: task.ID}
+	result = models.TaskResult{TaskID: task.ID, Status: taskStatusError}
+	fail := func(err error) (models.TaskResult, *taskRun) {
+		s.log.Printf("runner: task %s of submission %s failed: %v", task.ID, submissionID, err)
+		result.Error = err.Error()
+		result.ToolCalls = run.toolCalls
+		result.Violations = run.argumentErrors + run.guardrailViolations
+		result.ArgumentAccuracy = run.argumentAccuracy()
+		return result, run
+	}
+
+	var err error
+	run.guard, err = guardrails.New(benchmark.Guardrails, task.Guardrails)
+	if err != nil {
+		return fail(fmt.Errorf("invalid guardrails: %w", err))
+	}
+
+	env := environment.Merge(benchmark.Environment, task.Environment)
+	image := env.Image
+	if image == "" {
+		image = sandbox.DefaultImage
 	}
 
 	// Initialize Sandbox
-	sb, err := sandbox.NewDockerSandbox("python:3.9-slim")
+	spec := sandbox.NewSpec(image, sandboxOptions(benchmark.Sandbox)...)
+	leased, err := s.sandboxes.Acquire(ctx, spec)
 	if err != nil {
-		s.log.Printf("runner: failed to create sandbox: %v", err)
-		return err
+		return fail(fmt.Errorf("failed to acquire sandbox: %w", err))
 	}
-	if err := sb.Start(); err != nil {
-		s.log.Printf("runner: failed to start sandbox: %v", err)
-		return err
+	defer s.sandboxes.Release(leased)
+	sb := sandbox.WithEnv(leased, env.Env)
+	run.tools = tools.NewExecutor(sb)
+	defer run.tools.Close()
+
+	defer func() {
+		// Teardown must run even when the submission context was cancelled.
+		s.traceSteps(run, environment.Teardown(context.WithoutCancel(ctx), sb, env))
+	}()
+	steps, err := s.preparer.Prepare(ctx, sb, env)
+	s.traceSteps(run, steps)
+	if err != nil {
+		return fail(fmt.Errorf("environment setup failed: %w", err))
 	}
-	defer sb.Stop()
+
+	// Changes are measured against the prepared workdir and captured before verify commands add their own.
+	baseline, err := artifacts.TakeSnapshot(sb, sb.Workdir())
+	if err != nil {
+		s.log.Printf("runner: failed to snapshot workdir for task %s: %v", task.ID, err)
+	}
+	captured := false
+	capture := func() {
+		if !captured {
+			captured = true
+			s.captureArtifacts(run, &result, sb, env, baseline)
+		}
+	}
+	defer capture()
+
+	prompt := task.Prompt
 
 	// 1. Plan
-	plan, err := patterns.GeneratePlan(&agent, prompt)
+	plan, err := patterns.GeneratePlan(agent, prompt)
 	if err != nil {
 		s.log.Printf("runner: planning failed: %v", err)
 		// Fallback to no plan? Or fail? Let's log and continue without plan for now, or fail.
@@ -133,11 +286,12 @@ func (s *Service) handleSubmission(ctx context.Context, msg queue.Message) error
 		s.log.Printf("runner: generated plan: %s", plan)
 		// Inject plan into prompt
 		prompt = fmt.Sprintf("Goal: %s\n\nPlan:\n%s\n\nExecute the plan using available tools.", prompt, plan)
-		
+
 		// Log Plan Trace
 		s.repo.SaveTrace(models.TraceEvent{
 			ID:           fmt.Sprintf("trace-%d", time.Now().UnixNano()),
-			SubmissionID: submission.ID,
+			SubmissionID: submissionID,
+			TaskID:       task.ID,
 			Type:         "plan",
 			Message:      plan,
 			Timestamp:    time.Now(),
@@ -148,27 +302,25 @@ func (s *Service) handleSubmission(ctx context.Context, msg queue.Message) error
 	// 2. Execute & Reflect Loop
 	maxRetries := 3
 	var response string
-	
 	for i := 0; i < maxRetries; i++ {
-		response, err = s.callOpenAI(&agent, prompt, sb)
+		response, err = s.callOpenAI(ctx, agent, prompt, sb, run)
 		if err != nil {
-			s.log.Printf("runner: execution failed: %v", err)
-			submission.Status = "failed"
-			break
+			return fail(fmt.Errorf("execution failed: %w", err))
 		}
 
 		// 3. Reflect
-		approved, feedback, err := patterns.Reflect(&agent, benchmark.Tasks[0].Prompt, response)
+		approved, feedback, err := patterns.Reflect(agent, task.Prompt, response)
 		if err != nil {
 			s.log.Printf("runner: reflection failed: %v", err)
 			// If reflection fails, assume success or break?
 			break
 		}
-		
+
 		// Log Reflection Trace
 		s.repo.SaveTrace(models.TraceEvent{
 			ID:           fmt.Sprintf("trace-%d-reflect-%d", time.Now().UnixNano(), i),
-			SubmissionID: submission.ID,
+			SubmissionID: submissionID,
+			TaskID:       task.ID,
 			Type:         "reflection",
 			Message:      fmt.Sprintf("Approved: %v\nFeedback: %s", approved, feedback),
 			Timestamp:    time.Now(),
@@ -178,8 +330,8 @@ func (s *Service) handleSubmission(ctx context.Context, msg queue.Message) error
 
 		if approved {
 			s.log.Printf("runner: result approved")
-			submission.Status = "completed"
-			totalScore = 1.0
+			s.log.Printf("runner: final response: %s", response)
+			result.Approved = true
 			break
 		}
 
@@ -187,42 +339,189 @@ func (s *Service) handleSubmission(ctx context.Context, msg queue.Message) error
 		prompt = fmt.Sprintf("Previous attempt failed.\nFeedback: %s\n\nTry again.", feedback)
 		// Continue loop
 	}
-	
-	if submission.Status == "" {
-		submission.Status = "failed" // If loop finishes without approval
-	} else if submission.Status == "completed" {
-		// Log final response
-		s.log.Printf("runner: final response: %s", response)
+
+	// 4. Verify: when the task declares checks or graders they decide the outcome instead of the reflection.
+	capture()
+	success := result.Approved
+	if len(env.Verify) > 0 {
+		steps, verified := environment.Verify(ctx, sb, env)
+		s.traceSteps(run, steps)
+		result.Verified = &verified
+		success = verified
+	}
+	score := 0.0
+	if success {
+		score = 1
+	}
+	if len(task.Graders) > 0 {
+		outcome := graders.Evaluate(ctx, task.Graders, task.PassThreshold, graders.Input{
+			Prompt:    task.Prompt,
+			Response:  response,
+			ToolCalls: run.calls,
+			Sandbox:   sb,
+			Judge:     s.judge,
+		})
+		s.traceGrades(run, outcome.Grades)
+		result.Grades = outcome.Grades
+		// Verify commands stay hard requirements; graders add partial credit.
+		verified := result.Verified == nil || *result.Verified
+		success = verified && outcome.Passed
+		score = 0
+		if verified {
+			score = outcome.Score
+		}
 	}
 
-	now := time.Now()
-	submission.CompletedAt = &now
-	submission.ScoreSummary = &models.ScoreSummary{
-		Score:      totalScore,
-		Metrics:    map[string]float64{"accuracy": totalScore},
-		Calculated: now,
+	result.Status = taskStatusFailed
+	if success {
+		result.Status = taskStatusPassed
 	}
+	result.Score = score
+	result.ToolCalls = run.toolCalls
+	result.Violations = run.argumentErrors + run.guardrailViolations
+	result.ArgumentAccuracy = run.argumentAccuracy()
+	return result, run
+}
 
-	// Important: Save to the shared repository
-	s.repo.Save(submission)
+// captureArtifacts stores the workdir diff against baseline and an archive of the environment's
+// artifact paths, and links them from the task result. Failures are logged and never fail the task.
+func (s *Service) captureArtifacts(run *taskRun, result *models.TaskResult, sb sandbox.Sandbox, env models.Environment, baseline *artifacts.Snapshot) {
+	if baseline != nil {
+		current, err := artifacts.TakeSnapshot(sb, sb.Workdir())
+		if err != nil {
+			s.log.Printf("runner: failed to snapshot workdir for task %s: %v", run.taskID, err)
+		} else {
+			changes, patch := artifacts.Diff(baseline, current)
+			result.Changes = &changes
+			if patch != "" {
+				s.storeArtifact(run, result, models.Artifact{Kind: artifacts.KindDiff, Name: "workdir.diff", ContentType: "text/x-diff"}, []byte(patch))
+			}
+		}
+	}
+	if len(env.Artifacts) == 0 {
+		return
+	}
+	archive, missing, err := artifacts.Archive(sb, sb.Workdir(), env.Artifacts)
+	if len(missing) > 0 {
+		s.log.Printf("runner: artifact paths of task %s not found: %v", run.taskID, missing)
+	}
+	if err != nil {
+		s.log.Printf("runner: failed to archive artifacts of task %s: %v", run.taskID, err)
+		s.observeArtifact(artifacts.KindArchive, "error")
+		return
+	}
+	s.storeArtifact(run, result, models.Artifact{Kind: artifacts.KindArchive, Name: "artifacts.tar", ContentType: "application/x-tar"}, archive)
+}
 
-	if err := s.publisher.Publish(ctx, queue.Message{Type: "score.calculated", Data: submission}); err != nil {
-		if s.log != nil {
-			s.log.Printf("runner: failed to publish score for submission %s: %v", submission.ID, err)
+func (s *Service) storeArtifact(run *taskRun, result *models.TaskResult, meta models.Artifact, data []byte) {
+	meta.SubmissionID = run.submissionID
+	meta.TaskID = run.taskID
+	stored, err := s.artifacts.Put(meta, data)
+	if err != nil {
+		s.log.Printf("runner: failed to store %s artifact of task %s: %v", meta.Kind, run.taskID, err)
+		s.observeArtifact(meta.Kind, "error")
+		return
+	}
+	result.Artifacts = append(result.Artifacts, stored)
+	s.observeArtifact(meta.Kind, "ok")
+}
+
+// traceSteps records environment commands as "environment" trace events.
+func (s *Service) traceSteps(run *taskRun, steps []environment.Step) {
+	for _, step := range steps {
+		level := "info"
+		if !step.OK() {
+			level = "warn"
 		}
-		s.observeRun(start, "error")
-		return err
+		message := step.Result.Stdout + step.Result.Stderr
+		if step.Err != nil {
+			message = step.Err.Error()
+		}
+		s.repo.SaveTrace(models.TraceEvent{
+			ID:           fmt.Sprintf("trace-%d-%s", time.Now().UnixNano(), step.Phase),
+			SubmissionID: run.submissionID,
+			TaskID:       run.taskID,
+			Type:         "environment",
+			Message:      message,
+			Parameters:   map[string]string{"phase": step.Phase, "command": step.Command},
+			Result:       map[string]string{"exitCode": strconv.Itoa(step.Result.ExitCode)},
+			Level:        level,
+			Timestamp:    time.Now(),
+			Success:      step.OK(),
+			Latency:      step.Result.Duration.Seconds(),
+		})
 	}
-	if s.log != nil {
-		s.log.Printf("runner: completed submission %s", submission.ID)
+}
+
+// traceGrades records grader results as "grader" trace events.
+func (s *Service) traceGrades(run *taskRun, grades []models.Grade) {
+	for _, grade := range grades {
+		level := "info"
+		message := grade.Detail
+		if grade.Error != "" {
+			level = "warn"
+			message = grade.Error
+		}
+		s.repo.SaveTrace(models.TraceEvent{
+			ID:           fmt.Sprintf("trace-%d-grader", time.Now().UnixNano()),
+			SubmissionID: run.submissionID,
+			TaskID:       run.taskID,
+			Type:         "grader",
+			Message:      message,
+			Parameters:   map[string]string{"type": grade.Type, "weight": strconv.FormatFloat(grade.Weight, 'g', -1, 64)},
+			Result:       map[string]string{"score": strconv.FormatFloat(grade.Score, 'g', -1, 64)},
+			Level:        level,
+			Timestamp:    time.Now(),
+			Success:      grade.Score >= 1,
+		})
 	}
-	s.observeRun(start, "ok")
-	return nil
 }
 
-func (s *Service) callOpenAI(agent *models.User, prompt string, sb sandbox.Sandbox) (string, error) {
+// sandboxOptions maps benchmark sandbox settings onto sandbox options.
+func sandboxOptions(settings *models.SandboxSettings) []sandbox.Option {
+	if settings == nil {
+		return nil
+	}
+	return []sandbox.Option{
+		sandbox.WithLimits(sandbox.Limits{
+			CPUs:      settings.CPUs,
+			MemoryMB:  settings.MemoryMB,
+			PidsLimit: settings.PidsLimit,
+			DiskMB:    settings.DiskMB,
+		}),
+		sandbox.WithNetwork(settings.Network),
+		sandbox.WithUser(settings.User),
+		sandbox.WithWorkdir(settings.Workdir),
+	}
+}
+
+// taskRun carries per-task bookkeeping shared across agent turns.
+type taskRun struct {
+	submissionID   string
+	taskID         string
+	guard          *guardrails.Engine
+	tools          *tools.Executor // Runs tool calls in the task's sandbox
+	toolCalls      int
+	calls          []string // Names of the executed tool calls, in order
+	argumentErrors int
+
+	guardrailViolations int
+}
+
+// argumentAccuracy is the fraction of tool calls whose arguments matched the tool schema.
+func (r *taskRun) argumentAccuracy() float64 {
+	if r.toolCalls == 0 {
+		return 1
+	}
+	return float64(r.toolCalls-r.argumentErrors) / float64(r.toolCalls)
+}
+
+func (s *Service) callOpenAI(ctx context.Context, agent *models.User, prompt string, sb sandbox.Sandbox, run *taskRun) (string, error) {
 	if agent.Model == "mock" {
-		return s.mockLLM(prompt)
+		start := time.Now()
+		response, err := s.mockLLM(prompt)
+		s.traceTurn(run, agent.Model, 1, usage{}, time.Since(start))
+		return response, err
 	}
 
 	endpoint := agent.Endpoint
@@ -274,6 +573,7 @@ func (s *Service) callOpenAI(agent *models.User, prompt string, sb sandbox.Sandb
 		}
 
 		client := &http.Client{Timeout: 60 * time.Second}
+		turnStart := time.Now()
 		resp, err := client.Do(req)
 		if err != nil {
 			return "", err
@@ -289,6 +589,7 @@ func (s *Service) callOpenAI(agent *models.User, prompt string, sb sandbox.Sandb
 		if err := json.Unmarshal(body, &result); err != nil {
 			return "", err
 		}
+		s.traceTurn(run, model, i+1, parseUsage(result), time.Since(turnStart))
 
 		choices, ok := result["choices"].([]interface{})
 		if !ok || len(choices) == 0 {
@@ -312,9 +613,9 @@ func (s *Service) callOpenAI(agent *models.User, prompt string, sb sandbox.Sandb
 				id := toolCall["id"].(string)
 
 				s.log.Printf("runner: executing tool %s", name)
-				output, err := tools.ExecuteTool(sb, name, args)
+				output, err := s.executeTool(ctx, run, name, args)
 				if err != nil {
-					output = fmt.Sprintf("Error executing tool: %v", err)
+					return "", err
 				}
 
 				messages = append(messages, map[string]interface{}{
@@ -339,6 +640,117 @@ func (s *Service) callOpenAI(agent *models.User, prompt string, sb sandbox.Sandb
 	return "", fmt.Errorf("max turns reached")
 }
 
+// traceTurn records a model response as an "agent" trace event carrying its token usage and cost.
+func (s *Service) traceTurn(run *taskRun, model string, turn int, u usage, latency time.Duration) {
+	s.repo.SaveTrace(models.TraceEvent{
+		ID:           fmt.Sprintf("trace-%d-agent", time.Now().UnixNano()),
+		SubmissionID: run.submissionID,
+		TaskID:       run.taskID,
+		Type:         "agent",
+		Parameters:   map[string]string{"model": model},
+		Result: map[string]string{
+			"promptTokens":     strconv.Itoa(u.PromptTokens),
+			"completionTokens": strconv.Itoa(u.CompletionTokens),
+		},
+		Level:     "info",
+		Timestamp: time.Now(),
+		Success:   true,
+		Turns:     turn,
+		Cost:      u.cost(model),
+		Latency:   latency.Seconds(),
+	})
+}
+
+// executeTool runs a single tool call, records it as a trace event and returns the content sent back to the agent.
+// An error is returned only when a guardrail aborts the task.
+func (s *Service) executeTool(ctx context.Context, run *taskRun, name, args string) (string, error) {
+	if decision := s.checkGuardrails(run, name, args); !decision.Allowed() {
+		if decision.Action == guardrails.ActionAbort {
+			return "", fmt.Errorf("%w: %s", errTaskAborted, decision.Message)
+		}
+		payload, _ := json.Marshal(map[string]string{
+			"error":   "guardrail_denied",
+			"tool":    name,
+			"rule":    decision.Rule,
+			"message": decision.Message,
+		})
+		return string(payload), nil
+	}
+
+	start := time.Now()
+	run.toolCalls++
+	status := toolStatusOK
+	output, err := run.tools.Execute(ctx, name, args)
+	var argErr *tools.ArgumentError
+	switch {
+	case errors.As(err, &argErr):
+		run.argumentErrors++
+		status = toolStatusInvalidArguments
+		output = argErr.Payload()
+		s.log.Printf("runner: rejected %s call: %v", name, argErr)
+	case err != nil:
+		status = toolStatusError
+		output = fmt.Sprintf("Error executing tool: %v", err)
+	}
+	if status != toolStatusInvalidArguments {
+		run.calls = append(run.calls, name)
+	}
+
+	level := "info"
+	if status != toolStatusOK {
+		level = "warn"
+	}
+	s.repo.SaveTrace(models.TraceEvent{
+		ID:           fmt.Sprintf("trace-%d-tool", time.Now().UnixNano()),
+		SubmissionID: run.submissionID,
+		TaskID:       run.taskID,
+		Type:         "tool",
+		Message:      output,
+		ToolName:     name,
+		Parameters:   map[string]string{"arguments": args},
+		Result:       map[string]string{"status": status},
+		Level:        level,
+		Timestamp:    time.Now(),
+		Success:      status == toolStatusOK,
+		Latency:      time.Since(start).Seconds(),
+	})
+	if s.metrics != nil {
+		s.metrics.AddCounter("runner_tool_calls_total", map[string]string{"tool": name, "status": status}, 1)
+	}
+	return output, nil
+}
+
+// checkGuardrails evaluates a tool call against the task policy and records the decision.
+func (s *Service) checkGuardrails(run *taskRun, name, args string) guardrails.Decision {
+	if run.guard == nil {
+		return guardrails.Decision{Action: guardrails.ActionAllow}
+	}
+	decision := run.guard.Evaluate(name, args)
+	level := "info"
+	if !decision.Allowed() {
+		run.guardrailViolations++
+		level = "warn"
+		s.log.Printf("runner: guardrail %s %s call: %s", decision.Action, name, decision.Message)
+	}
+	s.repo.SaveTrace(models.TraceEvent{
+		ID:           fmt.Sprintf("trace-%d-guardrail", time.Now().UnixNano()),
+		SubmissionID: run.submissionID,
+		TaskID:       run.taskID,
+		Type:         "guardrail",
+		Message:      decision.Message,
+		ToolName:     name,
+		Parameters:   map[string]string{"arguments": args},
+		Result:       map[string]string{"decision": decision.Action, "rule": decision.Rule},
+		Level:        level,
+		Timestamp:    time.Now(),
+		Success:      decision.Allowed(),
+	})
+	if s.metrics != nil {
+		s.metrics.AddCounter("runner_guardrail_decisions_total", map[string]string{"action": decision.Action}, 1)
+	}
+	return decision
+}
+
 func (s *Service) mockLLM(prompt string) (string, error) {
 	s.log.Printf("runner: using mock LLM for prompt: %s", prompt)
 	// Simple heuristic response
@@ -348,6 +760,33 @@ func (s *Service) mockLLM(prompt string) (string, error) {
 	return "I am a mock agent.", nil
 }
 
+// Artifacts returns the artifacts captured for a submission.
+func (s *Service) Artifacts(submissionID string) []models.Artifact {
+	return s.artifacts.List(submissionID)
+}
+
+// OpenArtifact returns the metadata and content of an artifact.
+func (s *Service) OpenArtifact(id string) (models.Artifact, io.ReadCloser, error) {
+	meta, err := s.artifacts.Get(id)
+	if err != nil {
+		return models.Artifact{}, nil, err
+	}
+	content, err := s.artifacts.Open(id)
+	if err != nil {
+		return models.Artifact{}, nil, err
+	}
+	return meta, content, nil
+}
+
+// PoolStats returns the occupancy and counters of the sandbox pool, or false when sandboxes are not pooled.
+func (s *Service) PoolStats() (sandbox.PoolStats, bool) {
+	pool, ok := s.sandboxes.(*sandbox.Pool)
+	if !ok {
+		return sandbox.PoolStats{}, false
+	}
+	return pool.Stats(), true
+}
+
 // Results returns processed submissions.
 func (s *Service) Results() []models.Submission {
 	if s.metrics != nil {
@@ -364,3 +803,10 @@ func (s *Service) observeRun(start time.Time, result string) {
 	s.metrics.AddCounter("runner_runs_total", labels, 1)
 	s.metrics.ObserveHistogram("runner_duration_ms", labels, float64(time.Since(start).Milliseconds()))
 }
+
+func (s *Service) observeArtifact(kind, result string) {
+	if s.metrics == nil {
+		return
+	}
+	s.metrics.AddCounter("runner_artifacts_total", map[string]string{"kind": kind, "result": result}, 1)
+}
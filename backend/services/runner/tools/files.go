@@ -0,0 +1,116 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/example/back-end-tcc/pkg/jsonschema"
+	"github.com/example/back-end-tcc/pkg/sandbox"
+)
+
+func readFile(sb sandbox.Sandbox, path string) (string, error) {
+	data, truncated, err := sandbox.ReadFile(sb, path, MaxReadBytes)
+	if errors.Is(err, sandbox.ErrNotFound) {
+		return fmt.Sprintf("Error: file not found: %s", path), nil
+	}
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err), nil
+	}
+	if !utf8.Valid(data) {
+		return fmt.Sprintf("Error: %s is a binary file (%d bytes read)", path, len(data)), nil
+	}
+	if truncated {
+		return fmt.Sprintf("%s\n[truncated after %d bytes]", data, MaxReadBytes), nil
+	}
+	return string(data), nil
+}
+
+// writeFile writes content to path. The size limit is checked here rather than in the schema, whose
+// maxLength counts characters, and is reported as an argument error.
+func writeFile(ctx context.Context, sb sandbox.Sandbox, path, content string) (string, error) {
+	if len(content) > MaxWriteBytes {
+		return "", &ArgumentError{Tool: "write_file", Violations: []jsonschema.Violation{{Path: "/content", Message: fmt.Sprintf("must be at most %d bytes", MaxWriteBytes)}}}
+	}
+	if err := sandbox.WriteFile(ctx, sb, path, []byte(content), 0o644); err != nil {
+		return fmt.Sprintf("Error: %v", err), nil
+	}
+	return fmt.Sprintf("Successfully wrote %d bytes to %s", len(content), path), nil
+}
+
+func listDir(ctx context.Context, sb sandbox.Sandbox, path string) (string, error) {
+	res, err := sb.Exec(ctx, []string{"ls", "-lA", "--", path}, sandbox.ExecOptions{})
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err), nil
+	}
+	if !res.Success() {
+		return fmt.Sprintf("Error: ls exited with code %d\nStderr: %s", res.ExitCode, res.Stderr), nil
+	}
+	return limitLines(res.Stdout, MaxListEntries+1, "entries"), nil
+}
+
+func searchFiles(ctx context.Context, sb sandbox.Sandbox, pattern, path, include string) (string, error) {
+	cmd := []string{"grep", "-rnIE"}
+	if include != "" {
+		cmd = append(cmd, "--include="+include)
+	}
+	cmd = append(cmd, "-e", pattern, "--", path)
+	res, err := sb.Exec(ctx, cmd, sandbox.ExecOptions{})
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err), nil
+	}
+	switch {
+	case res.TimedOut:
+		return "Error: search timed out", nil
+	case res.ExitCode == 1:
+		// grep exits with 1 when nothing matched.
+		return "No matches found.", nil
+	case res.ExitCode != 0:
+		return fmt.Sprintf("Error: grep exited with code %d\nStderr: %s", res.ExitCode, res.Stderr), nil
+	}
+	return limitLines(res.Stdout, MaxSearchMatches, "matches"), nil
+}
+
+// limitLines keeps at most max lines of output, clipping overly long lines.
+func limitLines(output string, max int, unit string) string {
+	lines := strings.Split(strings.TrimRight(output, "\n"), "\n")
+	truncated := len(lines) > max
+	if truncated {
+		lines = lines[:max]
+	}
+	for i, line := range lines {
+		if len(line) > maxLineLength {
+			lines[i] = line[:maxLineLength] + "..."
+		}
+	}
+	result := strings.Join(lines, "\n")
+	if truncated {
+		result += fmt.Sprintf("\n[output limited to %d %s]", max, unit)
+	}
+	return result
+}
+
+func runCommand(ctx context.Context, sb sandbox.Sandbox, command string, timeout time.Duration) (string, error) {
+	res, err := sb.Exec(ctx, []string{"sh", "-c", command}, sandbox.ExecOptions{Timeout: timeout})
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err), nil
+	}
+	var b strings.Builder
+	if res.TimedOut {
+		fmt.Fprintf(&b, "Command timed out after %s and was killed.\n", timeout)
+	} else {
+		fmt.Fprintf(&b, "Exit code: %d\n", res.ExitCode)
+	}
+	fmt.Fprintf(&b, "Stdout:\n%s\n", res.Stdout)
+	if res.StdoutTruncated {
+		b.WriteString("[stdout truncated]\n")
+	}
+	fmt.Fprintf(&b, "Stderr:\n%s", res.Stderr)
+	if res.StderrTruncated {
+		b.WriteString("\n[stderr truncated]")
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,352 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/example/back-end-tcc/pkg/sandbox"
+)
+
+const devNull = "/dev/null"
+
+// filePatch is the set of hunks targeting a single file in a unified diff.
+type filePatch struct {
+	oldPath string
+	newPath string
+	hunks   []hunk
+}
+
+// hunk is one "@@ -a,b +c,d @@" section. Lines keep their ' ', '-' or '+' prefix.
+type hunk struct {
+	oldStart int
+	lines    []string
+}
+
+func applyPatch(ctx context.Context, sb sandbox.Sandbox, patch, directory string) (string, error) {
+	if directory == "" {
+		directory = sb.Workdir()
+	}
+	files, err := parsePatch(patch)
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err), nil
+	}
+
+	// Check every path before changing any file.
+	for _, fp := range files {
+		for _, p := range []string{fp.oldPath, fp.newPath} {
+			if p == devNull {
+				continue
+			}
+			if _, err := resolvePatchPath(directory, p); err != nil {
+				return fmt.Sprintf("Error: %v", err), nil
+			}
+		}
+	}
+
+	// Apply every hunk in memory first, so a patch that fails leaves the files untouched.
+	contents := map[string]*string{} // Patched files by path; nil once deleted
+	read := func(source string) (string, error) {
+		if content, ok := contents[source]; ok {
+			if content == nil {
+				return "", fmt.Errorf("file not found: %s", source)
+			}
+			return *content, nil
+		}
+		original, truncated, err := sandbox.ReadFile(sb, source, MaxPatchBytes)
+		if errors.Is(err, sandbox.ErrNotFound) {
+			return "", fmt.Errorf("file not found: %s", source)
+		}
+		if err != nil {
+			return "", err
+		}
+		if truncated {
+			return "", fmt.Errorf("%s exceeds %d bytes", source, MaxPatchBytes)
+		}
+		return string(original), nil
+	}
+	var changed []string // Paths in contents, in the order they were first changed
+	set := func(p string, content *string) {
+		if _, ok := contents[p]; !ok {
+			changed = append(changed, p)
+		}
+		contents[p] = content
+	}
+
+	var summary []string
+	for _, fp := range files {
+		target, _ := resolvePatchPath(directory, fp.newPath)
+		switch {
+		case fp.newPath == devNull:
+			target, _ = resolvePatchPath(directory, fp.oldPath)
+			set(target, nil)
+			summary = append(summary, "deleted "+target)
+			continue
+		case fp.oldPath == devNull:
+			content, err := applyHunks("", fp.hunks)
+			if err != nil {
+				return fmt.Sprintf("Error: %s: %v", target, err), nil
+			}
+			set(target, &content)
+			summary = append(summary, "created "+target)
+			continue
+		}
+
+		source, _ := resolvePatchPath(directory, fp.oldPath)
+		original, err := read(source)
+		if err != nil {
+			return fmt.Sprintf("Error: %v", err), nil
+		}
+		content, err := applyHunks(original, fp.hunks)
+		if err != nil {
+			return fmt.Sprintf("Error: %s: %v", source, err), nil
+		}
+		set(target, &content)
+		if source != target {
+			set(source, nil)
+			summary = append(summary, fmt.Sprintf("renamed %s -> %s", source, target))
+			continue
+		}
+		summary = append(summary, "patched "+target)
+	}
+
+	// Write before removing, so a rename never loses the only copy.
+	for _, p := range changed {
+		if content := contents[p]; content != nil {
+			if err := sandbox.WriteFile(ctx, sb, p, []byte(*content), 0o644); err != nil {
+				return fmt.Sprintf("Error: %v", err), nil
+			}
+		}
+	}
+	for _, p := range changed {
+		if contents[p] == nil {
+			if err := removeFile(ctx, sb, p); err != nil {
+				return fmt.Sprintf("Error: failed to remove %s: %v", p, err), nil
+			}
+		}
+	}
+	return "Patch applied:\n" + strings.Join(summary, "\n"), nil
+}
+
+func removeFile(ctx context.Context, sb sandbox.Sandbox, target string) error {
+	res, err := sb.Exec(ctx, []string{"rm", "-f", "--", target}, sandbox.ExecOptions{})
+	if err != nil {
+		return err
+	}
+	if !res.Success() {
+		return errors.New(strings.TrimSpace(res.Stderr))
+	}
+	return nil
+}
+
+// resolvePatchPath resolves a diff path against directory, rejecting paths that leave it.
+func resolvePatchPath(directory, p string) (string, error) {
+	resolved := path.Join(directory, p)
+	if path.IsAbs(p) {
+		resolved = path.Clean(p)
+	}
+	directory = path.Clean(directory)
+	if resolved != directory && !strings.HasPrefix(resolved, strings.TrimSuffix(directory, "/")+"/") {
+		return "", fmt.Errorf("path %s is outside %s", p, directory)
+	}
+	return resolved, nil
+}
+
+// parsePatch reads a (possibly multi-file) unified diff.
+func parsePatch(patch string) ([]filePatch, error) {
+	lines := strings.SplitAfter(patch, "\n")
+	var files []filePatch
+	var current *filePatch
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		switch {
+		case strings.HasPrefix(line, "--- "):
+			files = append(files, filePatch{oldPath: patchPath(line[4:])})
+			current = &files[len(files)-1]
+		case strings.HasPrefix(line, "+++ "):
+			if current == nil {
+				return nil, fmt.Errorf("line %d: +++ header without --- header", i+1)
+			}
+			current.newPath = patchPath(line[4:])
+		case strings.HasPrefix(line, "@@"):
+			if current == nil || current.newPath == "" {
+				return nil, fmt.Errorf("line %d: hunk without file headers", i+1)
+			}
+			h, oldCount, newCount, err := parseHunkHeader(line)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", i+1, err)
+			}
+			for (oldCount > 0 || newCount > 0) && i+1 < len(lines) {
+				i++
+				body := lines[i]
+				if body == "" {
+					break
+				}
+				if body == "\n" {
+					// Some generators drop the leading space of blank context lines.
+					body = " " + body
+				}
+				switch body[0] {
+				case ' ':
+					oldCount--
+					newCount--
+				case '-':
+					oldCount--
+				case '+':
+					newCount--
+				case '\\':
+					trimTrailingNewline(&h)
+					continue
+				default:
+					return nil, fmt.Errorf("line %d: unexpected hunk line %q", i+1, strings.TrimRight(body, "\n"))
+				}
+				h.lines = append(h.lines, body)
+			}
+			if oldCount > 0 || newCount > 0 {
+				return nil, fmt.Errorf("line %d: truncated hunk", i+1)
+			}
+			if i+1 < len(lines) && strings.HasPrefix(lines[i+1], "\\") {
+				i++
+				trimTrailingNewline(&h)
+			}
+			current.hunks = append(current.hunks, h)
+		}
+	}
+	if len(files) == 0 {
+		return nil, errors.New("no file headers found in patch")
+	}
+	for _, f := range files {
+		if len(f.hunks) == 0 && f.newPath != devNull {
+			return nil, fmt.Errorf("no hunks for %s", f.newPath)
+		}
+	}
+	return files, nil
+}
+
+// patchPath strips timestamps and the conventional a/ and b/ prefixes from a header path.
+func patchPath(raw string) string {
+	p := strings.TrimRight(raw, "\r\n")
+	if idx := strings.Index(p, "\t"); idx >= 0 {
+		p = p[:idx]
+	}
+	if p == devNull {
+		return p
+	}
+	if strings.HasPrefix(p, "a/") || strings.HasPrefix(p, "b/") {
+		p = p[2:]
+	}
+	return p
+}
+
+func parseHunkHeader(line string) (hunk, int, int, error) {
+	fields := strings.Fields(line)
+	if len(fields) < 3 || !strings.HasPrefix(fields[1], "-") || !strings.HasPrefix(fields[2], "+") {
+		return hunk{}, 0, 0, fmt.Errorf("malformed hunk header %q", strings.TrimSpace(line))
+	}
+	oldStart, oldCount, err := parseRange(fields[1][1:])
+	if err != nil {
+		return hunk{}, 0, 0, err
+	}
+	_, newCount, err := parseRange(fields[2][1:])
+	if err != nil {
+		return hunk{}, 0, 0, err
+	}
+	return hunk{oldStart: oldStart}, oldCount, newCount, nil
+}
+
+func parseRange(r string) (int, int, error) {
+	start, count := r, "1"
+	if idx := strings.Index(r, ","); idx >= 0 {
+		start, count = r[:idx], r[idx+1:]
+	}
+	s, err := strconv.Atoi(start)
+	if err != nil {
+		return 0, 0, fmt.Errorf("malformed range %q", r)
+	}
+	c, err := strconv.Atoi(count)
+	if err != nil {
+		return 0, 0, fmt.Errorf("malformed range %q", r)
+	}
+	return s, c, nil
+}
+
+// trimTrailingNewline handles "\ No newline at end of file" markers for the previous hunk line.
+func trimTrailingNewline(h *hunk) {
+	if n := len(h.lines); n > 0 {
+		h.lines[n-1] = strings.TrimSuffix(h.lines[n-1], "\n")
+	}
+}
+
+// applyHunks applies hunks in order, locating each one by its context and tolerating line offsets.
+func applyHunks(original string, hunks []hunk) (string, error) {
+	lines := strings.SplitAfter(original, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	offset := 0
+	for idx, h := range hunks {
+		var oldBlock, newBlock []string
+		for _, l := range h.lines {
+			switch l[0] {
+			case ' ':
+				oldBlock = append(oldBlock, l[1:])
+				newBlock = append(newBlock, l[1:])
+			case '-':
+				oldBlock = append(oldBlock, l[1:])
+			case '+':
+				newBlock = append(newBlock, l[1:])
+			}
+		}
+
+		want := h.oldStart - 1 + offset
+		if len(oldBlock) == 0 {
+			want = h.oldStart + offset
+		}
+		pos := findBlock(lines, oldBlock, want)
+		if pos < 0 {
+			return "", fmt.Errorf("hunk %d does not apply", idx+1)
+		}
+		updated := make([]string, 0, len(lines)-len(oldBlock)+len(newBlock))
+		updated = append(updated, lines[:pos]...)
+		updated = append(updated, newBlock...)
+		updated = append(updated, lines[pos+len(oldBlock):]...)
+		lines = updated
+		offset += len(newBlock) - len(oldBlock)
+	}
+	return strings.Join(lines, ""), nil
+}
+
+// findBlock returns the index closest to want where block matches lines exactly, or -1.
+func findBlock(lines, block []string, want int) int {
+	if want < 0 {
+		want = 0
+	}
+	if want > len(lines) {
+		want = len(lines)
+	}
+	matches := func(pos int) bool {
+		if pos < 0 || pos+len(block) > len(lines) {
+			return false
+		}
+		for i, l := range block {
+			if lines[pos+i] != l {
+				return false
+			}
+		}
+		return true
+	}
+	for delta := 0; delta <= len(lines); delta++ {
+		if matches(want - delta) {
+			return want - delta
+		}
+		if matches(want + delta) {
+			return want + delta
+		}
+	}
+	return -1
+}
@@ -0,0 +1,116 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/example/back-end-tcc/pkg/sandbox"
+)
+
+// Limits of the shell tool.
+const (
+	DefaultShellWait        = 2 * time.Second
+	MaxShellWait            = 60 * time.Second
+	MaxShellSessions        = 4
+	MaxShellOutputBytes     = 64 << 10
+	defaultShellSessionName = "default"
+)
+
+// shells holds the interactive shell sessions a task opened through the shell tool.
+type shells struct {
+	mu       sync.Mutex
+	sessions map[string]sandbox.Session
+}
+
+func newShells() *shells {
+	return &shells{sessions: make(map[string]sandbox.Session)}
+}
+
+// closeAll kills every open session.
+func (s *shells) closeAll() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for name, session := range s.sessions {
+		session.Close()
+		delete(s.sessions, name)
+	}
+}
+
+// session returns the named session, opening it on first use.
+func (s *shells) session(ctx context.Context, sb sandbox.Sandbox, name string) (sandbox.Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if session, ok := s.sessions[name]; ok {
+		return session, nil
+	}
+	if len(s.sessions) >= MaxShellSessions {
+		return nil, fmt.Errorf("at most %d shell sessions can be open; close one first", MaxShellSessions)
+	}
+	session, err := sandbox.OpenSession(ctx, sb, sandbox.SessionOptions{MaxBufferBytes: MaxShellOutputBytes})
+	if err != nil {
+		return nil, err
+	}
+	s.sessions[name] = session
+	return session, nil
+}
+
+func (s *shells) close(name string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	session, ok := s.sessions[name]
+	if ok {
+		session.Close()
+		delete(s.sessions, name)
+	}
+	return ok
+}
+
+func shell(ctx context.Context, sb sandbox.Sandbox, shells *shells, name, input string, wait time.Duration, closeSession bool) (string, error) {
+	if closeSession {
+		if !shells.close(name) {
+			return fmt.Sprintf("Error: no shell session named %q", name), nil
+		}
+		return fmt.Sprintf("Closed shell session %q.", name), nil
+	}
+
+	session, err := shells.session(ctx, sb, name)
+	if errors.Is(err, sandbox.ErrSessionsUnsupported) {
+		return "Error: this sandbox does not support interactive shells; use run_command instead", nil
+	}
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err), nil
+	}
+	if input != "" {
+		if !strings.HasSuffix(input, "\n") {
+			input += "\n"
+		}
+		if err := session.Write(input); err != nil {
+			shells.close(name)
+			return fmt.Sprintf("Error: %v", err), nil
+		}
+	}
+
+	out, err := session.Read(ctx, wait)
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err), nil
+	}
+	var b strings.Builder
+	if out.Dropped > 0 {
+		fmt.Fprintf(&b, "[%d earlier bytes dropped]\n", out.Dropped)
+	}
+	b.WriteString(out.Output)
+	if out.Exited {
+		shells.close(name)
+		if b.Len() > 0 && !strings.HasSuffix(b.String(), "\n") {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "[shell exited with code %d; the next call opens a new session]", out.ExitCode)
+	} else if b.Len() == 0 {
+		b.WriteString("[no output yet; call shell without input to read more]")
+	}
+	return b.String(), nil
+}
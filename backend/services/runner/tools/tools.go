@@ -1,10 +1,15 @@
 package tools
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
+	"sync"
+	"time"
 
+	"github.com/example/back-end-tcc/pkg/jsonschema"
 	"github.com/example/back-end-tcc/pkg/sandbox"
 )
 
@@ -21,6 +26,22 @@ type Function struct {
 	Parameters  any    `json:"parameters"`
 }
 
+// Size limits applied to file transfer and search tools.
+const (
+	MaxReadBytes     = 256 << 10
+	MaxWriteBytes    = 1 << 20
+	MaxPatchBytes    = 1 << 20
+	MaxListEntries   = 500
+	MaxSearchMatches = 200
+	maxLineLength    = 500
+)
+
+// Command timeouts for run_command.
+const (
+	DefaultCommandTimeout = 60 * time.Second
+	MaxCommandTimeout     = 10 * time.Minute
+)
+
 // GetTools returns the list of available tools.
 func GetTools() []ToolDefinition {
 	return []ToolDefinition{
@@ -28,16 +49,18 @@ func GetTools() []ToolDefinition {
 			Type: "function",
 			Function: Function{
 				Name:        "read_file",
-				Description: "Read the content of a file from the sandbox.",
+				Description: fmt.Sprintf("Read the content of a file from the sandbox. Output is truncated after %d bytes.", MaxReadBytes),
 				Parameters: map[string]interface{}{
 					"type": "object",
 					"properties": map[string]interface{}{
 						"path": map[string]string{
 							"type":        "string",
 							"description": "The absolute path to the file.",
+							"pattern":     "^/",
 						},
 					},
-					"required": []string{"path"},
+					"required":             []string{"path"},
+					"additionalProperties": false,
 				},
 			},
 		},
@@ -45,20 +68,94 @@ func GetTools() []ToolDefinition {
 			Type: "function",
 			Function: Function{
 				Name:        "write_file",
-				Description: "Write content to a file in the sandbox. Overwrites if exists.",
+				Description: "Write content to a file in the sandbox. Overwrites if exists and creates missing parent directories. Only the working directory is writable.",
 				Parameters: map[string]interface{}{
 					"type": "object",
 					"properties": map[string]interface{}{
 						"path": map[string]string{
 							"type":        "string",
 							"description": "The absolute path to the file.",
+							"pattern":     "^/",
 						},
-						"content": map[string]string{
+						"content": map[string]interface{}{
 							"type":        "string",
-							"description": "The content to write.",
+							"description": fmt.Sprintf("The content to write, at most %d bytes.", MaxWriteBytes),
 						},
 					},
-					"required": []string{"path", "content"},
+					"required":             []string{"path", "content"},
+					"additionalProperties": false,
+				},
+			},
+		},
+		{
+			Type: "function",
+			Function: Function{
+				Name:        "list_dir",
+				Description: fmt.Sprintf("List the entries of a directory in the sandbox (at most %d entries).", MaxListEntries),
+				Parameters: map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"path": map[string]string{
+							"type":        "string",
+							"description": "The absolute path to the directory.",
+							"pattern":     "^/",
+						},
+					},
+					"required":             []string{"path"},
+					"additionalProperties": false,
+				},
+			},
+		},
+		{
+			Type: "function",
+			Function: Function{
+				Name:        "search_files",
+				Description: fmt.Sprintf("Search file contents recursively with a regular expression (at most %d matching lines).", MaxSearchMatches),
+				Parameters: map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"pattern": map[string]interface{}{
+							"type":        "string",
+							"description": "Extended regular expression to search for.",
+							"minLength":   1,
+						},
+						"path": map[string]string{
+							"type":        "string",
+							"description": "The absolute path of the directory or file to search.",
+							"pattern":     "^/",
+						},
+						"include": map[string]string{
+							"type":        "string",
+							"description": "Optional file name glob, e.g. *.py.",
+						},
+					},
+					"required":             []string{"pattern", "path"},
+					"additionalProperties": false,
+				},
+			},
+		},
+		{
+			Type: "function",
+			Function: Function{
+				Name:        "apply_patch",
+				Description: "Apply a unified diff to files in the sandbox. Paths in the diff are resolved against directory and must stay inside it.",
+				Parameters: map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"patch": map[string]interface{}{
+							"type":        "string",
+							"description": "The unified diff to apply.",
+							"minLength":   1,
+							"maxLength":   MaxPatchBytes,
+						},
+						"directory": map[string]string{
+							"type":        "string",
+							"description": "Absolute base directory for relative paths (defaults to the working directory).",
+							"pattern":     "^/",
+						},
+					},
+					"required":             []string{"patch"},
+					"additionalProperties": false,
 				},
 			},
 		},
@@ -66,7 +163,7 @@ func GetTools() []ToolDefinition {
 			Type: "function",
 			Function: Function{
 				Name:        "run_command",
-				Description: "Execute a shell command in the sandbox.",
+				Description: "Execute a shell command in the sandbox. Reports the exit code, stdout and stderr.",
 				Parameters: map[string]interface{}{
 					"type": "object",
 					"properties": map[string]interface{}{
@@ -74,71 +171,177 @@ func GetTools() []ToolDefinition {
 							"type":        "string",
 							"description": "The command to execute.",
 						},
+						"timeout_seconds": map[string]interface{}{
+							"type":        "integer",
+							"description": fmt.Sprintf("Kill the command after this many seconds (default %d).", int(DefaultCommandTimeout.Seconds())),
+							"minimum":     1,
+							"maximum":     int(MaxCommandTimeout.Seconds()),
+						},
 					},
-					"required": []string{"command"},
+					"required":             []string{"command"},
+					"additionalProperties": false,
+				},
+			},
+		},
+		{
+			Type: "function",
+			Function: Function{
+				Name: "shell",
+				Description: "Send input to a persistent interactive shell in the sandbox and read its output. The working directory, " +
+					"exported variables and background processes persist between calls, unlike run_command. Stdout and stderr are interleaved.",
+				Parameters: map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"input": map[string]string{
+							"type":        "string",
+							"description": "Text to send to the shell; a trailing newline is added. Omit to only read new output.",
+						},
+						"session": map[string]string{
+							"type":        "string",
+							"description": fmt.Sprintf("Session name (default %q); up to %d sessions can be open.", defaultShellSessionName, MaxShellSessions),
+							"pattern":     "^[A-Za-z0-9_-]{1,32}$",
+						},
+						"wait_seconds": map[string]interface{}{
+							"type":        "integer",
+							"description": fmt.Sprintf("How long to wait for output (default %d). Output is returned early once it stops.", int(DefaultShellWait.Seconds())),
+							"minimum":     0,
+							"maximum":     int(MaxShellWait.Seconds()),
+						},
+						"close": map[string]string{
+							"type":        "boolean",
+							"description": "Kill the session and everything it started.",
+						},
+					},
+					"additionalProperties": false,
 				},
 			},
 		},
 	}
 }
 
-// ExecuteTool executes a tool in the sandbox.
-func ExecuteTool(sb sandbox.Sandbox, name string, args string) (string, error) {
-	var arguments map[string]interface{}
-	if err := json.Unmarshal([]byte(args), &arguments); err != nil {
-		return "", fmt.Errorf("invalid arguments: %w", err)
+// ArgumentError reports a tool call whose arguments do not satisfy the tool's declared schema.
+type ArgumentError struct {
+	Tool       string                 `json:"tool"`
+	Violations []jsonschema.Violation `json:"violations"`
+}
+
+func (e *ArgumentError) Error() string {
+	parts := make([]string, 0, len(e.Violations))
+	for _, v := range e.Violations {
+		parts = append(parts, fmt.Sprintf("%s: %s", v.Path, v.Message))
 	}
+	return fmt.Sprintf("invalid arguments for %s: %s", e.Tool, strings.Join(parts, "; "))
+}
 
-	switch name {
-	case "read_file":
-		path, ok := arguments["path"].(string)
-		if !ok {
-			return "", fmt.Errorf("missing path")
-		}
-		// Use cat to read file
-		stdout, stderr, err := sb.Exec([]string{"cat", path})
-		if err != nil {
-			return fmt.Sprintf("Error: %v\nStderr: %s", err, stderr), nil
-		}
-		if stderr != "" {
-			return fmt.Sprintf("Stderr: %s", stderr), nil
-		}
-		return stdout, nil
+// Payload renders the error as the structured tool result returned to the agent.
+func (e *ArgumentError) Payload() string {
+	data, _ := json.Marshal(map[string]interface{}{
+		"error":      "invalid_arguments",
+		"tool":       e.Tool,
+		"violations": e.Violations,
+	})
+	return string(data)
+}
 
-	case "write_file":
-		path, ok := arguments["path"].(string)
-		if !ok {
-			return "", fmt.Errorf("missing path")
-		}
-		content, ok := arguments["content"].(string)
-		if !ok {
-			return "", fmt.Errorf("missing content")
-		}
-		// Use sh -c to write file. Need to be careful with quoting.
-		// A safer way is to write to a temp file via Exec input if supported, but Docker Exec doesn't easily support stdin.
-		// For MVP, we'll try simple echo or printf. printf is safer.
-		// escape single quotes
-		escapedContent := strings.ReplaceAll(content, "'", "'\\''")
-		cmd := fmt.Sprintf("printf '%%s' '%s' > '%s'", escapedContent, path)
-		stdout, stderr, err := sb.Exec([]string{"sh", "-c", cmd})
-		if err != nil {
-			return fmt.Sprintf("Error: %v\nStderr: %s", err, stderr), nil
+var (
+	schemasOnce sync.Once
+	schemas     map[string]*jsonschema.Schema
+)
+
+func toolSchemas() map[string]*jsonschema.Schema {
+	schemasOnce.Do(func() {
+		schemas = make(map[string]*jsonschema.Schema)
+		for _, def := range GetTools() {
+			schema, err := jsonschema.Compile(def.Function.Parameters)
+			if err != nil {
+				panic(fmt.Sprintf("tools: invalid schema for %s: %v", def.Function.Name, err))
+			}
+			schemas[def.Function.Name] = schema
 		}
-		if stderr != "" {
-			return fmt.Sprintf("Stderr: %s", stderr), nil
+	})
+	return schemas
+}
+
+// ValidateArguments decodes the raw arguments of a tool call and checks them against the tool schema.
+// Schema mismatches are reported as *ArgumentError.
+func ValidateArguments(name string, args string) (map[string]interface{}, error) {
+	schema, ok := toolSchemas()[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown tool: %s", name)
+	}
+	var decoded interface{}
+	if err := json.Unmarshal([]byte(args), &decoded); err != nil {
+		return nil, &ArgumentError{Tool: name, Violations: []jsonschema.Violation{{Path: "/", Message: fmt.Sprintf("invalid JSON: %v", err)}}}
+	}
+	if err := schema.Validate(decoded); err != nil {
+		var verr *jsonschema.ValidationError
+		if errors.As(err, &verr) {
+			return nil, &ArgumentError{Tool: name, Violations: verr.Violations}
 		}
-		return fmt.Sprintf("Successfully wrote to %s\nOutput: %s", path, stdout), nil
+		return nil, err
+	}
+	arguments, ok := decoded.(map[string]interface{})
+	if !ok {
+		return nil, &ArgumentError{Tool: name, Violations: []jsonschema.Violation{{Path: "/", Message: "arguments must be a JSON object"}}}
+	}
+	return arguments, nil
+}
 
+// Executor runs the tool calls of one task in its sandbox and keeps the shell sessions the task opens.
+type Executor struct {
+	sandbox sandbox.Sandbox
+	shells  *shells
+}
+
+// NewExecutor creates an executor for a task's sandbox. Close it when the task ends.
+func NewExecutor(sb sandbox.Sandbox) *Executor {
+	return &Executor{sandbox: sb, shells: newShells()}
+}
+
+// Close kills the shell sessions the task left open.
+func (e *Executor) Close() {
+	e.shells.closeAll()
+}
+
+// Execute validates the arguments and executes a tool in the sandbox.
+func (e *Executor) Execute(ctx context.Context, name string, args string) (string, error) {
+	arguments, err := ValidateArguments(name, args)
+	if err != nil {
+		return "", err
+	}
+	sb := e.sandbox
+
+	switch name {
+	case "read_file":
+		return readFile(sb, arguments["path"].(string))
+	case "write_file":
+		return writeFile(ctx, sb, arguments["path"].(string), arguments["content"].(string))
+	case "list_dir":
+		return listDir(ctx, sb, arguments["path"].(string))
+	case "search_files":
+		include, _ := arguments["include"].(string)
+		return searchFiles(ctx, sb, arguments["pattern"].(string), arguments["path"].(string), include)
+	case "apply_patch":
+		directory, _ := arguments["directory"].(string)
+		return applyPatch(ctx, sb, arguments["patch"].(string), directory)
 	case "run_command":
-		command, ok := arguments["command"].(string)
-		if !ok {
-			return "", fmt.Errorf("missing command")
+		timeout := DefaultCommandTimeout
+		if seconds, ok := arguments["timeout_seconds"].(float64); ok {
+			timeout = time.Duration(seconds) * time.Second
+		}
+		return runCommand(ctx, sb, arguments["command"].(string), timeout)
+	case "shell":
+		session := defaultShellSessionName
+		if name, ok := arguments["session"].(string); ok {
+			session = name
 		}
-		stdout, stderr, err := sb.Exec([]string{"sh", "-c", command})
-		if err != nil {
-			return fmt.Sprintf("Error: %v\nStderr: %s", err, stderr), nil
+		wait := DefaultShellWait
+		if seconds, ok := arguments["wait_seconds"].(float64); ok {
+			wait = time.Duration(seconds) * time.Second
 		}
-		return fmt.Sprintf("Stdout:\n%s\nStderr:\n%s", stdout, stderr), nil
+		input, _ := arguments["input"].(string)
+		closeSession, _ := arguments["close"].(bool)
+		return shell(ctx, sb, e.shells, session, input, wait, closeSession)
 
 	default:
 		return "", fmt.Errorf("unknown tool: %s", name)
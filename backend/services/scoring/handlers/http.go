@@ -1,9 +1,15 @@
 package handlers
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
 	"net/http"
+	"strconv"
 
+	"github.com/example/back-end-tcc/pkg/export"
 	pkghttp "github.com/example/back-end-tcc/pkg/http"
+	"github.com/example/back-end-tcc/pkg/models"
 	"github.com/example/back-end-tcc/services/scoring/service"
 )
 
@@ -17,7 +23,181 @@ func New(service *service.Service) *HTTP {
 	return &HTTP{service: service}
 }
 
-// List returns summaries.
+// List returns summaries, optionally filtered by ?agent_id=, ?benchmark_id= and ?version=.
 func (h *HTTP) List(w http.ResponseWriter, r *http.Request) {
-	pkghttp.JSON(w, http.StatusOK, h.service.Summaries())
+	query := r.URL.Query()
+	version, ok := scoringVersion(w, r)
+	if !ok {
+		return
+	}
+	summaries, err := h.service.Find(query.Get("agent_id"), query.Get("benchmark_id"), version)
+	if err != nil {
+		pkghttp.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	pkghttp.JSON(w, http.StatusOK, summaries)
+}
+
+// Export renders the summaries List would return, or one ?submission_id='s, as ?format=csv,
+// markdown or jsonl with the comma-separated ?columns=. ?rows=tasks exports their per-task results
+// instead (see export.Summaries and export.Tasks).
+func (h *HTTP) Export(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	version, ok := scoringVersion(w, r)
+	if !ok {
+		return
+	}
+	format, err := export.ParseFormat(query.Get("format"))
+	if err != nil {
+		pkghttp.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	write := export.WriteSummaries
+	switch query.Get("rows") {
+	case "", "summaries":
+	case "tasks":
+		write = export.WriteTasks
+	default:
+		pkghttp.Error(w, http.StatusBadRequest, "rows must be summaries or tasks")
+		return
+	}
+	var summaries []models.ScoreSummary
+	if submissionID := query.Get("submission_id"); submissionID != "" {
+		summary, ok, err := h.service.Summary(submissionID)
+		if version != 0 {
+			summary, ok = h.service.SummaryVersion(submissionID, version)
+		}
+		if err != nil {
+			pkghttp.Error(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		if !ok {
+			pkghttp.Error(w, http.StatusNotFound, "score not found")
+			return
+		}
+		summaries = append(summaries, summary)
+	} else if summaries, err = h.service.Find(query.Get("agent_id"), query.Get("benchmark_id"), version); err != nil {
+		pkghttp.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	var body bytes.Buffer
+	if err := write(&body, format, export.ParseColumns(query.Get("columns")), summaries); err != nil {
+		pkghttp.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	name := "scores"
+	if query.Get("rows") == "tasks" {
+		name = "tasks"
+	}
+	pkghttp.Attachment(w, format.ContentType(), name+"."+format.Extension(), body.Bytes())
+}
+
+// Compare runs paired significance tests between the submissions ?a= and ?b=.
+func (h *HTTP) Compare(w http.ResponseWriter, r *http.Request) {
+	a, b := r.URL.Query().Get("a"), r.URL.Query().Get("b")
+	if a == "" || b == "" {
+		pkghttp.Error(w, http.StatusBadRequest, "a and b submission ids are required")
+		return
+	}
+	cmp, err := h.service.Compare(a, b)
+	switch {
+	case errors.Is(err, service.ErrScoreNotFound):
+		pkghttp.Error(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, service.ErrDifferentBenchmarks), errors.Is(err, service.ErrNoPairedTasks):
+		pkghttp.Error(w, http.StatusBadRequest, err.Error())
+	case err != nil:
+		pkghttp.Error(w, http.StatusInternalServerError, err.Error())
+	default:
+		pkghttp.JSON(w, http.StatusOK, cmp)
+	}
+}
+
+// Get returns the summary of the submission in the {submissionId} path segment, under the scoring
+// ?version= when given.
+func (h *HTTP) Get(w http.ResponseWriter, r *http.Request) {
+	version, ok := scoringVersion(w, r)
+	if !ok {
+		return
+	}
+	summary, ok, err := h.service.Summary(r.PathValue("submissionId"))
+	if version != 0 {
+		summary, ok = h.service.SummaryVersion(r.PathValue("submissionId"), version)
+	}
+	if err != nil {
+		pkghttp.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if !ok {
+		pkghttp.Error(w, http.StatusNotFound, "score not found")
+		return
+	}
+	pkghttp.JSON(w, http.StatusOK, summary)
+}
+
+// History returns every scoring version's summary of the submission in the {submissionId} path segment.
+func (h *HTTP) History(w http.ResponseWriter, r *http.Request) {
+	history, err := h.service.History(r.PathValue("submissionId"))
+	if err != nil {
+		pkghttp.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if len(history) == 0 {
+		pkghttp.Error(w, http.StatusNotFound, "score not found")
+		return
+	}
+	pkghttp.JSON(w, http.StatusOK, history)
+}
+
+// Configs lists the scoring config versions.
+func (h *HTTP) Configs(w http.ResponseWriter, r *http.Request) {
+	pkghttp.JSON(w, http.StatusOK, h.service.Configs())
+}
+
+// CreateConfig stores a new scoring config version.
+func (h *HTTP) CreateConfig(w http.ResponseWriter, r *http.Request) {
+	var config models.ScoringConfig
+	if err := json.NewDecoder(r.Body).Decode(&config); err != nil {
+		pkghttp.Error(w, http.StatusBadRequest, "invalid payload")
+		return
+	}
+	created, err := h.service.CreateConfig(config)
+	if err != nil {
+		pkghttp.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	pkghttp.JSON(w, http.StatusCreated, created)
+}
+
+// Rescore recomputes the summaries of the stored submissions the body selects.
+func (h *HTTP) Rescore(w http.ResponseWriter, r *http.Request) {
+	var req service.RescoreRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			pkghttp.Error(w, http.StatusBadRequest, "invalid payload")
+			return
+		}
+	}
+	result, err := h.service.Rescore(r.Context(), req)
+	switch {
+	case errors.Is(err, service.ErrConfigNotFound):
+		pkghttp.Error(w, http.StatusNotFound, err.Error())
+	case err != nil:
+		pkghttp.Error(w, http.StatusInternalServerError, err.Error())
+	default:
+		pkghttp.JSON(w, http.StatusOK, result)
+	}
+}
+
+// scoringVersion parses the optional ?version= query parameter, answering 400 when it is invalid.
+func scoringVersion(w http.ResponseWriter, r *http.Request) (int, bool) {
+	raw := r.URL.Query().Get("version")
+	if raw == "" {
+		return 0, true
+	}
+	version, err := strconv.Atoi(raw)
+	if err != nil || version < 1 {
+		pkghttp.Error(w, http.StatusBadRequest, "version must be a positive integer")
+		return 0, false
+	}
+	return version, true
 }
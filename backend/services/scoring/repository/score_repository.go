@@ -1,26 +1,157 @@
 package repository
 
 import (
+	"fmt"
+	"sort"
+	"strconv"
+
 	"github.com/example/back-end-tcc/pkg/models"
 	"github.com/example/back-end-tcc/pkg/storage"
 )
 
-// ScoreRepository stores score summaries.
+// Option customises the repository.
+type Option func(*ScoreRepository)
+
+// WithSubmissions lets rescoring read stored submissions and their task results.
+func WithSubmissions(store storage.Repository[models.Submission]) Option {
+	return func(r *ScoreRepository) {
+		r.submissionStore = store
+	}
+}
+
+// WithConfigs persists scoring config versions. Without it they are kept in memory.
+func WithConfigs(store storage.Repository[models.ScoringConfig]) Option {
+	return func(r *ScoreRepository) {
+		r.configStore = store
+	}
+}
+
+// ScoreRepository stores score summaries per scoring version and reads the trace events and
+// submissions they are computed from.
 type ScoreRepository struct {
-	store storage.Repository[models.ScoreSummary]
+	store           storage.Repository[models.ScoreSummary]
+	traceStore      storage.Repository[models.TraceEvent]
+	submissionStore storage.Repository[models.Submission]
+	configStore     storage.Repository[models.ScoringConfig]
+}
+
+// New creates repository. traceStore may be nil, in which case summaries rely on task results only.
+func New(store storage.Repository[models.ScoreSummary], traceStore storage.Repository[models.TraceEvent], opts ...Option) *ScoreRepository {
+	r := &ScoreRepository{store: store, traceStore: traceStore}
+	for _, opt := range opts {
+		opt(r)
+	}
+	if r.configStore == nil {
+		r.configStore = storage.NewMemoryRepository[models.ScoringConfig]()
+	}
+	return r
+}
+
+// Save stores summary under its submission and scoring version, replacing an earlier summary of
+// the same version.
+func (r *ScoreRepository) Save(summary models.ScoreSummary) {
+	r.store.Save(fmt.Sprintf("%s@v%d", summary.SubmissionID, summary.ScoringVersion), summary)
+}
+
+// Get returns the summary of a submission under the newest scoring version it was scored with.
+func (r *ScoreRepository) Get(submissionID string) (models.ScoreSummary, bool, error) {
+	newest, err := storage.Find(r.store, storage.Query{
+		Where:   []storage.Condition{{Field: "submissionId", Op: storage.Eq, Value: submissionID}},
+		OrderBy: []storage.Order{{Field: "scoringVersion", Desc: true}},
+		Limit:   1,
+	})
+	if err != nil || len(newest) == 0 {
+		return models.ScoreSummary{}, false, err
+	}
+	return newest[0], true, nil
 }
 
-// New creates repository.
-func New(store storage.Repository[models.ScoreSummary]) *ScoreRepository {
-	return &ScoreRepository{store: store}
+// GetVersion returns the summary of a submission under one scoring version.
+func (r *ScoreRepository) GetVersion(submissionID string, version int) (models.ScoreSummary, bool) {
+	return r.store.Get(fmt.Sprintf("%s@v%d", submissionID, version))
 }
 
-// Save stores summary.
-func (r *ScoreRepository) Save(id string, summary models.ScoreSummary) {
-	r.store.Save(id, summary)
+// History returns every summary of a submission, oldest scoring version first.
+func (r *ScoreRepository) History(submissionID string) ([]models.ScoreSummary, error) {
+	return storage.Find(r.store, storage.Query{
+		Where:   []storage.Condition{{Field: "submissionId", Op: storage.Eq, Value: submissionID}},
+		OrderBy: []storage.Order{{Field: "scoringVersion"}},
+	})
 }
 
-// List returns all summaries.
+// List returns all summaries of every version.
 func (r *ScoreRepository) List() []models.ScoreSummary {
 	return r.store.List()
 }
+
+// Find returns the summaries matching the agent and benchmark, newest first. Empty IDs match any.
+// Version 0 picks the newest version of each submission; otherwise only that version is returned.
+func (r *ScoreRepository) Find(agentID, benchmarkID string, version int) ([]models.ScoreSummary, error) {
+	var where []storage.Condition
+	for _, eq := range [][2]string{{"agentId", agentID}, {"benchmarkId", benchmarkID}} {
+		if eq[1] != "" {
+			where = append(where, storage.Condition{Field: eq[0], Op: storage.Eq, Value: eq[1]})
+		}
+	}
+	if version != 0 {
+		return storage.Find(r.store, storage.Query{
+			Where:   append(where, storage.Condition{Field: "scoringVersion", Op: storage.Eq, Value: version}),
+			OrderBy: []storage.Order{{Field: "calculated", Desc: true}, {Field: "submissionId"}},
+		})
+	}
+	// Newest version first, so the first summary of each submission is the one to keep.
+	matched, err := storage.Find(r.store, storage.Query{
+		Where:   where,
+		OrderBy: []storage.Order{{Field: "scoringVersion", Desc: true}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	seen := map[string]bool{}
+	var summaries []models.ScoreSummary
+	for _, summary := range matched {
+		if !seen[summary.SubmissionID] {
+			seen[summary.SubmissionID] = true
+			summaries = append(summaries, summary)
+		}
+	}
+	sort.SliceStable(summaries, func(i, j int) bool { return summaries[i].Calculated.After(summaries[j].Calculated) })
+	return summaries, nil
+}
+
+// Traces returns the trace events of a submission in chronological order, or none when no trace
+// store is configured.
+func (r *ScoreRepository) Traces(submissionID string) ([]models.TraceEvent, error) {
+	if r.traceStore == nil {
+		return nil, nil
+	}
+	return storage.Find(r.traceStore, storage.Query{
+		Where:   []storage.Condition{{Field: "submissionId", Op: storage.Eq, Value: submissionID}},
+		OrderBy: []storage.Order{{Field: "timestamp"}, {Field: "id"}},
+	})
+}
+
+// Submissions returns the stored submissions, or none when no submission store is configured.
+func (r *ScoreRepository) Submissions() []models.Submission {
+	if r.submissionStore == nil {
+		return nil
+	}
+	return r.submissionStore.List()
+}
+
+// SaveConfig stores a scoring config version.
+func (r *ScoreRepository) SaveConfig(config models.ScoringConfig) {
+	r.configStore.Save(strconv.Itoa(config.Version), config)
+}
+
+// GetConfig returns a scoring config version.
+func (r *ScoreRepository) GetConfig(version int) (models.ScoringConfig, bool) {
+	return r.configStore.Get(strconv.Itoa(version))
+}
+
+// Configs returns the scoring config versions, oldest first.
+func (r *ScoreRepository) Configs() []models.ScoringConfig {
+	configs := r.configStore.List()
+	sort.Slice(configs, func(i, j int) bool { return configs[i].Version < configs[j].Version })
+	return configs
+}
@@ -0,0 +1,186 @@
+package service
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/example/back-end-tcc/pkg/formula"
+	"github.com/example/back-end-tcc/pkg/models"
+)
+
+// Trace event types and statuses written by the runner.
+const (
+	traceAgent     = "agent"
+	traceTool      = "tool"
+	traceGuardrail = "guardrail"
+
+	taskStatusPassed           = "passed"
+	taskStatusFailed           = "failed"
+	taskStatusError            = "error"
+	toolStatusInvalidArguments = "invalid_arguments"
+)
+
+// taskStats accumulates the trace events of one task.
+type taskStats struct {
+	turns      int
+	latency    float64
+	cost       float64
+	tokens     int
+	violations int
+	toolsUsed  map[string]bool
+}
+
+// summarize computes a score summary from a submission's task results and trace events under a
+// scoring config. The benchmark supplies the score formula, the expected tools and the grader
+// weights that stored grades are recombined with; tasks without an expected tool are left out of
+// ToolCorrectness.
+//
+//   - Score is composed by the benchmark's formula, by default the mean task score.
+//   - SuccessRate is the fraction of passed tasks.
+//   - ToolCorrectness is the fraction of tasks with an expected tool in which that tool was called,
+//     unset (and dropped from the formula) when no task expects one.
+//   - Violations counts guardrail denials and tool calls rejected for invalid arguments. A task's
+//     free-text Constraints are not checked: only breaches enforced at run time are counted.
+//   - AvgTurns and AvgLatency are per task, counting model turns and model plus tool latency in seconds.
+//   - TotalCost sums the cost of every event, and TotalTokens the tokens of every model turn.
+func summarize(submission models.Submission, benchmark models.Benchmark, traces []models.TraceEvent, config models.ScoringConfig) models.ScoreSummary {
+	summary := models.ScoreSummary{
+		SubmissionID:   submission.ID,
+		AgentID:        submission.AgentID,
+		BenchmarkID:    submission.BenchmarkID,
+		Metrics:        map[string]float64{},
+		Calculated:     time.Now(),
+		SubmittedAt:    submission.SubmittedAt,
+		ScoringVersion: config.Version,
+	}
+	if submission.ScoreSummary != nil {
+		for name, value := range submission.ScoreSummary.Metrics {
+			summary.Metrics[name] = value
+		}
+	}
+	results := submission.TaskResults
+	if len(results) == 0 {
+		// Nothing to recompute from: keep what the runner reported.
+		if submission.ScoreSummary != nil {
+			summary.Score = submission.ScoreSummary.Score
+			summary.SuccessRate = submission.ScoreSummary.SuccessRate
+			summary.Violations = submission.ScoreSummary.Violations
+		}
+		return summary
+	}
+
+	byTask := map[string]*taskStats{}
+	toolCalls, invalidCalls := 0, 0
+	for _, event := range traces {
+		summary.TotalCost += event.Cost
+		st := byTask[event.TaskID]
+		if st == nil {
+			st = &taskStats{toolsUsed: map[string]bool{}}
+			byTask[event.TaskID] = st
+		}
+		st.cost += event.Cost
+		switch event.Type {
+		case traceAgent:
+			st.turns++
+			st.latency += event.Latency
+			st.tokens += tokens(event)
+		case traceTool:
+			st.latency += event.Latency
+			toolCalls++
+			if event.Result["status"] == toolStatusInvalidArguments {
+				// Rejected before running: a violation, not a use of the tool.
+				invalidCalls++
+				st.violations++
+			} else {
+				st.toolsUsed[event.ToolName] = true
+			}
+		case traceGuardrail:
+			if !event.Success {
+				st.violations++
+			}
+		}
+	}
+
+	byID := map[string]models.Task{}
+	for _, task := range benchmark.Tasks {
+		byID[task.ID] = task
+	}
+	var scoreSum, turns, latency float64
+	passed, withTool, toolCorrect := 0, 0, 0
+	for _, result := range results {
+		result = regrade(result, byID[result.TaskID], config)
+		task := models.TaskScore{
+			TaskID:       result.TaskID,
+			Status:       result.Status,
+			Score:        result.Score,
+			ExpectedTool: byID[result.TaskID].ExpectedTool,
+			Grades:       result.Grades,
+		}
+		if st := byTask[result.TaskID]; st != nil {
+			task.Violations = st.violations
+			task.Turns = st.turns
+			task.Cost = st.cost
+			task.Latency = st.latency
+			task.Tokens = st.tokens
+			if task.ExpectedTool != "" {
+				called := st.toolsUsed[task.ExpectedTool]
+				task.ToolCorrect = &called
+			}
+		} else if len(traces) == 0 {
+			task.Violations = result.Violations
+		}
+		if task.ExpectedTool != "" && task.ToolCorrect == nil {
+			called := false
+			task.ToolCorrect = &called
+		}
+
+		scoreSum += task.Score
+		if task.Status == taskStatusPassed {
+			passed++
+		}
+		if task.ToolCorrect != nil {
+			withTool++
+			if *task.ToolCorrect {
+				toolCorrect++
+			}
+		}
+		turns += float64(task.Turns)
+		latency += task.Latency
+		summary.TotalTokens += task.Tokens
+		summary.Violations += task.Violations
+		summary.Tasks = append(summary.Tasks, task)
+	}
+	n := float64(len(results))
+	summary.SuccessRate = float64(passed) / n
+	if withTool > 0 {
+		correctness := float64(toolCorrect) / float64(withTool)
+		summary.ToolCorrectness = &correctness
+		summary.Metrics["tool_correctness"] = correctness
+	} else {
+		// Drop the value an earlier scoring may have stored.
+		delete(summary.Metrics, "tool_correctness")
+	}
+	summary.AvgTurns = turns / n
+	summary.AvgLatency = latency / n
+
+	summary.Metrics["accuracy"] = scoreSum / n
+	summary.Metrics["success_rate"] = summary.SuccessRate
+	if toolCalls > 0 {
+		summary.Metrics["argument_accuracy"] = float64(toolCalls-invalidCalls) / float64(toolCalls)
+	}
+	summary.Formula, _ = formula.Normalize(benchmark.ScoreFormula)
+	if summary.Formula == nil {
+		// Benchmarks are validated when created; fall back for ones stored before formulas existed.
+		summary.Formula = formula.Default()
+	}
+	summary.Score, summary.Components = formula.Compose(summary.Formula, formula.Values(summary))
+	summary.Intervals = intervals(summary)
+	return summary
+}
+
+// tokens returns the prompt and completion tokens an agent event reports.
+func tokens(event models.TraceEvent) int {
+	prompt, _ := strconv.Atoi(event.Result["promptTokens"])
+	completion, _ := strconv.Atoi(event.Result["completionTokens"])
+	return prompt + completion
+}
@@ -0,0 +1,151 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/example/back-end-tcc/pkg/models"
+)
+
+// ErrConfigNotFound is returned for unknown scoring versions.
+var ErrConfigNotFound = errors.New("scoring config not found")
+
+// RescoreRequest selects the stored submissions to rescore. Empty fields match any submission,
+// and Version 0 rescores with the active config.
+type RescoreRequest struct {
+	BenchmarkID string    `json:"benchmark_id"`
+	AgentID     string    `json:"agent_id"`
+	From        time.Time `json:"from"`
+	To          time.Time `json:"to"`
+	Version     int       `json:"version"`
+}
+
+// RescoreResult lists the submissions a rescore recomputed.
+type RescoreResult struct {
+	Version     int      `json:"version"`
+	Submissions []string `json:"submissions"`
+}
+
+// Config returns the active scoring config, the newest version.
+func (s *Service) Config() models.ScoringConfig {
+	configs := s.repo.Configs()
+	return configs[len(configs)-1]
+}
+
+// Configs lists the scoring config versions, oldest first.
+func (s *Service) Configs() []models.ScoringConfig {
+	return s.repo.Configs()
+}
+
+// CreateConfig stores config as the next scoring version, which becomes active. Summaries already
+// stored keep their version until they are rescored.
+func (s *Service) CreateConfig(config models.ScoringConfig) (models.ScoringConfig, error) {
+	for graderType, weight := range config.GraderWeights {
+		if weight < 0 {
+			return models.ScoringConfig{}, fmt.Errorf("grader weight of %q must not be negative", graderType)
+		}
+	}
+	if config.PassThreshold < 0 || config.PassThreshold > 1 {
+		return models.ScoringConfig{}, errors.New("pass threshold must be between 0 and 1")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	config.Version = s.Config().Version + 1
+	config.Created = time.Now()
+	s.repo.SaveConfig(config)
+	if s.log != nil {
+		s.log.Printf("scoring: created scoring config version %d", config.Version)
+	}
+	return config, nil
+}
+
+// Rescore recomputes the summaries of stored submissions from their task results and traces,
+// without running the agents again. Each submission gains a summary under the chosen version,
+// and its score history keeps the summaries of earlier versions.
+func (s *Service) Rescore(ctx context.Context, req RescoreRequest) (RescoreResult, error) {
+	config := s.Config()
+	if req.Version != 0 {
+		var ok bool
+		if config, ok = s.repo.GetConfig(req.Version); !ok {
+			return RescoreResult{}, fmt.Errorf("%w: version %d", ErrConfigNotFound, req.Version)
+		}
+	}
+	var submissions []models.Submission
+	for _, submission := range s.repo.Submissions() {
+		switch {
+		case submission.ScoreSummary == nil && len(submission.TaskResults) == 0:
+		case req.BenchmarkID != "" && submission.BenchmarkID != req.BenchmarkID:
+		case req.AgentID != "" && submission.AgentID != req.AgentID:
+		case !req.From.IsZero() && submission.SubmittedAt.Before(req.From):
+		case !req.To.IsZero() && submission.SubmittedAt.After(req.To):
+		default:
+			submissions = append(submissions, submission)
+		}
+	}
+	sort.SliceStable(submissions, func(i, j int) bool { return submissions[i].SubmittedAt.Before(submissions[j].SubmittedAt) })
+
+	result := RescoreResult{Version: config.Version, Submissions: []string{}}
+	for _, submission := range submissions {
+		start := time.Now()
+		if err := s.score(ctx, submission, config); err != nil {
+			s.observeScore(start, "error")
+			return result, err
+		}
+		s.observeScore(start, "rescored")
+		result.Submissions = append(result.Submissions, submission.ID)
+	}
+	if s.log != nil {
+		s.log.Printf("scoring: rescored %d submissions with scoring version %d", len(result.Submissions), config.Version)
+	}
+	return result, nil
+}
+
+// regrade recombines the stored grades of a task result with the weights and pass threshold in
+// force: the task's graders, overridden by the config. As in the runner, failed verify commands
+// still fail the task. Results without grades, or that errored, are returned unchanged.
+func regrade(result models.TaskResult, task models.Task, config models.ScoringConfig) models.TaskResult {
+	if len(result.Grades) == 0 || result.Status == taskStatusError {
+		return result
+	}
+	threshold := task.PassThreshold
+	if config.PassThreshold > 0 {
+		threshold = config.PassThreshold
+	}
+	if threshold == 0 {
+		threshold = 1
+	}
+	grades := make([]models.Grade, len(result.Grades))
+	var total, weights float64
+	for i, grade := range result.Grades {
+		if i < len(task.Graders) && task.Graders[i].Type == grade.Type {
+			grade.Weight = task.Graders[i].Weight
+			if grade.Weight == 0 {
+				grade.Weight = 1
+			}
+		}
+		if weight, ok := config.GraderWeights[grade.Type]; ok {
+			grade.Weight = weight
+		}
+		grades[i] = grade
+		total += grade.Score * grade.Weight
+		weights += grade.Weight
+	}
+	score := 0.0
+	if weights > 0 {
+		score = total / weights
+	}
+	verified := result.Verified == nil || *result.Verified
+	result.Grades = grades
+	result.Score = 0
+	if verified {
+		result.Score = score
+	}
+	result.Status = taskStatusFailed
+	if verified && score >= threshold-1e-9 {
+		result.Status = taskStatusPassed
+	}
+	return result
+}
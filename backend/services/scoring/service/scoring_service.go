@@ -2,12 +2,15 @@ package service
 
 import (
 	"context"
+	"fmt"
+	"sync"
 	"time"
 
 	"github.com/example/back-end-tcc/pkg/logger"
 	"github.com/example/back-end-tcc/pkg/models"
 	"github.com/example/back-end-tcc/pkg/observability/metrics"
 	"github.com/example/back-end-tcc/pkg/queue"
+	benchrepo "github.com/example/back-end-tcc/services/benchmark/repository"
 	scoringrepo "github.com/example/back-end-tcc/services/scoring/repository"
 )
 
@@ -31,13 +34,23 @@ func WithMetrics(rec metrics.Recorder) Option {
 	}
 }
 
+// WithBenchmarks lets the service look up a submission's benchmark: its score formula, and the
+// expected tools ToolCorrectness needs.
+func WithBenchmarks(repo *benchrepo.BenchmarkRepository) Option {
+	return func(s *Service) {
+		s.benchmarks = repo
+	}
+}
+
 // Service aggregates metrics for submissions.
 type Service struct {
 	repo       *scoringrepo.ScoreRepository
+	benchmarks *benchrepo.BenchmarkRepository
 	subscriber queue.Subscriber
 	publisher  queue.Publisher
 	log        logger.Logger
 	metrics    metrics.Recorder
+	mu         sync.Mutex
 }
 
 // New creates service.
@@ -46,6 +59,9 @@ func New(repo *scoringrepo.ScoreRepository, subscriber queue.Subscriber, publish
 	for _, opt := range opts {
 		opt(s)
 	}
+	if len(repo.Configs()) == 0 {
+		repo.SaveConfig(models.ScoringConfig{Version: 1, Description: "default", Created: time.Now()})
+	}
 	return s
 }
 
@@ -60,20 +76,11 @@ func (s *Service) Start() {
 func (s *Service) handleSubmission(ctx context.Context, msg queue.Message) error {
 	start := time.Now()
 	submission, ok := msg.Data.(models.Submission)
-	if !ok || submission.ScoreSummary == nil {
+	if !ok || (submission.ScoreSummary == nil && len(submission.TaskResults) == 0) {
 		s.observeScore(start, "ignored")
 		return nil
 	}
-	summary := models.ScoreSummary{
-		Score:      submission.ScoreSummary.Score,
-		Metrics:    submission.ScoreSummary.Metrics,
-		Calculated: submission.ScoreSummary.Calculated,
-	}
-	s.repo.Save(submission.ID, summary)
-	if err := s.publisher.Publish(ctx, queue.Message{Type: "leaderboard.updated", Data: summary}); err != nil {
-		if s.log != nil {
-			s.log.Printf("scoring: failed to publish summary for submission %s: %v", submission.ID, err)
-		}
+	if err := s.score(ctx, submission, s.Config()); err != nil {
 		s.observeScore(start, "error")
 		return err
 	}
@@ -84,12 +91,86 @@ func (s *Service) handleSubmission(ctx context.Context, msg queue.Message) error
 	return nil
 }
 
-// Summaries lists stored summaries.
-func (s *Service) Summaries() []models.ScoreSummary {
+// score summarizes a submission under config, stores the summary and announces it to the leaderboard.
+func (s *Service) score(ctx context.Context, submission models.Submission, config models.ScoringConfig) error {
+	traces, err := s.repo.Traces(submission.ID)
+	if err != nil {
+		if s.log != nil {
+			s.log.Printf("scoring: failed to load traces for submission %s: %v", submission.ID, err)
+		}
+		return err
+	}
+	summary := summarize(submission, s.benchmark(submission.BenchmarkID), traces, config)
+	s.repo.Save(summary)
+	if err := s.publisher.Publish(ctx, queue.Message{Type: "leaderboard.updated", Data: summary}); err != nil {
+		if s.log != nil {
+			s.log.Printf("scoring: failed to publish summary for submission %s: %v", submission.ID, err)
+		}
+		return err
+	}
+	return nil
+}
+
+// benchmark returns a benchmark, or a zero one when it is unknown or benchmarks are not configured.
+func (s *Service) benchmark(benchmarkID string) models.Benchmark {
+	if s.benchmarks == nil {
+		return models.Benchmark{}
+	}
+	benchmark, _ := s.benchmarks.Get(benchmarkID)
+	return benchmark
+}
+
+// Summaries lists the newest summary of every submission.
+func (s *Service) Summaries() ([]models.ScoreSummary, error) {
+	return s.Find("", "", 0)
+}
+
+// Find lists the summaries of an agent and/or benchmark, newest first. Empty IDs match any.
+// Version 0 returns the newest summary of each submission, otherwise those of that scoring version.
+func (s *Service) Find(agentID, benchmarkID string, version int) ([]models.ScoreSummary, error) {
+	summaries, err := s.repo.Find(agentID, benchmarkID, version)
 	if s.metrics != nil {
-		s.metrics.AddCounter("scoring_summaries_total", map[string]string{"result": "ok"}, float64(len(s.repo.List())))
+		if err != nil {
+			s.metrics.AddCounter("scoring_summaries_total", map[string]string{"result": "error"}, 1)
+		} else {
+			s.metrics.AddCounter("scoring_summaries_total", map[string]string{"result": "ok"}, float64(len(summaries)))
+		}
+	}
+	return summaries, err
+}
+
+// Compare tests whether submission b scores differently from a on the tasks both ran.
+func (s *Service) Compare(a, b string) (models.Comparison, error) {
+	summaryA, ok, err := s.repo.Get(a)
+	if err != nil {
+		return models.Comparison{}, err
+	}
+	if !ok {
+		return models.Comparison{}, fmt.Errorf("%w: %s", ErrScoreNotFound, a)
+	}
+	summaryB, ok, err := s.repo.Get(b)
+	if err != nil {
+		return models.Comparison{}, err
 	}
-	return s.repo.List()
+	if !ok {
+		return models.Comparison{}, fmt.Errorf("%w: %s", ErrScoreNotFound, b)
+	}
+	return compare(summaryA, summaryB)
+}
+
+// Summary returns the newest summary of a submission.
+func (s *Service) Summary(submissionID string) (models.ScoreSummary, bool, error) {
+	return s.repo.Get(submissionID)
+}
+
+// SummaryVersion returns the summary of a submission under one scoring version.
+func (s *Service) SummaryVersion(submissionID string, version int) (models.ScoreSummary, bool) {
+	return s.repo.GetVersion(submissionID, version)
+}
+
+// History returns the summaries of a submission, oldest scoring version first.
+func (s *Service) History(submissionID string) ([]models.ScoreSummary, error) {
+	return s.repo.History(submissionID)
 }
 
 func (s *Service) observeScore(start time.Time, result string) {
@@ -0,0 +1,104 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/example/back-end-tcc/pkg/models"
+	"github.com/example/back-end-tcc/services/scoring/stats"
+)
+
+// Comparison errors.
+var (
+	ErrScoreNotFound       = errors.New("score not found")
+	ErrDifferentBenchmarks = errors.New("submissions belong to different benchmarks")
+	ErrNoPairedTasks       = errors.New("submissions share no scored tasks")
+)
+
+// intervals bootstraps confidence intervals of the per-task metrics behind a summary. Resampling is
+// seeded by the submission, so recomputing a summary yields the same intervals.
+func intervals(summary models.ScoreSummary) map[string]models.Interval {
+	if len(summary.Tasks) == 0 {
+		return nil
+	}
+	var score, success, toolCorrect, turns, cost, latency []float64
+	for _, task := range summary.Tasks {
+		score = append(score, task.Score)
+		success = append(success, boolValue(task.Status == taskStatusPassed))
+		if task.ToolCorrect != nil {
+			toolCorrect = append(toolCorrect, boolValue(*task.ToolCorrect))
+		}
+		turns = append(turns, float64(task.Turns))
+		cost = append(cost, task.Cost)
+		latency = append(latency, task.Latency)
+	}
+	rng := stats.RNG(summary.SubmissionID)
+	ci := func(values []float64, scale float64) models.Interval {
+		lower, upper := stats.BootstrapCI(values, stats.DefaultLevel, stats.DefaultResamples, rng)
+		return models.Interval{Lower: lower * scale, Upper: upper * scale, Level: stats.DefaultLevel}
+	}
+	out := map[string]models.Interval{
+		"accuracy":    ci(score, 1),
+		"successRate": ci(success, 1),
+		"avgTurns":    ci(turns, 1),
+		// Total cost scales the mean task cost by the number of tasks.
+		"totalCost":  ci(cost, float64(len(cost))),
+		"avgLatency": ci(latency, 1),
+	}
+	if len(toolCorrect) > 0 {
+		out["toolCorrectness"] = ci(toolCorrect, 1)
+	}
+	return out
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// compare runs paired tests over the tasks two summaries share.
+func compare(a, b models.ScoreSummary) (models.Comparison, error) {
+	if a.BenchmarkID != b.BenchmarkID {
+		return models.Comparison{}, ErrDifferentBenchmarks
+	}
+	tasksA := make(map[string]models.TaskScore, len(a.Tasks))
+	for _, task := range a.Tasks {
+		tasksA[task.TaskID] = task
+	}
+	cmp := models.Comparison{SubmissionA: a.SubmissionID, SubmissionB: b.SubmissionID, BenchmarkID: a.BenchmarkID}
+	var scoresA, scoresB, diffs []float64
+	for _, taskB := range b.Tasks {
+		taskA, ok := tasksA[taskB.TaskID]
+		if !ok {
+			continue
+		}
+		scoresA = append(scoresA, taskA.Score)
+		scoresB = append(scoresB, taskB.Score)
+		diffs = append(diffs, taskB.Score-taskA.Score)
+		passedA, passedB := taskA.Status == taskStatusPassed, taskB.Status == taskStatusPassed
+		switch {
+		case passedA && !passedB:
+			cmp.OnlyAPassed++
+		case passedB && !passedA:
+			cmp.OnlyBPassed++
+		}
+	}
+	if len(diffs) == 0 {
+		return models.Comparison{}, ErrNoPairedTasks
+	}
+	cmp.PairedTasks = len(diffs)
+	cmp.ScoreA = stats.Mean(scoresA)
+	cmp.ScoreB = stats.Mean(scoresB)
+	cmp.ScoreDiff = stats.Mean(diffs)
+	rng := stats.RNG(fmt.Sprintf("%s/%s", a.SubmissionID, b.SubmissionID))
+	lower, upper, p := stats.PairedBootstrap(diffs, stats.DefaultLevel, stats.DefaultResamples, rng)
+	cmp.DiffInterval = models.Interval{Lower: lower, Upper: upper, Level: stats.DefaultLevel}
+	cmp.PValue = p
+	if d, ok := stats.CohensDz(diffs); ok {
+		cmp.EffectSize = &d
+	}
+	cmp.McNemarPValue = stats.McNemar(cmp.OnlyAPassed, cmp.OnlyBPassed)
+	return cmp, nil
+}
@@ -0,0 +1,138 @@
+// Package stats provides the resampling and hypothesis tests used to tell score differences from noise.
+package stats
+
+import (
+	"hash/fnv"
+	"math"
+	"math/rand/v2"
+	"sort"
+)
+
+// Defaults of the bootstrap procedures.
+const (
+	DefaultResamples = 2000
+	DefaultLevel     = 0.95
+)
+
+// RNG returns a generator seeded from key, so repeated computations over the same data agree.
+func RNG(key string) *rand.Rand {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	seed := h.Sum64()
+	return rand.New(rand.NewPCG(seed, seed^0x9e3779b97f4a7c15))
+}
+
+// Mean returns the arithmetic mean, or 0 for no values.
+func Mean(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
+
+// StdDev returns the sample standard deviation, or 0 for fewer than two values.
+func StdDev(values []float64) float64 {
+	if len(values) < 2 {
+		return 0
+	}
+	mean := Mean(values)
+	sum := 0.0
+	for _, v := range values {
+		sum += (v - mean) * (v - mean)
+	}
+	return math.Sqrt(sum / float64(len(values)-1))
+}
+
+// bootstrapMeans returns the means of resamples drawn with replacement from values, sorted.
+func bootstrapMeans(values []float64, resamples int, rng *rand.Rand) []float64 {
+	means := make([]float64, resamples)
+	for i := range means {
+		sum := 0.0
+		for range values {
+			sum += values[rng.IntN(len(values))]
+		}
+		means[i] = sum / float64(len(values))
+	}
+	sort.Float64s(means)
+	return means
+}
+
+// percentile returns the p-quantile of sorted values by linear interpolation.
+func percentile(sorted []float64, p float64) float64 {
+	pos := p * float64(len(sorted)-1)
+	lo := int(math.Floor(pos))
+	hi := int(math.Ceil(pos))
+	return sorted[lo] + (sorted[hi]-sorted[lo])*(pos-float64(lo))
+}
+
+// BootstrapCI returns the percentile bootstrap confidence interval of the mean of values.
+// With fewer than two values the interval collapses to the mean.
+func BootstrapCI(values []float64, level float64, resamples int, rng *rand.Rand) (lower, upper float64) {
+	if len(values) < 2 {
+		m := Mean(values)
+		return m, m
+	}
+	means := bootstrapMeans(values, resamples, rng)
+	alpha := (1 - level) / 2
+	return percentile(means, alpha), percentile(means, 1-alpha)
+}
+
+// PairedBootstrap tests whether the mean of paired differences is zero. It returns the confidence
+// interval of the mean difference and a two-sided p-value: twice the share of resampled means on
+// the far side of zero.
+func PairedBootstrap(diffs []float64, level float64, resamples int, rng *rand.Rand) (lower, upper, p float64) {
+	if len(diffs) < 2 {
+		m := Mean(diffs)
+		return m, m, 1
+	}
+	means := bootstrapMeans(diffs, resamples, rng)
+	alpha := (1 - level) / 2
+	below, above := 0, 0
+	for _, m := range means {
+		if m <= 0 {
+			below++
+		}
+		if m >= 0 {
+			above++
+		}
+	}
+	p = 2 * float64(min(below, above)) / float64(len(means))
+	return percentile(means, alpha), percentile(means, 1-alpha), min(p, 1)
+}
+
+// McNemar runs the exact McNemar test on discordant pairs: b pairs where only the first system
+// succeeded and c where only the second did. It returns the two-sided p-value.
+func McNemar(b, c int) float64 {
+	n := b + c
+	if n == 0 {
+		return 1
+	}
+	k := min(b, c)
+	// Two-sided exact binomial test with p = 0.5, summed in log space to avoid overflow.
+	p := 0.0
+	for i := 0; i <= k; i++ {
+		p += math.Exp(logChoose(n, i) - float64(n)*math.Ln2)
+	}
+	return min(2*p, 1)
+}
+
+func logChoose(n, k int) float64 {
+	a, _ := math.Lgamma(float64(n + 1))
+	b, _ := math.Lgamma(float64(k + 1))
+	c, _ := math.Lgamma(float64(n - k + 1))
+	return a - b - c
+}
+
+// CohensDz is the standardized mean of paired differences. ok is false when the differences do
+// not vary, which leaves the effect size undefined.
+func CohensDz(diffs []float64) (d float64, ok bool) {
+	sd := StdDev(diffs)
+	if sd == 0 {
+		return 0, false
+	}
+	return Mean(diffs) / sd, true
+}
@@ -1,8 +1,13 @@
 package handlers
 
 import (
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	pkghttp "github.com/example/back-end-tcc/pkg/http"
 	"github.com/example/back-end-tcc/services/trace/service"
@@ -33,7 +38,100 @@ func (h *HTTP) Record(w http.ResponseWriter, r *http.Request) {
 	pkghttp.JSON(w, http.StatusCreated, event)
 }
 
-// List returns stored events.
+// List returns a page of events filtered by ?submission_id=, ?task_id=, ?type=, ?tool_name=,
+// ?level=, ?success= and the RFC 3339 ?from= and ?to=, ordered by timestamp (?order=asc|desc).
+// ?limit= sizes the page and the X-Next-Cursor header carries the ?cursor= of the next one.
 func (h *HTTP) List(w http.ResponseWriter, r *http.Request) {
-	pkghttp.JSON(w, http.StatusOK, h.service.Events())
+	query := r.URL.Query()
+	q := service.Query{
+		SubmissionID: query.Get("submission_id"),
+		TaskID:       query.Get("task_id"),
+		Type:         query.Get("type"),
+		ToolName:     query.Get("tool_name"),
+		Level:        query.Get("level"),
+		Order:        query.Get("order"),
+	}
+	if raw := query.Get("success"); raw != "" {
+		success, err := strconv.ParseBool(raw)
+		if err != nil {
+			pkghttp.Error(w, http.StatusBadRequest, "success must be true or false")
+			return
+		}
+		q.Success = &success
+	}
+	for name, dst := range map[string]*time.Time{"from": &q.From, "to": &q.To} {
+		if raw := query.Get(name); raw != "" {
+			t, err := time.Parse(time.RFC3339, raw)
+			if err != nil {
+				pkghttp.Error(w, http.StatusBadRequest, name+" must be an RFC 3339 timestamp")
+				return
+			}
+			*dst = t
+		}
+	}
+	if raw := query.Get("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit < 1 {
+			pkghttp.Error(w, http.StatusBadRequest, "limit must be a positive integer")
+			return
+		}
+		q.Limit = limit
+	}
+	if raw := query.Get("cursor"); raw != "" {
+		cursor, err := decodeCursor(raw)
+		if err != nil {
+			pkghttp.Error(w, http.StatusBadRequest, "invalid cursor")
+			return
+		}
+		q.After = &cursor
+	}
+	page, err := h.service.Query(q)
+	switch {
+	case errors.Is(err, service.ErrInvalidQuery):
+		pkghttp.Error(w, http.StatusBadRequest, err.Error())
+		return
+	case err != nil:
+		pkghttp.Error(w, http.StatusInternalServerError, "failed to query traces")
+		return
+	}
+	if page.Next != nil {
+		w.Header().Set("X-Next-Cursor", encodeCursor(*page.Next))
+	}
+	pkghttp.JSON(w, http.StatusOK, page.Events)
+}
+
+// Conversation returns the events of the submission in the {id} path segment, oldest first,
+// optionally of one ?task_id= only.
+func (h *HTTP) Conversation(w http.ResponseWriter, r *http.Request) {
+	events, err := h.service.Conversation(r.PathValue("id"), r.URL.Query().Get("task_id"))
+	if err != nil {
+		pkghttp.Error(w, http.StatusInternalServerError, "failed to query traces")
+		return
+	}
+	if len(events) == 0 {
+		pkghttp.Error(w, http.StatusNotFound, "trace not found")
+		return
+	}
+	pkghttp.JSON(w, http.StatusOK, events)
+}
+
+// Cursors are opaque to clients: the timestamp and ID of the last event of a page.
+func encodeCursor(c service.Cursor) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(c.Timestamp.UnixNano(), 10) + ":" + c.ID))
+}
+
+func decodeCursor(raw string) (service.Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return service.Cursor{}, err
+	}
+	nanos, id, ok := strings.Cut(string(data), ":")
+	if !ok {
+		return service.Cursor{}, errors.New("malformed cursor")
+	}
+	n, err := strconv.ParseInt(nanos, 10, 64)
+	if err != nil {
+		return service.Cursor{}, err
+	}
+	return service.Cursor{Timestamp: time.Unix(0, n), ID: id}, nil
 }
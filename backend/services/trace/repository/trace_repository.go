@@ -24,3 +24,8 @@ func (r *Repository) Save(event models.TraceEvent) {
 func (r *Repository) List() []models.TraceEvent {
 	return r.store.List()
 }
+
+// Find returns the events q selects, filtered and paged in storage when the store supports it.
+func (r *Repository) Find(q storage.Query) ([]models.TraceEvent, error) {
+	return storage.Find(r.store, q)
+}
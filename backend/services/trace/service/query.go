@@ -0,0 +1,119 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/example/back-end-tcc/pkg/models"
+	"github.com/example/back-end-tcc/pkg/storage"
+)
+
+// Query limits.
+const (
+	DefaultLimit = 100
+	MaxLimit     = 1000
+)
+
+// ErrInvalidQuery is returned for queries with an unknown order or an out of range limit.
+var ErrInvalidQuery = errors.New("invalid trace query")
+
+// Cursor marks the last event of a page: events sort by timestamp, then ID.
+type Cursor struct {
+	Timestamp time.Time
+	ID        string
+}
+
+// Query selects trace events. Empty fields match any event; From is inclusive and To exclusive.
+type Query struct {
+	SubmissionID string
+	TaskID       string
+	Type         string
+	ToolName     string
+	Level        string
+	Success      *bool
+	From         time.Time
+	To           time.Time
+	Order        string // asc (default, oldest first) or desc
+	Limit        int    // 0 uses DefaultLimit
+	After        *Cursor
+}
+
+// Page is a page of events; Next is set when more events follow.
+type Page struct {
+	Events []models.TraceEvent
+	Next   *Cursor
+}
+
+// Query returns a page of the events q selects, filtered, sorted and paged by the storage.
+func (s *Service) Query(q Query) (Page, error) {
+	if q.Order != "" && q.Order != "asc" && q.Order != "desc" {
+		return Page{}, fmt.Errorf("%w: order must be asc or desc", ErrInvalidQuery)
+	}
+	if q.Limit == 0 {
+		q.Limit = DefaultLimit
+	}
+	if q.Limit < 0 || q.Limit > MaxLimit {
+		return Page{}, fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidQuery, MaxLimit)
+	}
+	sq := storage.Query{Where: conditions(q), OrderBy: order(q.Order == "desc"), Limit: q.Limit + 1}
+	if q.After != nil {
+		sq.After = []any{q.After.Timestamp, q.After.ID}
+	}
+	events, err := s.repo.Find(sq)
+	s.observeQuery(err)
+	if err != nil {
+		return Page{}, err
+	}
+	page := Page{Events: events}
+	if len(events) > q.Limit {
+		page.Events = events[:q.Limit]
+		last := page.Events[q.Limit-1]
+		page.Next = &Cursor{Timestamp: last.Timestamp, ID: last.ID}
+	}
+	if page.Events == nil {
+		page.Events = []models.TraceEvent{}
+	}
+	return page, nil
+}
+
+// Conversation returns every event of a submission, or of one of its tasks, oldest first.
+func (s *Service) Conversation(submissionID, taskID string) ([]models.TraceEvent, error) {
+	events, err := s.repo.Find(storage.Query{Where: conditions(Query{SubmissionID: submissionID, TaskID: taskID}), OrderBy: order(false)})
+	s.observeQuery(err)
+	return events, err
+}
+
+func conditions(q Query) []storage.Condition {
+	var where []storage.Condition
+	for _, eq := range [][2]string{{"submissionId", q.SubmissionID}, {"taskId", q.TaskID}, {"type", q.Type}, {"toolName", q.ToolName}, {"level", q.Level}} {
+		if eq[1] != "" {
+			where = append(where, storage.Condition{Field: eq[0], Op: storage.Eq, Value: eq[1]})
+		}
+	}
+	if q.Success != nil {
+		where = append(where, storage.Condition{Field: "success", Op: storage.Eq, Value: *q.Success})
+	}
+	if !q.From.IsZero() {
+		where = append(where, storage.Condition{Field: "timestamp", Op: storage.Gte, Value: q.From})
+	}
+	if !q.To.IsZero() {
+		where = append(where, storage.Condition{Field: "timestamp", Op: storage.Lt, Value: q.To})
+	}
+	return where
+}
+
+func order(desc bool) []storage.Order {
+	return []storage.Order{{Field: "timestamp", Desc: desc}, {Field: "id", Desc: desc}}
+}
+
+func (s *Service) observeQuery(err error) {
+	if s.metrics == nil {
+		return
+	}
+	result := "ok"
+	if err != nil {
+		result = "error"
+	}
+	s.metrics.AddCounter("trace_events_list_total", map[string]string{"result": result}, 1)
+}
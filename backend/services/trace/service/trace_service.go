@@ -74,14 +74,6 @@ func (s *Service) Record(ctx context.Context, submissionID, message, level strin
 	return event
 }
 
-// Events returns stored traces.
-func (s *Service) Events() []models.TraceEvent {
-	if s.metrics != nil {
-		s.metrics.AddCounter("trace_events_list_total", map[string]string{"result": "ok"}, 1)
-	}
-	return s.repo.List()
-}
-
 func (s *Service) observe(result string, start time.Time) {
 	if s.metrics == nil {
 		return
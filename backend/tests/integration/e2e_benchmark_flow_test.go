@@ -7,6 +7,8 @@ import (
 
 	"github.com/example/back-end-tcc/pkg/models"
 	"github.com/example/back-end-tcc/pkg/queue"
+	"github.com/example/back-end-tcc/pkg/sandbox"
+	"github.com/example/back-end-tcc/pkg/sandbox/sandboxtest"
 	"github.com/example/back-end-tcc/pkg/storage"
 	agentrepository "github.com/example/back-end-tcc/services/agent/repository"
 	benchmarkrepository "github.com/example/back-end-tcc/services/benchmark/repository"
@@ -25,18 +27,35 @@ func TestEndToEndBenchmarkFlow(t *testing.T) {
 	orchestratorRepo := orchestratorrepository.New(submissionStore)
 	orchestratorSvc := orchestratorservice.New(orchestratorRepo, bus)
 
-	runnerRepo := runnerrepository.New(storage.NewMemoryRepository[models.Submission]())
+	traceStore := storage.NewMemoryRepository[models.TraceEvent]()
+	runnerRepo := runnerrepository.New(storage.NewMemoryRepository[models.Submission](), traceStore)
 	agentRepo := agentrepository.NewAgentRepository(storage.NewMemoryRepository[models.User]())
-	agentRepo.Save(models.User{ID: "agent", Name: "Test Agent"})
+	agentRepo.Save(models.User{ID: "agent", Name: "Test Agent", Model: "mock"})
 
 	benchmarkRepo := benchmarkrepository.New(storage.NewMemoryRepository[models.Benchmark]())
-	benchmarkRepo.Save(models.Benchmark{ID: "bench", Name: "Test Benchmark", Tasks: []models.Task{{Prompt: "Test Prompt"}}})
+	benchmarkRepo.Save(models.Benchmark{
+		ID:   "bench",
+		Name: "Test Benchmark",
+		Tasks: []models.Task{{
+			Prompt: "Test Prompt",
+			Graders: []models.Grader{
+				{Type: "regex", Pattern: "completed the task"},
+				{Type: "command", Command: "ls build"},
+			},
+		}},
+		Environment: &models.Environment{
+			Setup:  []string{"mkdir -p build"},
+			Verify: []string{"ls build"},
+		},
+	})
 
-	runnerSvc := runnerservice.New(runnerRepo, agentRepo, benchmarkRepo, bus, bus)
+	var sandboxes []*sandboxtest.Fake
+	provider := sandbox.NewDirectProvider(sandboxtest.Factory(func(f *sandboxtest.Fake) { sandboxes = append(sandboxes, f) }))
+	runnerSvc := runnerservice.New(runnerRepo, agentRepo, benchmarkRepo, bus, bus, runnerservice.WithSandboxProvider(provider))
 	runnerSvc.Start()
 
-	scoringRepo := scoringrepository.New(storage.NewMemoryRepository[models.ScoreSummary]())
-	scoringSvc := scoringservice.New(scoringRepo, bus, bus)
+	scoringRepo := scoringrepository.New(storage.NewMemoryRepository[models.ScoreSummary](), traceStore)
+	scoringSvc := scoringservice.New(scoringRepo, bus, bus, scoringservice.WithBenchmarks(benchmarkRepo))
 	scoringSvc.Start()
 
 	if _, err := orchestratorSvc.Submit(context.Background(), "bench", "agent", "payload"); err != nil {
@@ -45,15 +64,29 @@ func TestEndToEndBenchmarkFlow(t *testing.T) {
 
 	// allow asynchronous handlers to run synchronously by reusing same goroutine
 	// (handlers execute inline in the in-memory bus).
-	if len(runnerSvc.Results()) == 0 {
+	results := runnerSvc.Results()
+	if len(results) == 0 {
 		t.Fatal("expected runner results to be available")
 	}
+	if tasks := results[0].TaskResults; len(tasks) != 1 || tasks[0].Status != "passed" || len(tasks[0].Grades) != 2 {
+		t.Fatalf("expected the task to pass verification and both graders, got %+v", tasks)
+	}
+	if changes := results[0].TaskResults[0].Changes; changes == nil || len(changes.Added)+len(changes.Modified)+len(changes.Deleted) != 0 {
+		t.Fatalf("expected an empty workdir change set for the mock agent, got %+v", changes)
+	}
+	if len(sandboxes) != 1 || !sandboxes[0].Stopped() {
+		t.Fatalf("expected one sandbox to be used and stopped")
+	}
+	sandboxes[0].AssertExecuted(t, "ls build")
 
 	eventually := time.After(10 * time.Millisecond)
 	<-eventually
 
-	summaries := scoringSvc.Summaries()
-	if len(summaries) != 1 {
-		t.Fatalf("expected 1 summary, got %d", len(summaries))
+	summaries, err := scoringSvc.Summaries()
+	if err != nil || len(summaries) != 1 {
+		t.Fatalf("expected 1 summary, got %d (err %v)", len(summaries), err)
+	}
+	if summary := summaries[0]; summary.Score != 1 || summary.SuccessRate != 1 || summary.AvgTurns < 1 || summary.Violations != 0 {
+		t.Fatalf("expected a passing summary computed from traces, got %+v", summary)
 	}
 }
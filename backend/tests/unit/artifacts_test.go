@@ -0,0 +1,135 @@
+package unit
+
+import (
+	"archive/tar"
+	"bytes"
+	"io"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/example/back-end-tcc/pkg/artifacts"
+	"github.com/example/back-end-tcc/pkg/models"
+	"github.com/example/back-end-tcc/pkg/sandbox"
+	"github.com/example/back-end-tcc/pkg/sandbox/sandboxtest"
+)
+
+func snapshot(t *testing.T, sb sandbox.Sandbox) *artifacts.Snapshot {
+	t.Helper()
+	snap, err := artifacts.TakeSnapshot(sb, sb.Workdir())
+	if err != nil {
+		t.Fatalf("snapshot: %v", err)
+	}
+	return snap
+}
+
+func TestWorkdirDiffReportsChangesAsApplicablePatch(t *testing.T) {
+	fake := sandboxtest.NewFake()
+	lines := "one\ntwo\nthree\nfour\nfive\nsix\nseven\neight\nnine\nten\n"
+	fake.SetFile("src/main.txt", lines)
+	fake.SetFile("obsolete.txt", "bye\n")
+	before := snapshot(t, fake)
+
+	fake.SetFile("src/main.txt", strings.Replace(strings.Replace(lines, "two", "TWO", 1), "nine\n", "nine\nnine and a half\n", 1))
+	fake.SetFile("src/new.txt", "fresh")
+	fake.SetFile("image.bin", "\x00\x01")
+	runTool(t, fake, "run_command", `{"command": "rm obsolete.txt"}`)
+
+	changes, patch := artifacts.Diff(before, snapshot(t, fake))
+	want := models.WorkdirChanges{Added: []string{"image.bin", "src/new.txt"}, Modified: []string{"src/main.txt"}, Deleted: []string{"obsolete.txt"}}
+	if strings.Join(changes.Added, ",") != strings.Join(want.Added, ",") ||
+		strings.Join(changes.Modified, ",") != strings.Join(want.Modified, ",") ||
+		strings.Join(changes.Deleted, ",") != strings.Join(want.Deleted, ",") {
+		t.Fatalf("unexpected changes: %+v", changes)
+	}
+	for _, fragment := range []string{
+		"Binary files /dev/null and b/image.bin differ\n",
+		"--- a/src/main.txt\n+++ b/src/main.txt\n@@ -1,5 +1,5 @@\n one\n-two\n+TWO\n",
+		"@@ -7,4 +7,5 @@\n seven\n eight\n nine\n+nine and a half\n ten\n",
+		"--- /dev/null\n+++ b/src/new.txt\n@@ -0,0 +1,1 @@\n+fresh\n\\ No newline at end of file\n",
+		"--- a/obsolete.txt\n+++ /dev/null\n@@ -1,1 +0,0 @@\n-bye\n",
+	} {
+		if !strings.Contains(patch, fragment) {
+			t.Errorf("expected patch to contain %q, got:\n%s", fragment, patch)
+		}
+	}
+
+	// The text part of the diff applies cleanly to the original workdir.
+	original := sandboxtest.NewFake()
+	original.SetFile("src/main.txt", lines)
+	original.SetFile("obsolete.txt", "bye\n")
+	textPatch := patch[strings.Index(patch, "--- a/obsolete.txt"):]
+	if out := runTool(t, original, "apply_patch", `{"patch": `+strconv.Quote(textPatch)+`}`); !strings.HasPrefix(out, "Patch applied:") {
+		t.Fatalf("patch did not apply: %s", out)
+	}
+	if got, _ := original.File("src/main.txt"); got != strings.Replace(strings.Replace(lines, "two", "TWO", 1), "nine\n", "nine\nnine and a half\n", 1) {
+		t.Fatalf("unexpected patched content: %q", got)
+	}
+}
+
+func TestArchiveCollectsSelectedPaths(t *testing.T) {
+	fake := sandboxtest.NewFake()
+	fake.SetFile("out/report.json", "{}")
+	fake.SetFile("out/logs/run.log", "ok")
+	fake.SetFile("scratch.txt", "ignored")
+
+	data, missing, err := artifacts.Archive(fake, fake.Workdir(), []string{"out", "missing.txt"})
+	if err != nil {
+		t.Fatalf("archive: %v", err)
+	}
+	if len(missing) != 1 || missing[0] != "missing.txt" {
+		t.Fatalf("expected missing path reported, got %v", missing)
+	}
+	var files []string
+	tr := tar.NewReader(bytes.NewReader(data))
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("read archive: %v", err)
+		}
+		if header.Typeflag == tar.TypeReg {
+			files = append(files, header.Name)
+		}
+	}
+	if strings.Join(files, ",") != "out/logs/run.log,out/report.json" {
+		t.Fatalf("unexpected archive entries: %v", files)
+	}
+}
+
+func TestArtifactStoresRoundTrip(t *testing.T) {
+	fileStore, err := artifacts.NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("file store: %v", err)
+	}
+	for name, store := range map[string]artifacts.Store{"memory": artifacts.NewMemoryStore(), "file": fileStore} {
+		t.Run(name, func(t *testing.T) {
+			meta, err := store.Put(models.Artifact{SubmissionID: "sub-1", TaskID: "t1", Kind: artifacts.KindDiff, Name: "workdir.diff"}, []byte("+hello\n"))
+			if err != nil {
+				t.Fatalf("put: %v", err)
+			}
+			if meta.ID == "" || meta.Size != 7 || len(meta.SHA256) != 64 {
+				t.Fatalf("expected completed metadata, got %+v", meta)
+			}
+			store.Put(models.Artifact{SubmissionID: "sub-2", Kind: artifacts.KindDiff}, nil)
+
+			if list := store.List("sub-1"); len(list) != 1 || list[0].ID != meta.ID {
+				t.Fatalf("expected one artifact for sub-1, got %+v", list)
+			}
+			content, err := store.Open(meta.ID)
+			if err != nil {
+				t.Fatalf("open: %v", err)
+			}
+			data, _ := io.ReadAll(content)
+			content.Close()
+			if string(data) != "+hello\n" {
+				t.Fatalf("unexpected content %q", data)
+			}
+			if _, err := store.Get("../etc/passwd"); err != artifacts.ErrNotFound {
+				t.Fatalf("expected ErrNotFound, got %v", err)
+			}
+		})
+	}
+}
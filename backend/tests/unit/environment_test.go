@@ -0,0 +1,108 @@
+package unit
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/example/back-end-tcc/pkg/environment"
+	"github.com/example/back-end-tcc/pkg/models"
+	"github.com/example/back-end-tcc/pkg/sandbox"
+	"github.com/example/back-end-tcc/pkg/sandbox/sandboxtest"
+)
+
+func TestEnvironmentMergeLetsTasksExtendTheBenchmark(t *testing.T) {
+	base := &models.Environment{
+		Image:    "python:3.11-slim",
+		Env:      map[string]string{"MODE": "bench", "SHARED": "1"},
+		Setup:    []string{"pip list"},
+		Teardown: []string{"echo bench-teardown"},
+	}
+	task := &models.Environment{
+		Image:    "node:20-slim",
+		Env:      map[string]string{"MODE": "task"},
+		Setup:    []string{"npm ci"},
+		Verify:   []string{"npm test"},
+		Teardown: []string{"echo task-teardown"},
+	}
+
+	merged := environment.Merge(base, task)
+	if merged.Image != "node:20-slim" {
+		t.Errorf("expected task image to win, got %q", merged.Image)
+	}
+	if merged.Env["MODE"] != "task" || merged.Env["SHARED"] != "1" {
+		t.Errorf("unexpected env: %v", merged.Env)
+	}
+	if strings.Join(merged.Setup, ";") != "pip list;npm ci" {
+		t.Errorf("expected benchmark setup first, got %v", merged.Setup)
+	}
+	if strings.Join(merged.Teardown, ";") != "echo task-teardown;echo bench-teardown" {
+		t.Errorf("expected task teardown first, got %v", merged.Teardown)
+	}
+	if base.Env["MODE"] != "bench" {
+		t.Errorf("merge must not modify its inputs")
+	}
+}
+
+func TestEnvironmentValidateRejectsBadDefinitions(t *testing.T) {
+	bad := []*models.Environment{
+		{Env: map[string]string{"BAD-NAME": "x"}},
+		{Fixtures: []models.Fixture{{Content: "x"}}},
+		{Fixtures: []models.Fixture{{Path: "a", Content: "x", Source: "b"}}},
+		{Fixtures: []models.Fixture{{Path: "a", Source: "../../etc/passwd"}}},
+		{Setup: []string{"  "}},
+		{TimeoutSeconds: -1},
+	}
+	for _, env := range bad {
+		if err := environment.Validate(env); err == nil {
+			t.Errorf("expected error for %+v", env)
+		}
+	}
+	ok := &models.Environment{Fixtures: []models.Fixture{{Path: "repo", Source: "repos/sample"}}}
+	if err := environment.Validate(ok); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestEnvironmentPrepareAndVerify(t *testing.T) {
+	fake := sandboxtest.NewFake()
+	fake.On("make deps", sandbox.ExecResult{})
+	fake.On("make test", sandbox.ExecResult{})
+	fake.On("make check", sandbox.ExecResult{ExitCode: 2})
+	env := models.Environment{
+		Env:      map[string]string{"DATASET": "small"},
+		Fixtures: []models.Fixture{{Path: "data/input.csv", Content: "a,b\n"}},
+		Setup:    []string{"make deps"},
+		Verify:   []string{"make test", "make check"},
+	}
+	sb := sandbox.WithEnv(fake, env.Env)
+
+	steps, err := environment.NewPreparer("").Prepare(context.Background(), sb, env)
+	if err != nil {
+		t.Fatalf("prepare: %v", err)
+	}
+	if len(steps) != 1 || !steps[0].OK() {
+		t.Fatalf("expected one successful setup step, got %+v", steps)
+	}
+	if content, ok := fake.File("data/input.csv"); !ok || content != "a,b\n" {
+		t.Errorf("expected fixture copied into the workdir, got %v", fake.Files())
+	}
+	calls := fake.Calls()
+	if got := calls[len(calls)-1].Opts.Env; len(got) != 1 || got[0] != "DATASET=small" {
+		t.Errorf("expected env injected into setup, got %v", got)
+	}
+
+	steps, passed := environment.Verify(context.Background(), sb, env)
+	if passed || len(steps) != 2 || !steps[0].OK() || steps[1].Result.ExitCode != 2 {
+		t.Fatalf("expected second verify command to fail, got passed=%v steps=%+v", passed, steps)
+	}
+
+	fake.On("make deps", sandbox.ExecResult{ExitCode: 1})
+	if _, err := environment.NewPreparer("").Prepare(context.Background(), sb, env); err == nil {
+		t.Fatal("expected failing setup to abort preparation")
+	}
+	src := models.Environment{Fixtures: []models.Fixture{{Path: "repo", Source: "repos/sample"}}}
+	if _, err := environment.NewPreparer("").Prepare(context.Background(), sb, src); err == nil {
+		t.Fatal("expected fixture sources to require a fixtures directory")
+	}
+}
@@ -0,0 +1,55 @@
+package unit
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/example/back-end-tcc/pkg/export"
+	"github.com/example/back-end-tcc/pkg/models"
+)
+
+func TestExportFormats(t *testing.T) {
+	perDollar := 0.45
+	entries := []models.LeaderboardEntry{
+		{Rank: 1, AgentID: "a1", AgentName: "Agent, One", Score: 0.9, TotalCost: 2, ScorePerDollar: &perDollar, TaskScores: map[string]float64{"t1": 1}},
+		{Rank: 2, AgentID: "a2", AgentName: "pipe|agent", Score: 0.5},
+	}
+	columns := []string{"rank", "agentName", "score", "scorePerDollar", "tasks.t1"}
+	render := func(format export.Format) string {
+		var b bytes.Buffer
+		if err := export.WriteLeaderboard(&b, format, columns, entries); err != nil {
+			t.Fatalf("%s export error: %v", format, err)
+		}
+		return b.String()
+	}
+
+	if got, want := render(export.CSV), "rank,agentName,score,scorePerDollar,tasks.t1\n1,\"Agent, One\",0.9,0.45,1\n2,pipe|agent,0.5,,\n"; got != want {
+		t.Errorf("csv:\n%s\nwant:\n%s", got, want)
+	}
+	if got, want := render(export.Markdown), "| rank | agentName | score | scorePerDollar | tasks.t1 |\n| ---: | --- | ---: | ---: | ---: |\n| 1 | Agent, One | 0.9 | 0.45 | 1 |\n| 2 | pipe\\|agent | 0.5 |  |  |\n"; got != want {
+		t.Errorf("markdown:\n%s\nwant:\n%s", got, want)
+	}
+	if got, want := render(export.JSONL), "{\"rank\":1,\"agentName\":\"Agent, One\",\"score\":0.9,\"scorePerDollar\":0.45,\"tasks.t1\":1}\n{\"rank\":2,\"agentName\":\"pipe|agent\",\"score\":0.5,\"scorePerDollar\":null,\"tasks.t1\":null}\n"; got != want {
+		t.Errorf("jsonl:\n%s\nwant:\n%s", got, want)
+	}
+
+	summaries := []models.ScoreSummary{{SubmissionID: "s1", Tasks: []models.TaskScore{
+		{TaskID: "t1", Status: "passed", Score: 1, Grades: []models.Grade{{Type: "exact", Score: 1}}},
+		{TaskID: "t2", Status: "failed"},
+	}}}
+	var b bytes.Buffer
+	if err := export.WriteTasks(&b, export.CSV, []string{"submissionId", "taskId", "grades.exact"}, summaries); err != nil {
+		t.Fatalf("tasks export error: %v", err)
+	}
+	if got, want := b.String(), "submissionId,taskId,grades.exact\ns1,t1,1\ns1,t2,\n"; got != want {
+		t.Errorf("tasks csv:\n%s\nwant:\n%s", got, want)
+	}
+
+	if err := export.WriteSummaries(&b, export.CSV, []string{"score", "secret"}, summaries); !errors.Is(err, export.ErrUnknownColumn) {
+		t.Errorf("expected an unknown column error, got %v", err)
+	}
+	if _, err := export.ParseFormat("xlsx"); !errors.Is(err, export.ErrUnknownFormat) {
+		t.Errorf("expected an unknown format error, got %v", err)
+	}
+}
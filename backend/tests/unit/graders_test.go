@@ -0,0 +1,93 @@
+package unit
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/example/back-end-tcc/pkg/graders"
+	"github.com/example/back-end-tcc/pkg/models"
+	"github.com/example/back-end-tcc/pkg/sandbox"
+	"github.com/example/back-end-tcc/pkg/sandbox/sandboxtest"
+)
+
+type fixedJudge struct {
+	got graders.JudgeRequest
+}
+
+func (j *fixedJudge) Judge(ctx context.Context, req graders.JudgeRequest) (graders.Result, error) {
+	j.got = req
+	return graders.Result{Score: 0.7, Detail: "mostly right"}, nil
+}
+
+func TestBuiltinGraders(t *testing.T) {
+	fake := sandboxtest.NewFake()
+	fake.SetFile("answer.json", `{"total": 42}`)
+	fake.On("pytest -q hidden", sandbox.ExecResult{ExitCode: 1, Stdout: "1 failed"})
+	judge := &fixedJudge{}
+	in := graders.Input{
+		Prompt:    "Add the numbers",
+		Response:  "The Total is:  41.98!",
+		ToolCalls: []string{"read_file", "run_command", "write_file"},
+		Sandbox:   fake,
+		Judge:     judge,
+	}
+
+	cases := []struct {
+		spec models.Grader
+		want float64
+	}{
+		{models.Grader{Type: "exact", Expected: "The Total is:  41.98!"}, 1},
+		{models.Grader{Type: "exact", Expected: "the total is 41.98"}, 0},
+		{models.Grader{Type: "normalized", Expected: "the total is 41.98"}, 1},
+		{models.Grader{Type: "regex", Pattern: `(?i)total is:\s+\d+`}, 1},
+		{models.Grader{Type: "numeric", Expected: "42", Tolerance: 0.05}, 1},
+		{models.Grader{Type: "numeric", Expected: "42", Tolerance: 0.01}, 0},
+		{models.Grader{Type: "json_schema", Path: "answer.json", Schema: json.RawMessage(`{"type": "object", "required": ["total"], "properties": {"total": {"type": "integer"}}}`)}, 1},
+		{models.Grader{Type: "json_schema", Schema: json.RawMessage(`{"type": "object"}`)}, 0},
+		{models.Grader{Type: "tool_sequence", Tools: []string{"read_file", "write_file"}}, 1},
+		{models.Grader{Type: "tool_sequence", Tools: []string{"write_file", "run_command"}}, 0.5},
+		{models.Grader{Type: "command", Command: "pytest -q hidden", ExitCode: 1}, 1},
+		{models.Grader{Type: "command", Command: "pytest -q hidden"}, 0},
+		{models.Grader{Type: "llm_judge", Rubric: "Sum is correct"}, 0.7},
+	}
+	for _, tc := range cases {
+		outcome := graders.Evaluate(context.Background(), []models.Grader{tc.spec}, 0, in)
+		grade := outcome.Grades[0]
+		if grade.Error != "" || math.Abs(grade.Score-tc.want) > 1e-9 {
+			t.Errorf("%s %+v: expected score %v, got %+v", tc.spec.Type, tc.spec, tc.want, grade)
+		}
+	}
+	if judge.got.Model != graders.DefaultJudgeModel || judge.got.Rubric != "Sum is correct" || judge.got.Response != in.Response {
+		t.Errorf("unexpected judge request: %+v", judge.got)
+	}
+}
+
+func TestGraderWeightsAndThreshold(t *testing.T) {
+	specs := []models.Grader{
+		{Type: "regex", Pattern: "done", Weight: 3},
+		{Type: "exact", Expected: "nope"},
+	}
+	in := graders.Input{Response: "done"}
+	if outcome := graders.Evaluate(context.Background(), specs, 0, in); outcome.Passed || outcome.Score != 0.75 {
+		t.Fatalf("expected score 0.75 failing the default threshold, got %+v", outcome)
+	}
+	if outcome := graders.Evaluate(context.Background(), specs, 0.7, in); !outcome.Passed {
+		t.Fatalf("expected a pass with threshold 0.7, got %+v", outcome)
+	}
+
+	for _, spec := range []models.Grader{
+		{Type: "unknown"},
+		{Type: "regex", Pattern: "("},
+		{Type: "numeric", Expected: "many"},
+		{Type: "json_schema", Schema: json.RawMessage(`{"type": 5}`)},
+		{Type: "json_schema", Schema: json.RawMessage(`{"oneOf": [{"type": "string"}]}`)},
+		{Type: "llm_judge"},
+		{Type: "exact", Weight: -1},
+	} {
+		if err := graders.Validate([]models.Grader{spec}, 0); err == nil {
+			t.Errorf("expected %+v to be rejected", spec)
+		}
+	}
+}
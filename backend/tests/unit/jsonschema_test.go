@@ -0,0 +1,112 @@
+package unit
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/example/back-end-tcc/pkg/jsonschema"
+)
+
+func compileSchema(t *testing.T, definition string) *jsonschema.Schema {
+	t.Helper()
+	schema, err := jsonschema.Compile(definition)
+	if err != nil {
+		t.Fatalf("compile: %v", err)
+	}
+	return schema
+}
+
+// violationPaths validates document and returns the paths of the violations found.
+func violationPaths(t *testing.T, schema *jsonschema.Schema, document string) []string {
+	t.Helper()
+	err := schema.ValidateJSON([]byte(document))
+	if err == nil {
+		return nil
+	}
+	var verr *jsonschema.ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("expected ValidationError, got %v", err)
+	}
+	paths := make([]string, len(verr.Violations))
+	for i, v := range verr.Violations {
+		paths[i] = v.Path
+	}
+	return paths
+}
+
+func TestSchemaKeywords(t *testing.T) {
+	schema := compileSchema(t, `{
+		"type": "object",
+		"properties": {
+			"mode": {"type": "string", "enum": ["fast", "slow"]},
+			"retries": {"type": "integer", "minimum": 0, "maximum": 5},
+			"name": {"type": "string", "minLength": 2, "maxLength": 4, "pattern": "^[a-z]+$"},
+			"tags": {"type": "array", "items": {"type": "string"}, "minItems": 1, "maxItems": 2},
+			"note": {"type": ["string", "null"]}
+		},
+		"additionalProperties": false
+	}`)
+
+	cases := []struct {
+		document string
+		want     []string
+	}{
+		{`{"mode": "fast", "retries": 3, "name": "abc", "tags": ["a"], "note": null}`, nil},
+		{`{"mode": "medium"}`, []string{"/mode"}},
+		{`{"retries": 6}`, []string{"/retries"}},
+		{`{"retries": -1}`, []string{"/retries"}},
+		{`{"retries": 1.5}`, []string{"/retries"}},
+		{`{"name": "a"}`, []string{"/name"}},
+		{`{"name": "abcde"}`, []string{"/name"}},
+		{`{"name": "AB"}`, []string{"/name"}},
+		{`{"tags": []}`, []string{"/tags"}},
+		{`{"tags": ["a", "b", "c"]}`, []string{"/tags"}},
+		{`{"tags": ["a", 1]}`, []string{"/tags/1"}},
+		{`{"note": 1}`, []string{"/note"}},
+		{`{"extra": true}`, []string{"/extra"}},
+		{`[]`, []string{"/"}},
+		{`{"mode": `, []string{"/"}},
+	}
+	for _, c := range cases {
+		got := violationPaths(t, schema, c.document)
+		if len(got) != len(c.want) {
+			t.Errorf("%s: expected violations at %v, got %v", c.document, c.want, got)
+			continue
+		}
+		for i := range got {
+			if got[i] != c.want[i] {
+				t.Errorf("%s: expected violations at %v, got %v", c.document, c.want, got)
+			}
+		}
+	}
+}
+
+func TestSchemaAdditionalPropertiesSchema(t *testing.T) {
+	schema := compileSchema(t, `{"type": "object", "additionalProperties": {"type": "number"}}`)
+	if got := violationPaths(t, schema, `{"a": 1, "b": 2.5}`); got != nil {
+		t.Fatalf("expected no violations, got %v", got)
+	}
+	if got := violationPaths(t, schema, `{"a": "x"}`); len(got) != 1 || got[0] != "/a" {
+		t.Fatalf("expected a violation at /a, got %v", got)
+	}
+}
+
+func TestSchemaCompileRejectsInvalidDefinitions(t *testing.T) {
+	for _, definition := range []string{
+		`{"type": "string", "pattern": "("}`,
+		`{"properties": {"a": {"pattern": "["}}}`,
+		`{"type": 3}`,
+		`not json`,
+		`{"oneOf": [{"type": "string"}, {"type": "integer"}]}`,
+		`{"properties": {"email": {"type": "string", "format": "email"}}}`,
+		`{"additionalProperties": {"const": 1}}`,
+		`{"items": {"$ref": "#"}}`,
+	} {
+		if _, err := jsonschema.Compile(definition); err == nil {
+			t.Errorf("%s: expected a compile error", definition)
+		}
+	}
+	if _, err := jsonschema.Compile(`{"$schema": "https://json-schema.org/draft/2020-12/schema", "title": "Answer", "type": "object"}`); err != nil {
+		t.Fatalf("expected annotations to be accepted, got %v", err)
+	}
+}
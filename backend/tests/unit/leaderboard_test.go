@@ -0,0 +1,313 @@
+package unit
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/example/back-end-tcc/pkg/models"
+	"github.com/example/back-end-tcc/pkg/queue"
+	"github.com/example/back-end-tcc/pkg/storage"
+	agentrepository "github.com/example/back-end-tcc/services/agent/repository"
+	benchmarkrepository "github.com/example/back-end-tcc/services/benchmark/repository"
+	leaderboardhandlers "github.com/example/back-end-tcc/services/leaderboard/handlers"
+	leaderboardrepository "github.com/example/back-end-tcc/services/leaderboard/repository"
+	leaderboardservice "github.com/example/back-end-tcc/services/leaderboard/service"
+)
+
+func TestLeaderboardEfficiencyAndPareto(t *testing.T) {
+	repo := leaderboardrepository.New(storage.NewMemoryRepository[models.LeaderboardEntry]())
+	for _, entry := range []models.LeaderboardEntry{
+		{SubmissionID: "cheap", AgentID: "a1", Score: 0.5, TotalCost: 1, AvgLatency: 9, TotalTokens: 3000, PassedTasks: 3},
+		{SubmissionID: "strong", AgentID: "a2", Score: 0.9, TotalCost: 4, AvgLatency: 2},
+		{SubmissionID: "dominated", AgentID: "a3", Score: 0.6, TotalCost: 5, AvgLatency: 10},
+		{SubmissionID: "middle", AgentID: "a4", Score: 0.7, TotalCost: 2, AvgLatency: 2},
+		{SubmissionID: "other", BenchmarkID: "other", AgentID: "a5", Score: 1},
+	} {
+		if entry.BenchmarkID == "" {
+			entry.BenchmarkID = "bench"
+		}
+		repo.Save(entry)
+	}
+	svc := leaderboardservice.New(repo, queue.NewBus())
+
+	for _, entry := range svc.Entries() {
+		switch entry.SubmissionID {
+		case "cheap":
+			if entry.ScorePerDollar == nil || *entry.ScorePerDollar != 0.5 || entry.TokensPerSuccess == nil || *entry.TokensPerSuccess != 1000 {
+				t.Errorf("unexpected efficiency of %+v", entry)
+			}
+		case "other":
+			if entry.ScorePerDollar != nil || entry.ScorePerSecond != nil || entry.TokensPerSuccess != nil {
+				t.Errorf("expected no efficiency metrics without cost, latency or passed tasks: %+v", entry)
+			}
+		}
+	}
+
+	frontier := svc.Pareto("bench")
+	ids := func(entries []models.LeaderboardEntry) []string {
+		var out []string
+		for _, entry := range entries {
+			out = append(out, entry.SubmissionID)
+		}
+		return out
+	}
+	if got := ids(frontier.Cost); len(got) != 3 || got[0] != "cheap" || got[1] != "middle" || got[2] != "strong" {
+		t.Errorf("unexpected cost frontier %v", got)
+	}
+	if got := ids(frontier.Latency); len(got) != 1 || got[0] != "strong" {
+		t.Errorf("unexpected latency frontier %v", got)
+	}
+}
+
+func TestLeaderboardProjectsPerBenchmark(t *testing.T) {
+	agents := agentrepository.NewAgentRepository(storage.NewMemoryRepository[models.User]())
+	agents.Save(models.User{ID: "a1", Name: "Alpha"})
+	now := time.Now()
+	summaries := []models.ScoreSummary{
+		{SubmissionID: "s1", AgentID: "a1", BenchmarkID: "b1", Score: 0.8, TotalCost: 2, Tasks: []models.TaskScore{{Status: "passed"}, {Status: "failed"}}},
+		{SubmissionID: "s2", AgentID: "a2", BenchmarkID: "b1", Score: 0.8, TotalCost: 1},
+		{SubmissionID: "s3", AgentID: "a1", BenchmarkID: "b1", Score: 0.4},
+		{SubmissionID: "s4", AgentID: "a1", BenchmarkID: "b2", Score: 0.1},
+	}
+	for _, selection := range []leaderboardservice.Selection{leaderboardservice.SelectBest, leaderboardservice.SelectLatest} {
+		bus := queue.NewBus()
+		svc := leaderboardservice.New(leaderboardrepository.New(storage.NewMemoryRepository[models.LeaderboardEntry]()), bus,
+			leaderboardservice.WithAgents(agents), leaderboardservice.WithSelection(selection))
+		svc.Start()
+		for i, summary := range summaries {
+			summary.SubmittedAt = now.Add(time.Duration(i) * time.Second)
+			if err := bus.Publish(context.Background(), queue.Message{Type: leaderboardservice.LeaderboardUpdated, Data: summary}); err != nil {
+				t.Fatalf("publish error: %v", err)
+			}
+		}
+
+		entries := svc.Entries()
+		if len(entries) != 3 {
+			t.Fatalf("%s: expected one entry per agent and benchmark, got %+v", selection, entries)
+		}
+		byKey := map[string]models.LeaderboardEntry{}
+		for _, entry := range entries {
+			byKey[entry.BenchmarkID+"/"+entry.AgentID] = entry
+		}
+		if b2 := byKey["b2/a1"]; b2.Rank != 1 || b2.SubmissionID != "s4" || b2.AgentName != "Alpha" {
+			t.Errorf("%s: expected a1 to rank first on its own benchmark, got %+v", selection, b2)
+		}
+		alpha, beta := byKey["b1/a1"], byKey["b1/a2"]
+		switch selection {
+		case leaderboardservice.SelectBest:
+			// Equal scores rank the cheaper agent first.
+			if alpha.SubmissionID != "s1" || alpha.PassedTasks != 1 || alpha.TotalCost != 2 || alpha.Rank != 2 || beta.Rank != 1 {
+				t.Errorf("expected a1's best submission ranked behind the cheaper a2, got %+v and %+v", alpha, beta)
+			}
+		case leaderboardservice.SelectLatest:
+			if alpha.SubmissionID != "s3" || alpha.Rank != 2 || beta.Rank != 1 {
+				t.Errorf("expected a1's latest submission ranked second, got %+v and %+v", alpha, beta)
+			}
+		}
+	}
+}
+
+func TestLeaderboardQueryAPI(t *testing.T) {
+	agents := agentrepository.NewAgentRepository(storage.NewMemoryRepository[models.User]())
+	benchmarks := benchmarkrepository.New(storage.NewMemoryRepository[models.Benchmark]())
+	benchmarks.Save(models.Benchmark{ID: "b1", Domain: "coding"})
+	benchmarks.Save(models.Benchmark{ID: "b2", Domain: "support"})
+	bus := queue.NewBus()
+	svc := leaderboardservice.New(leaderboardrepository.New(storage.NewMemoryRepository[models.LeaderboardEntry]()), bus,
+		leaderboardservice.WithAgents(agents), leaderboardservice.WithBenchmarks(benchmarks))
+	svc.Start()
+	for i, summary := range []models.ScoreSummary{
+		{AgentID: "a1", BenchmarkID: "b1", Score: 0.9, TotalCost: 3},
+		{AgentID: "a2", BenchmarkID: "b1", Score: 0.7, TotalCost: 1},
+		{AgentID: "a3", BenchmarkID: "b1", Score: 0.5, TotalCost: 2},
+		{AgentID: "a1", BenchmarkID: "b2", Score: 0.2, TotalCost: 9},
+	} {
+		agents.Save(models.User{ID: summary.AgentID})
+		summary.SubmissionID = strconv.Itoa(i)
+		if err := bus.Publish(context.Background(), queue.Message{Type: leaderboardservice.LeaderboardUpdated, Data: summary}); err != nil {
+			t.Fatalf("publish error: %v", err)
+		}
+	}
+
+	handlers := leaderboardhandlers.New(svc)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/leaderboard", handlers.List)
+	mux.HandleFunc("GET /agents/{id}/metrics", handlers.AgentMetrics)
+	get := func(target string, out interface{}) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		if out != nil {
+			json.NewDecoder(rec.Body).Decode(out)
+		}
+		return rec
+	}
+
+	var page []models.LeaderboardEntry
+	rec := get("/leaderboard?domain=coding&sort=cost&limit=2", &page)
+	if len(page) != 2 || page[0].AgentID != "a2" || page[0].Rank != 1 || page[1].AgentID != "a3" || rec.Header().Get("X-Total-Count") != "3" {
+		t.Fatalf("unexpected first page %+v", page)
+	}
+	cursor := rec.Header().Get("X-Next-Cursor")
+	rec = get("/leaderboard?domain=coding&sort=cost&limit=2&cursor="+cursor, &page)
+	if len(page) != 1 || page[0].AgentID != "a1" || page[0].Rank != 3 || rec.Header().Get("X-Next-Cursor") != "" {
+		t.Fatalf("unexpected last page %+v (cursor %q)", page, cursor)
+	}
+	get("/leaderboard", &page)
+	if len(page) != 4 || page[0].AgentID != "a1" || page[0].Rank != 1 || page[3].BenchmarkID != "b2" || page[3].Rank != 1 {
+		t.Fatalf("expected ranks within each benchmark, got %+v", page)
+	}
+	get("/leaderboard?benchmark_id=b1&sort=score&order=asc", &page)
+	if len(page) != 3 || page[0].AgentID != "a3" || page[0].Domain != "coding" {
+		t.Fatalf("expected the lowest score first, got %+v", page)
+	}
+	for _, target := range []string{"/leaderboard?sort=tokens", "/leaderboard?order=up", "/leaderboard?limit=0", "/leaderboard?cursor=bm9wZQ"} {
+		if rec := get(target, nil); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", target, rec.Code)
+		}
+	}
+
+	var metrics models.AgentMetrics
+	get("/agents/a1/metrics?benchmark_id=b2", &metrics)
+	if len(metrics.Benchmarks) != 1 || metrics.Benchmarks[0].Score != 0.2 || metrics.Benchmarks[0].Rank != 1 {
+		t.Fatalf("unexpected agent metrics %+v", metrics)
+	}
+	if rec := get("/agents/unknown/metrics", nil); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for an unknown agent, got %d", rec.Code)
+	}
+}
+
+func TestLeaderboardSelectsWithinOneScoringVersion(t *testing.T) {
+	submitted := time.Now()
+	// Rescoring under version 2 reaches the latest submission s2 first and turns the ranking around.
+	summaries := []models.ScoreSummary{
+		{SubmissionID: "s1", Score: 0.9, ScoringVersion: 1, SubmittedAt: submitted},
+		{SubmissionID: "s2", Score: 0.5, ScoringVersion: 1, SubmittedAt: submitted.Add(time.Second)},
+		{SubmissionID: "s2", Score: 0.7, ScoringVersion: 2, SubmittedAt: submitted.Add(time.Second)},
+		{SubmissionID: "s1", Score: 0.3, ScoringVersion: 2, SubmittedAt: submitted},
+	}
+	for _, selection := range []leaderboardservice.Selection{leaderboardservice.SelectBest, leaderboardservice.SelectLatest} {
+		bus := queue.NewBus()
+		svc := leaderboardservice.New(leaderboardrepository.New(storage.NewMemoryRepository[models.LeaderboardEntry]()), bus,
+			leaderboardservice.WithSelection(selection))
+		svc.Start()
+		for _, summary := range summaries {
+			summary.AgentID, summary.BenchmarkID, summary.Calculated = "a1", "b1", time.Now()
+			if err := bus.Publish(context.Background(), queue.Message{Type: leaderboardservice.LeaderboardUpdated, Data: summary}); err != nil {
+				t.Fatalf("publish error: %v", err)
+			}
+		}
+		entries := svc.Entries()
+		if len(entries) != 1 || entries[0].SubmissionID != "s2" || entries[0].ScoringVersion != 2 || entries[0].Score != 0.7 {
+			t.Errorf("%s: expected s2's version 2 score, got %+v", selection, entries)
+		}
+	}
+}
+
+func TestOverallRatings(t *testing.T) {
+	store := storage.NewMemoryRepository[models.LeaderboardEntry]()
+	bus := queue.NewBus()
+	svc := leaderboardservice.New(leaderboardrepository.New(store), bus, leaderboardservice.WithSelection(leaderboardservice.SelectLatest))
+	svc.Start()
+	publish := func(id, agent, benchmark string, scores ...float64) {
+		summary := models.ScoreSummary{SubmissionID: id, AgentID: agent, BenchmarkID: benchmark, SubmittedAt: time.Now()}
+		for i, score := range scores {
+			summary.Tasks = append(summary.Tasks, models.TaskScore{TaskID: strconv.Itoa(i), Score: score})
+		}
+		if err := bus.Publish(context.Background(), queue.Message{Type: leaderboardservice.LeaderboardUpdated, Data: summary}); err != nil {
+			t.Fatalf("publish error: %v", err)
+		}
+	}
+	publish("s1", "strong", "b1", 1, 1, 1, 0.5)
+	publish("s2", "middle", "b1", 0, 1, 0.5, 0.5)
+	publish("s3", "weak", "b1", 0, 0, 0, 0.5)
+	publish("s4", "strong", "b2", 1, 1)
+	publish("s5", "weak", "b2", 0, 1)
+
+	ratings := svc.Ratings()
+	if len(ratings) != 3 || ratings[0].AgentID != "strong" || ratings[1].AgentID != "middle" || ratings[2].AgentID != "weak" {
+		t.Fatalf("unexpected order %+v", ratings)
+	}
+	strong := ratings[0]
+	if strong.Wins != 6 || strong.Losses != 0 || strong.Ties != 4 || strong.Benchmarks != 2 || strong.Rank != 1 {
+		t.Errorf("unexpected record %+v", strong)
+	}
+	if strong.StdErr <= 0 || strong.Interval.Lower >= strong.Rating || strong.Interval.Upper <= strong.Rating {
+		t.Errorf("expected an interval around the rating, got %+v", strong)
+	}
+
+	// A worse resubmission replaces the strong agent's outcomes on b1.
+	publish("s6", "strong", "b1", 0, 0, 0, 0)
+	updated := svc.Ratings()
+	if updated[0].AgentID != "middle" {
+		t.Fatalf("expected middle to lead after the resubmission, got %+v", updated)
+	}
+	// Incremental updates match a fit from the stored entries.
+	rebuilt := leaderboardservice.New(leaderboardrepository.New(store), queue.NewBus()).Ratings()
+	for i := range updated {
+		if updated[i].AgentID != rebuilt[i].AgentID || math.Abs(updated[i].Rating-rebuilt[i].Rating) > 1e-3 || updated[i].Wins != rebuilt[i].Wins {
+			t.Fatalf("incremental ratings %+v differ from rebuilt %+v", updated[i], rebuilt[i])
+		}
+	}
+}
+
+func TestLeaderboardTimeTravel(t *testing.T) {
+	bus := queue.NewBus()
+	svc := leaderboardservice.New(leaderboardrepository.New(storage.NewMemoryRepository[models.LeaderboardEntry]()), bus,
+		leaderboardservice.WithSelection(leaderboardservice.SelectLatest))
+	svc.Start()
+	publish := func(id, agent string, score float64) {
+		summary := models.ScoreSummary{SubmissionID: id, AgentID: agent, BenchmarkID: "b1", Score: score, SubmittedAt: time.Now()}
+		if err := bus.Publish(context.Background(), queue.Message{Type: leaderboardservice.LeaderboardUpdated, Data: summary}); err != nil {
+			t.Fatalf("publish error: %v", err)
+		}
+	}
+	before := time.Now().Add(-time.Hour)
+	publish("s1", "a1", 0.5)
+	publish("s2", "a2", 0.7)
+	time.Sleep(time.Millisecond)
+	middle := time.Now()
+	time.Sleep(time.Millisecond)
+	publish("s3", "a1", 0.9)
+
+	handlers := leaderboardhandlers.New(svc)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/leaderboard", handlers.List)
+	mux.HandleFunc("GET /agents/{id}/rank-history", handlers.RankHistory)
+	get := func(target string, out interface{}) int {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		if out != nil {
+			json.NewDecoder(rec.Body).Decode(out)
+		}
+		return rec.Code
+	}
+
+	var entries []models.LeaderboardEntry
+	get("/leaderboard?as_of="+middle.Format(time.RFC3339Nano), &entries)
+	if len(entries) != 2 || entries[0].AgentID != "a2" || entries[1].AgentID != "a1" || entries[1].Score != 0.5 {
+		t.Fatalf("unexpected leaderboard as of the middle %+v", entries)
+	}
+	get("/leaderboard?as_of="+before.Format(time.RFC3339), &entries)
+	if len(entries) != 0 {
+		t.Fatalf("expected no entries before the first submission, got %+v", entries)
+	}
+	get("/leaderboard", &entries)
+	if entries[0].AgentID != "a1" {
+		t.Fatalf("expected a1 to lead now, got %+v", entries)
+	}
+	if code := get("/leaderboard?as_of=yesterday", nil); code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an invalid as_of, got %d", code)
+	}
+
+	var history []models.RankChange
+	get("/agents/a1/rank-history?benchmark_id=b1", &history)
+	if len(history) != 3 || history[0].Rank != 1 || history[1].Rank != 2 || history[2].Rank != 1 || history[2].SubmissionID != "s3" || history[2].Agents != 2 {
+		t.Fatalf("unexpected rank history %+v", history)
+	}
+}
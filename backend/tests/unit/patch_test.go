@@ -0,0 +1,118 @@
+package unit
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/example/back-end-tcc/pkg/sandbox/sandboxtest"
+)
+
+func applyPatch(t *testing.T, fake *sandboxtest.Fake, patch string) string {
+	t.Helper()
+	return runTool(t, fake, "apply_patch", `{"patch": `+strconv.Quote(patch)+`}`)
+}
+
+func TestApplyPatchMultipleHunksWithOffsets(t *testing.T) {
+	fake := sandboxtest.NewFake()
+	// Two lines were added at the top since the diff was made, so both hunks apply 2 lines later.
+	fake.SetFile("list.txt", "new1\nnew2\none\ntwo\nthree\nfour\nfive\nsix\nseven\neight\n")
+
+	patch := "--- a/list.txt\n+++ b/list.txt\n" +
+		"@@ -1,3 +1,3 @@\n one\n-two\n+TWO\n three\n" +
+		"@@ -6,3 +6,4 @@\n six\n seven\n+seven and a half\n eight\n"
+	if out := applyPatch(t, fake, patch); out != "Patch applied:\npatched /workspace/list.txt" {
+		t.Fatalf("unexpected result %q", out)
+	}
+	want := "new1\nnew2\none\nTWO\nthree\nfour\nfive\nsix\nseven\nseven and a half\neight\n"
+	if content, _ := fake.File("list.txt"); content != want {
+		t.Fatalf("got %q, want %q", content, want)
+	}
+}
+
+func TestApplyPatchRejectsContextMismatch(t *testing.T) {
+	fake := sandboxtest.NewFake()
+	fake.SetFile("greet.txt", "hello\nworld\n")
+
+	out := applyPatch(t, fake, "--- a/greet.txt\n+++ b/greet.txt\n@@ -1,2 +1,2 @@\n hello\n-planet\n+there\n")
+	if !strings.Contains(out, "hunk 1 does not apply") {
+		t.Fatalf("expected a mismatch error, got %q", out)
+	}
+	if content, _ := fake.File("greet.txt"); content != "hello\nworld\n" {
+		t.Fatalf("expected the file unchanged, got %q", content)
+	}
+}
+
+func TestApplyPatchNoNewlineAtEndOfFile(t *testing.T) {
+	fake := sandboxtest.NewFake()
+	fake.SetFile("a.txt", "first\nlast")
+	fake.SetFile("b.txt", "first\nlast")
+
+	patch := "--- a/a.txt\n+++ b/a.txt\n@@ -1,2 +1,2 @@\n first\n-last\n\\ No newline at end of file\n+end\n\\ No newline at end of file\n" +
+		"--- a/b.txt\n+++ b/b.txt\n@@ -1,2 +1,2 @@\n first\n-last\n\\ No newline at end of file\n+last\n"
+	if out := applyPatch(t, fake, patch); !strings.HasPrefix(out, "Patch applied:") {
+		t.Fatalf("unexpected result %q", out)
+	}
+	if content, _ := fake.File("a.txt"); content != "first\nend" {
+		t.Errorf("expected no trailing newline kept, got %q", content)
+	}
+	if content, _ := fake.File("b.txt"); content != "first\nlast\n" {
+		t.Errorf("expected a trailing newline added, got %q", content)
+	}
+}
+
+func TestApplyPatchCreatesAndDeletesFiles(t *testing.T) {
+	fake := sandboxtest.NewFake()
+	fake.SetFile("obsolete.txt", "gone\nsoon\n")
+
+	patch := "--- /dev/null\n+++ b/pkg/new.go\n@@ -0,0 +1,2 @@\n+package pkg\n+\n" +
+		"--- a/obsolete.txt\n+++ /dev/null\n@@ -1,2 +0,0 @@\n-gone\n-soon\n"
+	out := applyPatch(t, fake, patch)
+	if out != "Patch applied:\ncreated /workspace/pkg/new.go\ndeleted /workspace/obsolete.txt" {
+		t.Fatalf("unexpected result %q", out)
+	}
+	if content, _ := fake.File("pkg/new.go"); content != "package pkg\n\n" {
+		t.Errorf("unexpected created content %q", content)
+	}
+	if fake.Exists("obsolete.txt") {
+		t.Error("expected obsolete.txt to be deleted")
+	}
+}
+
+func TestApplyPatchRejectsPathsOutsideTheDirectory(t *testing.T) {
+	for _, path := range []string{"../etc/passwd", "a/../../etc/passwd", "/etc/passwd"} {
+		fake := sandboxtest.NewFake()
+		fake.SetFile("ok.txt", "x\n")
+		// The valid first file must not be written either.
+		patch := "--- a/ok.txt\n+++ b/ok.txt\n@@ -1 +1 @@\n-x\n+y\n" +
+			"--- /dev/null\n+++ " + path + "\n@@ -0,0 +1 @@\n+root::0:0::/:/bin/sh\n"
+		out := applyPatch(t, fake, patch)
+		if !strings.Contains(out, "is outside /workspace") {
+			t.Errorf("%s: expected the path to be rejected, got %q", path, out)
+		}
+		if content, _ := fake.File("ok.txt"); content != "x\n" {
+			t.Errorf("%s: expected no file changed, got %q", path, content)
+		}
+	}
+
+	fake := sandboxtest.NewFake()
+	fake.SetFile("inside.txt", "x\n")
+	if out := applyPatch(t, fake, "--- /workspace/inside.txt\n+++ /workspace/inside.txt\n@@ -1 +1 @@\n-x\n+y\n"); !strings.HasPrefix(out, "Patch applied:") {
+		t.Fatalf("expected absolute paths inside the workdir to apply, got %q", out)
+	}
+}
+
+func TestApplyPatchLeavesFilesUntouchedWhenAnyFileFails(t *testing.T) {
+	fake := sandboxtest.NewFake()
+	fake.SetFile("a.txt", "one\n")
+	fake.SetFile("b.txt", "two\n")
+
+	patch := "--- a/a.txt\n+++ b/a.txt\n@@ -1 +1 @@\n-one\n+ONE\n" +
+		"--- a/b.txt\n+++ b/b.txt\n@@ -1 +1 @@\n-deux\n+TWO\n"
+	if out := applyPatch(t, fake, patch); !strings.HasPrefix(out, "Error:") {
+		t.Fatalf("expected the patch to fail, got %q", out)
+	}
+	if content, _ := fake.File("a.txt"); content != "one\n" {
+		t.Fatalf("expected a.txt to be untouched, got %q", content)
+	}
+}
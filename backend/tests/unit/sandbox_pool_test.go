@@ -0,0 +1,216 @@
+package unit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/example/back-end-tcc/pkg/models"
+	"github.com/example/back-end-tcc/pkg/observability/metrics"
+	"github.com/example/back-end-tcc/pkg/queue"
+	"github.com/example/back-end-tcc/pkg/sandbox"
+	"github.com/example/back-end-tcc/pkg/sandbox/sandboxtest"
+	"github.com/example/back-end-tcc/pkg/storage"
+	agentrepository "github.com/example/back-end-tcc/services/agent/repository"
+	benchmarkrepository "github.com/example/back-end-tcc/services/benchmark/repository"
+	runnerhandlers "github.com/example/back-end-tcc/services/runner/handlers"
+	runnerrepository "github.com/example/back-end-tcc/services/runner/repository"
+	runnerservice "github.com/example/back-end-tcc/services/runner/service"
+)
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func fakeFactory(created *[]*sandboxtest.Fake, mu *sync.Mutex) sandbox.Factory {
+	return sandboxtest.Factory(func(f *sandboxtest.Fake) {
+		mu.Lock()
+		*created = append(*created, f)
+		mu.Unlock()
+	})
+}
+
+func TestSandboxPoolLeasesWarmSandboxesAndReusesThem(t *testing.T) {
+	var mu sync.Mutex
+	var created []*sandboxtest.Fake
+	rec := metrics.NewInMemory()
+	pool := sandbox.NewPool(fakeFactory(&created, &mu), sandbox.WithPoolSize(1), sandbox.WithPoolMetrics(rec))
+	defer pool.Close()
+
+	spec := sandbox.DefaultSpec("")
+	pool.Warm(spec)
+	waitFor(t, "pool to warm", func() bool { return pool.Stats().Idle == 1 })
+
+	sb, err := pool.Acquire(context.Background(), spec)
+	if err != nil {
+		t.Fatalf("acquire: %v", err)
+	}
+	if stats := pool.Stats(); stats.WarmLeases != 1 || stats.Leased != 1 {
+		t.Fatalf("expected a warm lease, got %+v", stats)
+	}
+
+	fake := sb.(*sandboxtest.Fake)
+	fake.SetFile("leftover.txt", "from the previous task")
+	pool.Release(sb)
+	waitFor(t, "sandbox reset", func() bool { return pool.Stats().Resets == 1 })
+
+	again, err := pool.Acquire(context.Background(), spec)
+	if err != nil {
+		t.Fatalf("acquire: %v", err)
+	}
+	if again != sb {
+		t.Fatalf("expected the reset sandbox to be reused")
+	}
+	if fake.Exists("leftover.txt") {
+		t.Fatalf("expected workdir to be wiped on release, files: %v", fake.Files())
+	}
+	if n := rec.Snapshot().Counters["sandbox_pool_acquire_total{result=warm}"]; n != 2 {
+		t.Fatalf("expected 2 warm acquisitions recorded, got %v", n)
+	}
+}
+
+// withoutReset hides the Reset of a fake, as backends without one look to the pool.
+type withoutReset struct {
+	sandbox.Sandbox
+}
+
+func TestSandboxPoolWipesWorkdirOfSandboxesWithoutReset(t *testing.T) {
+	fake := sandboxtest.NewFake()
+	wipe := "find " + sandbox.DefaultWorkdir + " -mindepth 1 -delete"
+	fake.On(wipe, sandbox.ExecResult{})
+	factory := func(spec sandbox.Spec) (sandbox.Sandbox, error) { return withoutReset{fake}, nil }
+	pool := sandbox.NewPool(factory, sandbox.WithPoolSize(1))
+	defer pool.Close()
+
+	sb, err := pool.Acquire(context.Background(), sandbox.DefaultSpec(""))
+	if err != nil {
+		t.Fatalf("acquire: %v", err)
+	}
+	pool.Release(sb)
+	waitFor(t, "sandbox reset", func() bool { return pool.Stats().Resets == 1 })
+	fake.AssertExecuted(t, wipe)
+}
+
+func TestSandboxPoolRecyclesAfterMaxUses(t *testing.T) {
+	var mu sync.Mutex
+	var created []*sandboxtest.Fake
+	pool := sandbox.NewPool(fakeFactory(&created, &mu), sandbox.WithPoolSize(1), sandbox.WithMaxUses(1))
+	defer pool.Close()
+
+	spec := sandbox.DefaultSpec("")
+	sb, err := pool.Acquire(context.Background(), spec)
+	if err != nil {
+		t.Fatalf("acquire: %v", err)
+	}
+	if stats := pool.Stats(); stats.ColdLeases != 1 {
+		t.Fatalf("expected a cold lease from an empty pool, got %+v", stats)
+	}
+
+	pool.Release(sb)
+	waitFor(t, "sandbox to be recycled", func() bool { return sb.(*sandboxtest.Fake).Stopped() })
+	waitFor(t, "replacement to warm", func() bool { return pool.Stats().Idle == 1 })
+
+	next, err := pool.Acquire(context.Background(), spec)
+	if err != nil {
+		t.Fatalf("acquire: %v", err)
+	}
+	if next == sb {
+		t.Fatalf("expected a fresh sandbox after max uses")
+	}
+}
+
+func TestSandboxPoolColdAcquireStartsPoolSize(t *testing.T) {
+	var mu sync.Mutex
+	var created []*sandboxtest.Fake
+	pool := sandbox.NewPool(fakeFactory(&created, &mu), sandbox.WithPoolSize(2))
+	defer pool.Close()
+
+	spec := sandbox.DefaultSpec("")
+	sb, err := pool.Acquire(context.Background(), spec)
+	if err != nil {
+		t.Fatalf("acquire: %v", err)
+	}
+	waitFor(t, "warm-up", func() bool { return pool.Stats().Starting == 0 })
+	if stats := pool.Stats(); stats.Created != 2 || stats.Idle != 1 || stats.Leased != 1 {
+		t.Fatalf("expected the cold sandbox and one warm-up, got %+v", stats)
+	}
+
+	pool.Release(sb)
+	waitFor(t, "sandbox reset", func() bool { return pool.Stats().Resets == 1 })
+	if stats := pool.Stats(); stats.Idle != 2 || stats.Created != 2 {
+		t.Fatalf("expected the released sandbox to fill the pool, got %+v", stats)
+	}
+}
+
+func TestSandboxPoolDiscardsUnhealthySandboxes(t *testing.T) {
+	var mu sync.Mutex
+	var created []*sandboxtest.Fake
+	pool := sandbox.NewPool(fakeFactory(&created, &mu), sandbox.WithPoolSize(1))
+
+	spec := sandbox.DefaultSpec("")
+	pool.Warm(spec)
+	waitFor(t, "pool to warm", func() bool { return pool.Stats().Idle == 1 })
+	mu.Lock()
+	stale := created[0]
+	mu.Unlock()
+	stale.Handle(func(ctx context.Context, cmd []string, opts sandbox.ExecOptions) (*sandbox.ExecResult, error) {
+		return nil, fmt.Errorf("container gone")
+	})
+
+	sb, err := pool.Acquire(context.Background(), spec)
+	if err != nil {
+		t.Fatalf("acquire: %v", err)
+	}
+	if sb == sandbox.Sandbox(stale) || !stale.Stopped() {
+		t.Fatalf("expected the unhealthy sandbox to be discarded")
+	}
+
+	pool.Release(sb)
+	pool.Close()
+	if _, err := pool.Acquire(context.Background(), spec); err != sandbox.ErrPoolClosed {
+		t.Fatalf("expected ErrPoolClosed, got %v", err)
+	}
+}
+
+func TestSandboxPoolStatsEndpoint(t *testing.T) {
+	var mu sync.Mutex
+	var created []*sandboxtest.Fake
+	pool := sandbox.NewPool(fakeFactory(&created, &mu), sandbox.WithPoolSize(1))
+	defer pool.Close()
+	pool.Warm(sandbox.DefaultSpec(""))
+	waitFor(t, "pool to warm", func() bool { return pool.Stats().Idle == 1 })
+
+	serve := func(provider sandbox.Provider) *httptest.ResponseRecorder {
+		runner := runnerservice.New(
+			runnerrepository.New(storage.NewMemoryRepository[models.Submission](), storage.NewMemoryRepository[models.TraceEvent]()),
+			agentrepository.NewAgentRepository(storage.NewMemoryRepository[models.User]()),
+			benchmarkrepository.New(storage.NewMemoryRepository[models.Benchmark]()),
+			queue.NewBus(), queue.NewBus(),
+			runnerservice.WithSandboxProvider(provider),
+		)
+		rec := httptest.NewRecorder()
+		runnerhandlers.New(runner).SandboxPool(rec, httptest.NewRequest(http.MethodGet, "/sandbox/pool", nil))
+		return rec
+	}
+
+	rec := serve(pool)
+	var stats sandbox.PoolStats
+	if err := json.Unmarshal(rec.Body.Bytes(), &stats); err != nil || rec.Code != http.StatusOK || stats.Idle != 1 || stats.Created != 1 {
+		t.Fatalf("expected one idle sandbox, got %d %s", rec.Code, rec.Body)
+	}
+	if rec := serve(sandbox.NewDirectProvider(fakeFactory(&created, &mu))); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 without a pool, got %d", rec.Code)
+	}
+}
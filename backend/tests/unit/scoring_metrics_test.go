@@ -2,19 +2,32 @@ package unit
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/example/back-end-tcc/pkg/formula"
 	"github.com/example/back-end-tcc/pkg/models"
 	"github.com/example/back-end-tcc/pkg/queue"
 	"github.com/example/back-end-tcc/pkg/storage"
+	benchmarkrepository "github.com/example/back-end-tcc/services/benchmark/repository"
+	benchmarkservice "github.com/example/back-end-tcc/services/benchmark/service"
+	leaderboardrepository "github.com/example/back-end-tcc/services/leaderboard/repository"
+	leaderboardservice "github.com/example/back-end-tcc/services/leaderboard/service"
+	scoringhandlers "github.com/example/back-end-tcc/services/scoring/handlers"
 	scoringrepository "github.com/example/back-end-tcc/services/scoring/repository"
 	scoringservice "github.com/example/back-end-tcc/services/scoring/service"
 )
 
 func TestScoringServiceStoresSummary(t *testing.T) {
 	bus := queue.NewBus()
-	repo := scoringrepository.New(storage.NewMemoryRepository[models.ScoreSummary]())
+	repo := scoringrepository.New(storage.NewMemoryRepository[models.ScoreSummary](), storage.NewMemoryRepository[models.TraceEvent]())
 	service := scoringservice.New(repo, bus, bus)
 	service.Start()
 
@@ -23,11 +36,269 @@ func TestScoringServiceStoresSummary(t *testing.T) {
 		t.Fatalf("publish error: %v", err)
 	}
 
-	summaries := service.Summaries()
-	if len(summaries) != 1 {
-		t.Fatalf("expected 1 summary, got %d", len(summaries))
+	summaries, err := service.Summaries()
+	if err != nil || len(summaries) != 1 {
+		t.Fatalf("expected 1 summary, got %d (err %v)", len(summaries), err)
 	}
 	if summaries[0].Score != 0.5 {
 		t.Fatalf("expected score 0.5, got %f", summaries[0].Score)
 	}
 }
+
+func TestScoringServiceComputesSummaryFromTraces(t *testing.T) {
+	bus := queue.NewBus()
+	traces := storage.NewMemoryRepository[models.TraceEvent]()
+	benchmarks := benchmarkrepository.New(storage.NewMemoryRepository[models.Benchmark]())
+	benchmarks.Save(models.Benchmark{ID: "bench", Tasks: []models.Task{
+		{ID: "t1", ExpectedTool: "read_file"},
+		// t2 only calls read_file with invalid arguments, which does not count as calling it.
+		{ID: "t2", ExpectedTool: "read_file"},
+		{ID: "t3"},
+	}})
+	now := time.Now()
+	for i, event := range []models.TraceEvent{
+		{TaskID: "t1", Type: "agent", Turns: 1, Cost: 0.01, Latency: 1, Result: map[string]string{"promptTokens": "120", "completionTokens": "30"}},
+		{TaskID: "t1", Type: "tool", ToolName: "read_file", Result: map[string]string{"status": "ok"}, Latency: 0.5},
+		{TaskID: "t1", Type: "agent", Turns: 2, Cost: 0.02, Latency: 1.5},
+		{TaskID: "t2", Type: "guardrail", ToolName: "write_file", Success: false},
+		{TaskID: "t2", Type: "tool", ToolName: "read_file", Result: map[string]string{"status": "invalid_arguments"}},
+		{TaskID: "t2", Type: "agent", Turns: 1, Cost: 0.03, Latency: 3},
+		{TaskID: "t3", Type: "agent", Turns: 1},
+		{SubmissionID: "other", TaskID: "t1", Type: "agent", Cost: 5},
+	} {
+		event.ID = strconv.Itoa(i)
+		if event.SubmissionID == "" {
+			event.SubmissionID = "sub"
+		}
+		event.Timestamp = now.Add(time.Duration(i) * time.Millisecond)
+		traces.Save(event.ID, event)
+	}
+
+	repo := scoringrepository.New(storage.NewMemoryRepository[models.ScoreSummary](), traces)
+	service := scoringservice.New(repo, bus, bus, scoringservice.WithBenchmarks(benchmarks))
+	service.Start()
+	submission := models.Submission{ID: "sub", BenchmarkID: "bench", TaskResults: []models.TaskResult{
+		{TaskID: "t1", Status: "passed", Score: 1},
+		{TaskID: "t2", Status: "failed"},
+		{TaskID: "t3", Status: "passed", Score: 1},
+	}}
+	if err := bus.Publish(context.Background(), queue.Message{Type: scoringservice.ScoreCalculated, Data: submission}); err != nil {
+		t.Fatalf("publish error: %v", err)
+	}
+
+	summary, _, _ := service.Summary("sub")
+	approx := func(got, want float64) bool { return math.Abs(got-want) < 1e-9 }
+	if !approx(summary.Score, 2.0/3) || !approx(summary.SuccessRate, 2.0/3) {
+		t.Errorf("expected score and success rate 2/3, got %f and %f", summary.Score, summary.SuccessRate)
+	}
+	if summary.ToolCorrectness == nil || !approx(*summary.ToolCorrectness, 0.5) {
+		t.Errorf("expected tool correctness 0.5, got %v", summary.ToolCorrectness)
+	}
+	if summary.Violations != 2 {
+		t.Errorf("expected 2 violations, got %d", summary.Violations)
+	}
+	if !approx(summary.AvgTurns, 4.0/3) || !approx(summary.AvgLatency, 2) || !approx(summary.TotalCost, 0.06) || summary.TotalTokens != 150 {
+		t.Errorf("unexpected turns, latency or cost: %+v", summary)
+	}
+	if !approx(summary.Metrics["argument_accuracy"], 0.5) {
+		t.Errorf("expected argument accuracy 0.5, got %v", summary.Metrics)
+	}
+	if summary.SubmissionID != "sub" || summary.BenchmarkID != "bench" || len(summary.Tasks) != 3 {
+		t.Fatalf("expected a keyed summary with 3 task scores, got %+v", summary)
+	}
+	if t2 := summary.Tasks[1]; t2.TaskID != "t2" || t2.ToolCorrect == nil || *t2.ToolCorrect || t2.Violations != 2 || t2.Turns != 1 {
+		t.Errorf("unexpected breakdown of t2: %+v", t2)
+	}
+}
+
+// failingTraces is a trace store whose queries fail.
+type failingTraces struct {
+	*storage.MemoryRepository[models.TraceEvent]
+}
+
+func (failingTraces) Query(storage.Query) ([]models.TraceEvent, error) {
+	return nil, errors.New("trace store unavailable")
+}
+
+func TestScoringServiceSkipsSubmissionsWhoseTracesFailToLoad(t *testing.T) {
+	bus := queue.NewBus()
+	traces := failingTraces{storage.NewMemoryRepository[models.TraceEvent]()}
+	service := scoringservice.New(scoringrepository.New(storage.NewMemoryRepository[models.ScoreSummary](), traces), bus, bus)
+	service.Start()
+
+	submission := models.Submission{ID: "sub", TaskResults: []models.TaskResult{{TaskID: "t1", Status: "passed", Score: 1}}}
+	_ = bus.Publish(context.Background(), queue.Message{Type: scoringservice.ScoreCalculated, Data: submission})
+	if summaries, _ := service.Summaries(); len(summaries) != 0 {
+		t.Fatalf("expected no summary without traces to score from, got %+v", summaries)
+	}
+}
+
+// queriedSummaries is a summary store that fails the test when it is listed in full.
+type queriedSummaries struct {
+	*storage.MemoryRepository[models.ScoreSummary]
+	t *testing.T
+}
+
+func (s queriedSummaries) List() []models.ScoreSummary {
+	s.t.Error("expected summaries to be queried, not listed")
+	return s.MemoryRepository.List()
+}
+
+func TestScoresHTTPLookups(t *testing.T) {
+	bus := queue.NewBus()
+	repo := scoringrepository.New(queriedSummaries{storage.NewMemoryRepository[models.ScoreSummary](), t}, nil)
+	service := scoringservice.New(repo, bus, bus)
+	service.Start()
+	for _, sub := range []models.Submission{
+		{ID: "s1", AgentID: "a1", BenchmarkID: "b1", TaskResults: []models.TaskResult{{TaskID: "t", Status: "passed", Score: 1}}},
+		{ID: "s2", AgentID: "a1", BenchmarkID: "b2", TaskResults: []models.TaskResult{{TaskID: "t", Status: "failed"}}},
+		{ID: "s3", AgentID: "a2", BenchmarkID: "b1", TaskResults: []models.TaskResult{{TaskID: "t", Status: "failed"}}},
+	} {
+		if err := bus.Publish(context.Background(), queue.Message{Type: scoringservice.ScoreCalculated, Data: sub}); err != nil {
+			t.Fatalf("publish error: %v", err)
+		}
+	}
+
+	handlers := scoringhandlers.New(service)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/scores", handlers.List)
+	mux.HandleFunc("GET /scores/{submissionId}", handlers.Get)
+	get := func(target string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		return rec
+	}
+
+	var summary models.ScoreSummary
+	rec := get("/scores/s1")
+	if err := json.NewDecoder(rec.Body).Decode(&summary); err != nil || summary.SubmissionID != "s1" || summary.Score != 1 || len(summary.Tasks) != 1 {
+		t.Fatalf("unexpected summary %+v (status %d, err %v)", summary, rec.Code, err)
+	}
+	if rec := get("/scores/missing"); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", rec.Code)
+	}
+	var list []models.ScoreSummary
+	json.NewDecoder(get("/scores?agent_id=a1&benchmark_id=b1").Body).Decode(&list)
+	if len(list) != 1 || list[0].SubmissionID != "s1" {
+		t.Fatalf("expected only s1, got %+v", list)
+	}
+	json.NewDecoder(get("/scores?benchmark_id=b1").Body).Decode(&list)
+	if len(list) != 2 {
+		t.Fatalf("expected 2 summaries for b1, got %d", len(list))
+	}
+}
+
+func TestRescoreKeepsVersionHistory(t *testing.T) {
+	bus := queue.NewBus()
+	submissions := storage.NewMemoryRepository[models.Submission]()
+	repo := scoringrepository.New(storage.NewMemoryRepository[models.ScoreSummary](), nil, scoringrepository.WithSubmissions(submissions))
+	service := scoringservice.New(repo, bus, bus)
+	service.Start()
+	leaderboard := leaderboardservice.New(leaderboardrepository.New(storage.NewMemoryRepository[models.LeaderboardEntry]()), bus, leaderboardservice.WithScoringVersion(1))
+	leaderboard.Start()
+
+	sub := models.Submission{ID: "s1", AgentID: "a1", BenchmarkID: "b1", SubmittedAt: time.Now(), TaskResults: []models.TaskResult{{
+		TaskID: "t", Status: "failed", Score: 0.5,
+		Grades: []models.Grade{{Type: "regex", Weight: 1, Score: 1}, {Type: "exact", Weight: 1, Score: 0}},
+	}}}
+	submissions.Save(sub.ID, sub)
+	if err := bus.Publish(context.Background(), queue.Message{Type: scoringservice.ScoreCalculated, Data: sub}); err != nil {
+		t.Fatalf("publish error: %v", err)
+	}
+	if summary, _, _ := service.Summary("s1"); summary.ScoringVersion != 1 || summary.Score != 0.5 || summary.SuccessRate != 0 {
+		t.Fatalf("unexpected initial summary %+v", summary)
+	}
+
+	config, err := service.CreateConfig(models.ScoringConfig{GraderWeights: map[string]float64{"exact": 0}})
+	if err != nil || config.Version != 2 {
+		t.Fatalf("expected config version 2, got %+v (err %v)", config, err)
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /scores/rescore", scoringhandlers.New(service).Rescore)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/scores/rescore", strings.NewReader(`{"benchmark_id": "b1"}`)))
+	var result scoringservice.RescoreResult
+	if err := json.NewDecoder(rec.Body).Decode(&result); err != nil || result.Version != 2 || len(result.Submissions) != 1 {
+		t.Fatalf("unexpected rescore result %+v (status %d, err %v)", result, rec.Code, err)
+	}
+
+	summary, _, _ := service.Summary("s1")
+	if summary.ScoringVersion != 2 || summary.Score != 1 || summary.SuccessRate != 1 {
+		t.Fatalf("expected the rescored summary to pass, got %+v", summary)
+	}
+	if history, _ := service.History("s1"); len(history) != 2 || history[0].Score != 0.5 {
+		t.Fatalf("expected both versions in the history, got %+v", history)
+	}
+	if pinned, _ := service.Find("", "b1", 1); len(pinned) != 1 || pinned[0].ScoringVersion != 1 {
+		t.Fatalf("expected the version 1 summary, got %+v", pinned)
+	}
+	if entries := leaderboard.Entries(); len(entries) != 1 || entries[0].Score != 0.5 {
+		t.Fatalf("expected the pinned leaderboard to keep the version 1 score, got %+v", entries)
+	}
+	if _, err := service.Rescore(context.Background(), scoringservice.RescoreRequest{Version: 9}); !errors.Is(err, scoringservice.ErrConfigNotFound) {
+		t.Fatalf("expected ErrConfigNotFound, got %v", err)
+	}
+}
+
+func TestCompositeScoreFormula(t *testing.T) {
+	benchmarks := benchmarkrepository.New(storage.NewMemoryRepository[models.Benchmark]())
+	benchmarkSvc := benchmarkservice.New(benchmarks)
+	if _, err := benchmarkSvc.Create(models.Benchmark{Name: "bad", ScoreFormula: &models.ScoreFormula{Terms: []models.ScoreTerm{{Metric: "totalCost", Weight: 1}}}}); err == nil {
+		t.Fatal("expected a cost term without a range to be rejected")
+	}
+	plain, err := benchmarkSvc.Create(models.Benchmark{Name: "plain"})
+	if err != nil || plain.ScoreFormula == nil || plain.ScoreFormula.Terms[0].Metric != "accuracy" {
+		t.Fatalf("expected the default formula to be stored, got %+v (err %v)", plain.ScoreFormula, err)
+	}
+	bench, err := benchmarkSvc.Create(models.Benchmark{ID: "bench", Name: "weighted", ScoreFormula: &models.ScoreFormula{Terms: []models.ScoreTerm{
+		{Metric: "successRate", Weight: 3},
+		{Metric: "avgLatency", Weight: 1, Max: 10},
+	}}})
+	if err != nil || bench.ScoreFormula.Terms[0].Max != 1 {
+		t.Fatalf("expected success rate to default to a 0-1 range, got %+v (err %v)", bench.ScoreFormula, err)
+	}
+
+	bus := queue.NewBus()
+	traces := storage.NewMemoryRepository[models.TraceEvent]()
+	traces.Save("1", models.TraceEvent{SubmissionID: "sub", TaskID: "t1", Type: "agent", Latency: 4})
+	traces.Save("2", models.TraceEvent{SubmissionID: "sub", TaskID: "t2", Type: "agent", Latency: 12})
+	service := scoringservice.New(scoringrepository.New(storage.NewMemoryRepository[models.ScoreSummary](), traces), bus, bus, scoringservice.WithBenchmarks(benchmarks))
+	service.Start()
+	submission := models.Submission{ID: "sub", BenchmarkID: "bench", TaskResults: []models.TaskResult{
+		{TaskID: "t1", Status: "passed", Score: 1},
+		{TaskID: "t2", Status: "failed", Score: 0.5},
+	}}
+	if err := bus.Publish(context.Background(), queue.Message{Type: scoringservice.ScoreCalculated, Data: submission}); err != nil {
+		t.Fatalf("publish error: %v", err)
+	}
+
+	// Success rate 0.5 weighs 3/4; average latency 8s over a 0-10s range normalises to 0.2 and weighs 1/4.
+	summary, _, _ := service.Summary("sub")
+	if math.Abs(summary.Score-(0.75*0.5+0.25*0.2)) > 1e-9 || summary.Metrics["accuracy"] != 0.75 {
+		t.Fatalf("unexpected composite score %f (accuracy %f)", summary.Score, summary.Metrics["accuracy"])
+	}
+	if len(summary.Components) != 2 || summary.Components[1].Value != 8 || math.Abs(summary.Components[1].Contribution-0.05) > 1e-9 || summary.Formula == nil {
+		t.Fatalf("unexpected components %+v", summary.Components)
+	}
+}
+
+func TestCompositeScoreDropsUnmeasuredToolCorrectness(t *testing.T) {
+	f, err := formula.Normalize(&models.ScoreFormula{Terms: []models.ScoreTerm{
+		{Metric: "successRate", Weight: 1},
+		{Metric: "toolCorrectness", Weight: 1},
+	}})
+	if err != nil {
+		t.Fatalf("normalize: %v", err)
+	}
+	summary := models.ScoreSummary{SuccessRate: 0.5}
+	score, components := formula.Compose(f, formula.Values(summary))
+	if score != 0.5 || len(components) != 1 || components[0].Metric != "successRate" {
+		t.Fatalf("expected only the success rate to count, got %f from %+v", score, components)
+	}
+
+	correctness := 1.0
+	summary.ToolCorrectness = &correctness
+	if score, _ := formula.Compose(f, formula.Values(summary)); score != 0.75 {
+		t.Fatalf("expected both terms to count once tool correctness is measured, got %f", score)
+	}
+}
@@ -0,0 +1,83 @@
+package unit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/example/back-end-tcc/pkg/models"
+	"github.com/example/back-end-tcc/pkg/queue"
+	"github.com/example/back-end-tcc/pkg/storage"
+	scoringrepository "github.com/example/back-end-tcc/services/scoring/repository"
+	scoringservice "github.com/example/back-end-tcc/services/scoring/service"
+	"github.com/example/back-end-tcc/services/scoring/stats"
+)
+
+func TestStatisticalTests(t *testing.T) {
+	if p := stats.McNemar(10, 0); math.Abs(p-2*math.Pow(0.5, 10)) > 1e-12 {
+		t.Errorf("unexpected McNemar p-value %g", p)
+	}
+	if p := stats.McNemar(3, 3); p != 1 {
+		t.Errorf("expected p=1 for balanced discordant pairs, got %g", p)
+	}
+
+	values := []float64{1, 0, 1, 1, 0, 1, 1, 1, 0, 1}
+	lower, upper := stats.BootstrapCI(values, 0.95, 2000, stats.RNG("seed"))
+	again, _ := stats.BootstrapCI(values, 0.95, 2000, stats.RNG("seed"))
+	if lower > 0.7 || upper < 0.7 || lower < 0.2 || upper > 1 || lower != again {
+		t.Errorf("unexpected interval [%g, %g] (repeat lower %g)", lower, upper, again)
+	}
+}
+
+func TestScoringComparesSubmissions(t *testing.T) {
+	bus := queue.NewBus()
+	service := scoringservice.New(scoringrepository.New(storage.NewMemoryRepository[models.ScoreSummary](), nil), bus, bus)
+	service.Start()
+	publish := func(id, benchmark string, passed func(i int) bool) {
+		sub := models.Submission{ID: id, BenchmarkID: benchmark}
+		for i := 0; i < 30; i++ {
+			result := models.TaskResult{TaskID: fmt.Sprintf("t%d", i), Status: "failed"}
+			if passed(i) {
+				result.Status, result.Score = "passed", 1
+			}
+			sub.TaskResults = append(sub.TaskResults, result)
+		}
+		if err := bus.Publish(context.Background(), queue.Message{Type: scoringservice.ScoreCalculated, Data: sub}); err != nil {
+			t.Fatalf("publish: %v", err)
+		}
+	}
+	publish("weak", "bench", func(i int) bool { return i%3 == 0 })
+	publish("strong", "bench", func(i int) bool { return i%3 == 0 || i%2 == 0 })
+	publish("same", "bench", func(i int) bool { return i%3 == 0 })
+	publish("elsewhere", "other", func(i int) bool { return true })
+
+	summary, _, _ := service.Summary("weak")
+	ci := summary.Intervals["successRate"]
+	if ci.Level != 0.95 || ci.Lower > summary.SuccessRate || ci.Upper < summary.SuccessRate || ci.Lower == ci.Upper {
+		t.Fatalf("expected a confidence interval around %g, got %+v", summary.SuccessRate, ci)
+	}
+
+	cmp, err := service.Compare("weak", "strong")
+	if err != nil {
+		t.Fatalf("compare: %v", err)
+	}
+	if cmp.PairedTasks != 30 || cmp.OnlyBPassed != 10 || cmp.OnlyAPassed != 0 || math.Abs(cmp.ScoreDiff-1.0/3) > 1e-9 {
+		t.Fatalf("unexpected comparison %+v", cmp)
+	}
+	if cmp.McNemarPValue > 0.01 || cmp.PValue > 0.01 || cmp.EffectSize == nil || *cmp.EffectSize <= 0 || cmp.DiffInterval.Lower <= 0 {
+		t.Fatalf("expected a significant positive difference, got %+v", cmp)
+	}
+
+	cmp, err = service.Compare("weak", "same")
+	if err != nil || cmp.PValue != 1 || cmp.McNemarPValue != 1 || cmp.EffectSize != nil {
+		t.Fatalf("expected no difference between identical runs, got %+v (err %v)", cmp, err)
+	}
+	if _, err := service.Compare("weak", "elsewhere"); !errors.Is(err, scoringservice.ErrDifferentBenchmarks) {
+		t.Fatalf("expected ErrDifferentBenchmarks, got %v", err)
+	}
+	if _, err := service.Compare("weak", "missing"); !errors.Is(err, scoringservice.ErrScoreNotFound) {
+		t.Fatalf("expected ErrScoreNotFound, got %v", err)
+	}
+}
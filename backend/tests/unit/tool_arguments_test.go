@@ -0,0 +1,76 @@
+package unit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/example/back-end-tcc/pkg/sandbox/sandboxtest"
+	"github.com/example/back-end-tcc/services/runner/tools"
+)
+
+func TestValidateArgumentsAcceptsSchemaConformingCall(t *testing.T) {
+	args, err := tools.ValidateArguments("write_file", `{"path": "/tmp/out.txt", "content": "hello"}`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if args["path"] != "/tmp/out.txt" {
+		t.Fatalf("expected decoded path, got %v", args["path"])
+	}
+}
+
+func TestValidateArgumentsReportsViolations(t *testing.T) {
+	_, err := tools.ValidateArguments("write_file", `{"path": 42, "mode": "0644"}`)
+	var argErr *tools.ArgumentError
+	if !errors.As(err, &argErr) {
+		t.Fatalf("expected ArgumentError, got %v", err)
+	}
+
+	paths := map[string]bool{}
+	for _, v := range argErr.Violations {
+		paths[v.Path] = true
+	}
+	for _, want := range []string{"/path", "/content", "/mode"} {
+		if !paths[want] {
+			t.Errorf("expected violation for %s, got %+v", want, argErr.Violations)
+		}
+	}
+	if !strings.Contains(argErr.Payload(), `"error":"invalid_arguments"`) {
+		t.Fatalf("expected structured payload, got %s", argErr.Payload())
+	}
+}
+
+func TestValidateArgumentsRejectsMalformedJSON(t *testing.T) {
+	_, err := tools.ValidateArguments("run_command", `{"command": `)
+	var argErr *tools.ArgumentError
+	if !errors.As(err, &argErr) {
+		t.Fatalf("expected ArgumentError, got %v", err)
+	}
+}
+
+func TestValidateArgumentsRejectsNonObjectArguments(t *testing.T) {
+	for _, args := range []string{`[1]`, `42`, `"path"`, `null`} {
+		_, err := tools.ValidateArguments("read_file", args)
+		var argErr *tools.ArgumentError
+		if !errors.As(err, &argErr) {
+			t.Fatalf("%s: expected ArgumentError, got %v", args, err)
+		}
+	}
+}
+
+func TestWriteFileCountsTheSizeLimitInBytes(t *testing.T) {
+	fake := sandboxtest.NewFake()
+	// Three bytes per rune: within the limit in characters, over it in bytes.
+	content := strings.Repeat("€", tools.MaxWriteBytes/3+1)
+	args, _ := json.Marshal(map[string]string{"path": fake.Workdir() + "/out.txt", "content": content})
+	_, err := tools.NewExecutor(fake).Execute(context.Background(), "write_file", string(args))
+	var argErr *tools.ArgumentError
+	if !errors.As(err, &argErr) || argErr.Violations[0].Path != "/content" {
+		t.Fatalf("expected an argument error on /content, got %v", err)
+	}
+	if fake.Exists("out.txt") {
+		t.Fatal("expected nothing to be written")
+	}
+}
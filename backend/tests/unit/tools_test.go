@@ -0,0 +1,117 @@
+package unit
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/example/back-end-tcc/pkg/sandbox"
+	"github.com/example/back-end-tcc/pkg/sandbox/sandboxtest"
+	"github.com/example/back-end-tcc/services/runner/tools"
+)
+
+func runTool(t *testing.T, sb sandbox.Sandbox, name, args string) string {
+	t.Helper()
+	executor := tools.NewExecutor(sb)
+	defer executor.Close()
+	out, err := executor.Execute(context.Background(), name, args)
+	if err != nil {
+		t.Fatalf("%s: %v", name, err)
+	}
+	return out
+}
+
+func TestFileToolsRoundTripThroughTheSandbox(t *testing.T) {
+	fake := sandboxtest.NewFake()
+
+	out := runTool(t, fake, "write_file", `{"path": "/workspace/src/main.go", "content": "package main\n"}`)
+	if !strings.HasPrefix(out, "Successfully wrote 13 bytes") {
+		t.Fatalf("unexpected write result: %s", out)
+	}
+	fake.AssertExecuted(t, "mkdir -p -- /workspace/src")
+
+	if out := runTool(t, fake, "read_file", `{"path": "/workspace/src/main.go"}`); out != "package main\n" {
+		t.Fatalf("unexpected read result: %q", out)
+	}
+	if out := runTool(t, fake, "read_file", `{"path": "/workspace/missing.go"}`); !strings.Contains(out, "file not found") {
+		t.Fatalf("expected not found error, got %q", out)
+	}
+	if out := runTool(t, fake, "list_dir", `{"path": "/workspace/src"}`); out != "main.go" {
+		t.Fatalf("unexpected listing: %q", out)
+	}
+}
+
+func TestApplyPatchEditsAndDeletesFiles(t *testing.T) {
+	fake := sandboxtest.NewFake()
+	fake.SetFile("greet.txt", "hello\nworld\n")
+	fake.SetFile("old.txt", "bye\n")
+
+	patch := "--- a/greet.txt\n+++ b/greet.txt\n@@ -1,2 +1,2 @@\n hello\n-world\n+there\n" +
+		"--- a/old.txt\n+++ /dev/null\n@@ -1 +0,0 @@\n-bye\n"
+	out := runTool(t, fake, "apply_patch", `{"patch": `+strconv.Quote(patch)+`}`)
+	if !strings.HasPrefix(out, "Patch applied:") {
+		t.Fatalf("unexpected patch result: %s", out)
+	}
+	if content, _ := fake.File("greet.txt"); content != "hello\nthere\n" {
+		t.Fatalf("expected patched content, got %q", content)
+	}
+	if fake.Exists("old.txt") {
+		t.Fatal("expected old.txt to be deleted")
+	}
+}
+
+func TestRunCommandReportsProgrammedResults(t *testing.T) {
+	fake := sandboxtest.NewFake()
+	fake.On("go test ./...", sandbox.ExecResult{ExitCode: 1, Stdout: "FAIL\tpkg", Stderr: "exit status 1"})
+
+	out := runTool(t, fake, "run_command", `{"command": "go test ./...", "timeout_seconds": 30}`)
+	if !strings.Contains(out, "Exit code: 1") || !strings.Contains(out, "FAIL\tpkg") {
+		t.Fatalf("unexpected command output: %s", out)
+	}
+	calls := fake.Calls()
+	if len(calls) != 1 || calls[0].Opts.Timeout.Seconds() != 30 {
+		t.Fatalf("expected one call with a 30s timeout, got %+v", calls)
+	}
+	if out := runTool(t, fake, "run_command", `{"command": "curl example.com"}`); !strings.Contains(out, "Exit code: 127") {
+		t.Fatalf("expected unknown commands to fail, got %s", out)
+	}
+}
+
+func TestShellToolKeepsSessionsPerTask(t *testing.T) {
+	fake := sandboxtest.NewFake()
+	executor := tools.NewExecutor(fake)
+	defer executor.Close()
+	shellTool := func(args string) string {
+		t.Helper()
+		out, err := executor.Execute(context.Background(), "shell", args)
+		if err != nil {
+			t.Fatalf("shell: %v", err)
+		}
+		return out
+	}
+
+	if out := shellTool(`{"input": "echo hello"}`); out != "hello\n" {
+		t.Fatalf("unexpected shell output %q", out)
+	}
+	fake.AssertExecuted(t, "echo hello")
+	if out := shellTool(`{}`); !strings.Contains(out, "no output yet") {
+		t.Fatalf("expected an empty read to say so, got %q", out)
+	}
+	if out := shellTool(`{"input": "exit 2"}`); !strings.Contains(out, "shell exited with code 2") {
+		t.Fatalf("expected the exit to be reported, got %q", out)
+	}
+
+	for _, name := range []string{"a", "b", "c", "d"} {
+		shellTool(`{"session": "` + name + `", "input": "true"}`)
+	}
+	if out := shellTool(`{"session": "e"}`); !strings.Contains(out, "at most 4 shell sessions") {
+		t.Fatalf("expected the session limit to apply, got %q", out)
+	}
+	if out := shellTool(`{"session": "a", "close": true}`); !strings.Contains(out, "Closed") {
+		t.Fatalf("unexpected close result %q", out)
+	}
+	if out := shellTool(`{"session": "e", "input": "true"}`); strings.Contains(out, "Error") {
+		t.Fatalf("expected a free slot after closing a session, got %q", out)
+	}
+}
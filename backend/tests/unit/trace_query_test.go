@@ -0,0 +1,101 @@
+package unit
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/example/back-end-tcc/pkg/models"
+	"github.com/example/back-end-tcc/pkg/queue"
+	"github.com/example/back-end-tcc/pkg/storage"
+	tracehandlers "github.com/example/back-end-tcc/services/trace/handlers"
+	tracerepository "github.com/example/back-end-tcc/services/trace/repository"
+	traceservice "github.com/example/back-end-tcc/services/trace/service"
+)
+
+func TestTraceQueryAPI(t *testing.T) {
+	store := storage.NewMemoryRepository[models.TraceEvent]()
+	base := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < 10; i++ {
+		event := models.TraceEvent{
+			ID:           fmt.Sprintf("e%02d", i),
+			SubmissionID: "s1",
+			TaskID:       fmt.Sprintf("t%d", i%2),
+			Type:         "agent",
+			Success:      i%3 == 0,
+			Timestamp:    base.Add(time.Duration(i) * time.Second),
+		}
+		if i >= 8 {
+			event.SubmissionID = "s2"
+		}
+		if i%4 == 1 {
+			event.Type, event.ToolName = "tool", "grep"
+		}
+		store.Save(event.ID, event)
+	}
+	svc := traceservice.New(tracerepository.New(store), queue.NewBus())
+	handlers := tracehandlers.New(svc)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/traces", handlers.List)
+	mux.HandleFunc("GET /submissions/{id}/trace", handlers.Conversation)
+	get := func(target string) ([]models.TraceEvent, *httptest.ResponseRecorder) {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		var events []models.TraceEvent
+		json.NewDecoder(rec.Body).Decode(&events)
+		return events, rec
+	}
+	ids := func(events []models.TraceEvent) string {
+		var s string
+		for _, event := range events {
+			s += event.ID + " "
+		}
+		return s
+	}
+
+	var pages []string
+	target := "/traces?submission_id=s1&limit=3"
+	for {
+		events, rec := get(target)
+		pages = append(pages, ids(events))
+		cursor := rec.Header().Get("X-Next-Cursor")
+		if cursor == "" {
+			break
+		}
+		target = "/traces?submission_id=s1&limit=3&cursor=" + cursor
+	}
+	if fmt.Sprint(pages) != "[e00 e01 e02  e03 e04 e05  e06 e07 ]" {
+		t.Fatalf("unexpected pages %q", pages)
+	}
+
+	events, _ := get("/traces?type=tool&tool_name=grep&order=desc")
+	if got := ids(events); got != "e09 e05 e01 " {
+		t.Errorf("unexpected tool events %q", got)
+	}
+	from, to := base.Add(2*time.Second).Format(time.RFC3339), base.Add(7*time.Second).Format(time.RFC3339)
+	events, _ = get("/traces?success=true&from=" + from + "&to=" + to)
+	if got := ids(events); got != "e03 e06 " {
+		t.Errorf("unexpected successful events in range %q", got)
+	}
+	for _, bad := range []string{"/traces?order=up", "/traces?limit=5000", "/traces?success=maybe", "/traces?from=today", "/traces?cursor=bm9wZQ"} {
+		if _, rec := get(bad); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", bad, rec.Code)
+		}
+	}
+
+	events, _ = get("/submissions/s1/trace?task_id=t1")
+	if got := ids(events); got != "e01 e03 e05 e07 " {
+		t.Errorf("unexpected conversation %q", got)
+	}
+	if _, rec := get("/submissions/unknown/trace"); rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for an unknown submission, got %d", rec.Code)
+	}
+
+	if _, err := storage.Apply(store.List(), storage.Query{OrderBy: []storage.Order{{Field: "parameters"}}}); !errors.Is(err, storage.ErrUnknownField) {
+		t.Errorf("expected an unknown field error, got %v", err)
+	}
+}